@@ -1,13 +1,133 @@
 package frameworkconstants
 
+import "time"
+
 type UserRole string
 
 const (
-	UserRoleTenantAdmin UserRole = "tenant_admin"
-	UserRoleTenantUser  UserRole = "tenant_user"
-	UserRoleSuperAdmin  UserRole = "super_admin"
-	UserRoleSuperUser   UserRole = "super_user"
+	UserRoleTenantAdmin   UserRole = "tenant_admin"
+	UserRoleTenantUser    UserRole = "tenant_user"
+	UserRoleSuperAdmin    UserRole = "super_admin"
+	UserRoleSuperUser     UserRole = "super_user"
+	UserRoleResellerAdmin UserRole = "reseller_admin"
 )
 
 const MaxFailedLoginAttempts = 3
+
+const (
+	DefaultPasswordMinLength = 8
+	// MaxPasswordBytes is the longest password bcrypt can hash
+	MaxPasswordBytes = 72
+	// MaxPasswordHistoryCount bounds the hashes compared when a password changes
+	MaxPasswordHistoryCount    = 24
+	PasswordChangeChallengeTTL = 10 * time.Minute
+)
+
+const (
+	DefaultArgon2idMemoryKiB   = 19 * 1024
+	DefaultArgon2idIterations  = 2
+	DefaultArgon2idParallelism = 1
+	Argon2idSaltLength         = 16
+	Argon2idKeyLength          = 32
+)
+
+const (
+	DefaultLockoutDuration    = 5 * time.Minute
+	DefaultMaxLockoutDuration = 24 * time.Hour
+)
+
 const TokenKey = "token"
+
+// ImpersonationAllowedKey marks a route that impersonation tokens may change data on
+const ImpersonationAllowedKey = "impersonation_allowed"
+
+// TenantDatabaseKey and TenantDatabaseReleaseKey hold a request's tenant database connection and
+// the function returning it to the pool
+const (
+	TenantDatabaseKey        = "tenant_database"
+	TenantDatabaseReleaseKey = "tenant_database_release"
+)
+
+// TenantIDSetting is the PostgreSQL setting row-level security policies read the tenant from
+const TenantIDSetting = "app.tenant_id"
+
+// BypassRowLevelSecuritySetting lets a connection past row-level security policies when set to
+// on. The framework's own connection sets it; tenant database connections turn it off.
+const BypassRowLevelSecuritySetting = "app.bypass_rls"
+
+// ResolvedTenantKey holds the tenant identified from the request's host or tenant header
+const ResolvedTenantKey = "resolved_tenant"
+
+const (
+	// TenantHeader carries a tenant ID or slug on requests that do not arrive on a tenant host
+	TenantHeader = "X-Tenant-ID"
+	// TenantResolutionCacheTTL bounds how long hosts and headers stay mapped to a tenant
+	TenantResolutionCacheTTL = time.Minute
+	// AccessControlCacheTTL bounds how long role, group and policy changes made by another
+	// replica take to apply
+	AccessControlCacheTTL = 30 * time.Second
+	// DomainVerificationRecordPrefix names the TXT record proving control of a custom domain,
+	// which must hold DomainVerificationValuePrefix followed by the domain's verification token
+	DomainVerificationRecordPrefix = "_serviceframework-verification"
+	DomainVerificationValuePrefix  = "serviceframework-verification="
+	DomainVerificationTimeout      = 5 * time.Second
+)
+
+// Sources a request's tenant can be resolved from
+const (
+	TenantSourceHeader    = "header"
+	TenantSourceDomain    = "domain"
+	TenantSourceSubdomain = "subdomain"
+)
+
+const DefaultImpersonationTTL = 15 * time.Minute
+
+const (
+	DefaultAccessTokenTTL  = 10 * time.Hour
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+const (
+	MFAChallengeTTL         = 5 * time.Minute
+	MaxMFAChallengeAttempts = 5
+	MFARecoveryCodeCount    = 10
+)
+
+// SessionLastSeenInterval throttles last_seen_at writes for active sessions
+const SessionLastSeenInterval = time.Minute
+
+const OIDCLoginStateTTL = 10 * time.Minute
+
+const DefaultMagicLinkTTL = 15 * time.Minute
+
+// Authentication methods recorded in the amr claim, using RFC 8176 values where one exists
+const (
+	AuthMethodPassword  = "pwd"
+	AuthMethodOTP       = "otp"
+	AuthMethodMFA       = "mfa"
+	AuthMethodMagicLink = "magic_link"
+	AuthMethodOIDC      = "oidc"
+)
+
+// DefaultReauthenticationMaxAge is how long a login or re-authentication satisfies routes that
+// require recent authentication
+const DefaultReauthenticationMaxAge = 5 * time.Minute
+
+const (
+	PrincipalTypeUser    = "user"
+	PrincipalTypeService = "service"
+)
+
+const (
+	APIKeyPrefix = "sfk_"
+	APIKeyHeader = "X-API-Key"
+	// APIKeyLastUsedInterval throttles last_used_at writes for busy keys
+	APIKeyLastUsedInterval = time.Minute
+)
+
+// Effects of an access policy whose condition holds. A matching deny always wins; when a
+// tenant has allow policies for an action, one of them must match.
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
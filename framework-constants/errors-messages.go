@@ -10,6 +10,7 @@ const (
 	ErrCodeConflict            = "CONFLICT"
 	ErrCodeValidation          = "VALIDATION_ERROR"
 	ErrCodeInternalServer      = "INTERNAL_SERVER_ERROR"
+	ErrCodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
 	ErrCodeDatabase            = "DATABASE_ERROR"
 	ErrCodeInvalidInput        = "INVALID_INPUT"
 	ErrCodeMissingHeader       = "MISSING_HEADER"
@@ -18,6 +19,7 @@ const (
 	ErrCodeForeignKeyViolation = "FOREIGN_KEY_VIOLATION"
 	ErrCodeInvalidBody         = "INVALID_BODY"
 	ErrUserAccountLocked       = "ACCOUNT_LOCKED"
+	ErrCodeReauthRequired      = "REAUTHENTICATION_REQUIRED"
 	ErrUnauthorizedError       = "UNAUTHORIZED_ERROR"
 )
 
@@ -27,12 +29,78 @@ var (
 	ErrFailedToHashPassword        = errors.New("failed to hash password")
 	ErrTenantAlreadyExists         = errors.New("tenant already exists")
 	ErrUserAlreadyExists           = errors.New("user already exists")
+	ErrTenantRequired              = errors.New("email address belongs to users of more than one tenant; address the request to the tenant")
 	ErrUserNotFound                = errors.New("user not found")
 	ErrInvalidPassword             = errors.New("invalid password")
+	ErrUnsupportedPasswordHash     = errors.New("unsupported password hash format")
+	ErrInvalidPasswordPolicy       = errors.New("password policy lengths must be between 1 and 72 bytes, min_length cannot exceed max_length, password_history_count must be between 0 and 24 and max_password_age_days cannot be negative")
+	ErrInvalidPasswordChange       = errors.New("invalid or expired password change token")
+	ErrInvalidResetPasswordToken   = errors.New("invalid or expired password reset token")
+	ErrAccountLocked               = errors.New("account is temporarily locked after too many failed login attempts")
 	ErrTenantNotFound              = errors.New("tenant not found")
 	ErrTenantLicenceNotFound       = errors.New("tenant licence not found")
 	ErrTenantLicenceExceeded       = errors.New("tenant licence exceeded")
 	ErrTenantLicenceExpired        = errors.New("tenant licence expired")
+	ErrTenantSuspended             = errors.New("tenant is suspended")
+	ErrTenantHasChildren           = errors.New("tenant has child tenants")
+	ErrInvalidSeatAllocation       = errors.New("seat allocations need a seat for the child tenant's admin and cannot be below the seats the child tenant uses or allocates")
+	ErrLicenceTypeNotFound         = errors.New("licence type not found")
+	ErrLicenceTypeNotGrantable     = errors.New("licence type allows more seats than the reseller's own licence type")
 	ErrFailedToCreateTenantLicence = errors.New("failed to create tenant licence")
 	ErrLicenceTypeAlreadyExists    = errors.New("licence type already exists")
+	ErrUserAccountInactive         = errors.New("user account is inactive")
+	ErrInvalidRefreshToken         = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired         = errors.New("refresh token expired")
+	ErrRefreshTokenReused          = errors.New("refresh token reuse detected")
+	ErrInvalidMFAChallenge         = errors.New("invalid or expired mfa challenge")
+	ErrInvalidMFACode              = errors.New("invalid mfa code")
+	ErrMFANotEnrolled              = errors.New("mfa enrollment has not been started")
+	ErrMFAAlreadyEnabled           = errors.New("mfa is already enabled")
+	ErrMFANotEnabled               = errors.New("mfa is not enabled")
+	ErrMFARequiredByTenant         = errors.New("mfa is required by the tenant")
+	ErrIdentityProviderNotFound    = errors.New("identity provider not configured for tenant")
+	ErrInvalidOIDCState            = errors.New("invalid or expired oidc state")
+	ErrOIDCAuthenticationFailed    = errors.New("oidc authentication failed")
+	ErrOIDCEmailNotVerified        = errors.New("oidc email address is not verified")
+	ErrOIDCUserNotProvisioned      = errors.New("user does not exist and provisioning is disabled")
+	ErrInvalidOIDCIssuer           = errors.New("issuer must be an https url without a query or fragment")
+	ErrLDAPDirectoryNotFound       = errors.New("ldap directory not configured for tenant")
+	ErrInvalidLDAPDirectory        = errors.New("ldap directories need an ldap:// or ldaps:// url, a base dn, a user filter containing {email} and group mappings to tenant roles")
+	ErrLDAPUnavailable             = errors.New("ldap directory unavailable")
+	ErrInsecureLDAPDirectory       = errors.New("ldap:// directories must use start_tls, which sends passwords encrypted, unless AllowInsecureLDAP is set")
+	ErrRoleNotFound                = errors.New("role not found")
+	ErrRoleAlreadyExists           = errors.New("a role with this name already exists")
+	ErrRoleInUse                   = errors.New("role is assigned to users or groups")
+	ErrInvalidRole                 = errors.New("roles need a name of at most 64 characters and known permissions")
+	ErrPermissionNotHeld           = errors.New("roles can only grant permissions you hold")
+	ErrGroupNotFound               = errors.New("group not found")
+	ErrGroupAlreadyExists          = errors.New("a group with this name already exists")
+	ErrGroupHasSubgroups           = errors.New("group has subgroups")
+	ErrInvalidGroup                = errors.New("groups need a name of at most 64 characters and a parent that is not the group or one of its subgroups")
+	ErrTenantScopeMissing          = errors.New("tenant-scoped models can only be queried with a tenant in the context")
+	ErrTenantIsolationUnsupported  = errors.New("schema-per-tenant isolation and row-level security require PostgreSQL")
+	ErrRowLevelSecurityDisabled    = errors.New("row-level security is not the configured tenant isolation")
+	ErrRowLevelSecurityColumn      = errors.New("row-level security needs a model with a tenant ID column")
+	ErrTenantMismatch              = errors.New("tenant-scoped record belongs to another tenant")
+	ErrInvalidTenantSlug           = errors.New("tenant slugs must be 1 to 63 lowercase letters, digits or hyphens, start and end with a letter or digit and not be all digits or www")
+	ErrTenantSlugTaken             = errors.New("tenant slug is already in use")
+	ErrInvalidDomain               = errors.New("invalid domain name")
+	ErrDomainAlreadyExists         = errors.New("domain is already registered")
+	ErrDomainAlreadyVerified       = errors.New("domain is already verified by another tenant")
+	ErrDomainNotFound              = errors.New("domain not found")
+	ErrDomainVerificationFailed    = errors.New("domain verification record not found")
+	ErrPolicyNotFound              = errors.New("access policy not found")
+	ErrInvalidPolicy               = errors.New("invalid access policy")
+	ErrInvalidPermission           = errors.New("permission names cannot be empty or contain whitespace")
+	ErrReauthenticationRequired    = errors.New("recent re-authentication required")
+	ErrCannotReauthenticate        = errors.New("only user session tokens can be re-authenticated")
+	ErrInvalidMagicLink            = errors.New("invalid or expired magic link")
+	ErrMagicLinkNotConfigured      = errors.New("magic link delivery is not configured")
+	ErrImpersonationNotFound       = errors.New("impersonation not found")
+	ErrCannotImpersonate           = errors.New("super admins, inactive users and yourself cannot be impersonated, and impersonations must start from a signed-in session")
+	ErrSessionNotFound             = errors.New("session not found")
+	ErrAPIKeyNotFound              = errors.New("api key not found")
+	ErrInvalidAPIKey               = errors.New("invalid, expired or revoked api key")
+	ErrInvalidAPIKeyScopes         = errors.New("api keys require at least one scope and scopes cannot contain whitespace")
+	ErrInvalidAPIKeyExpiry         = errors.New("api key expiry must be in the future")
 )
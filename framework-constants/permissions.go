@@ -0,0 +1,104 @@
+package frameworkconstants
+
+// Permissions guarding the built-in routes, named resource:action
+const (
+	PermissionUsersRead               = "users:read"
+	PermissionUsersCreate             = "users:create"
+	PermissionUsersUpdate             = "users:update"
+	PermissionUsersDelete             = "users:delete"
+	PermissionUsersUnlock             = "users:unlock"
+	PermissionSessionsManage          = "sessions:manage"
+	PermissionTenantsRead             = "tenants:read"
+	PermissionTenantsReadAll          = "tenants:read_all"
+	PermissionTenantsUpdate           = "tenants:update"
+	PermissionTenantsDelete           = "tenants:delete"
+	PermissionLicenceTypesRead        = "licence_types:read"
+	PermissionLicenceTypesManage      = "licence_types:manage"
+	PermissionAPIKeysManage           = "api_keys:manage"
+	PermissionPasswordPolicyManage    = "password_policy:manage"
+	PermissionIdentityProvidersManage = "identity_providers:manage"
+	PermissionLDAPManage              = "ldap:manage"
+	PermissionImpersonationManage     = "impersonation:manage"
+	PermissionRolesManage             = "roles:manage"
+	PermissionPoliciesManage          = "policies:manage"
+	PermissionGroupsRead              = "groups:read"
+	PermissionGroupsManage            = "groups:manage"
+	PermissionChildTenantsRead        = "child_tenants:read"
+	PermissionChildTenantsManage      = "child_tenants:manage"
+)
+
+// FrameworkPermissions describes every built-in permission. They are seeded at startup.
+var FrameworkPermissions = map[string]string{
+	PermissionUsersRead:               "List the tenant's users and the roles they can be given",
+	PermissionUsersCreate:             "Add users to the tenant",
+	PermissionUsersUpdate:             "Update other users, including their role and status",
+	PermissionUsersDelete:             "Delete users",
+	PermissionUsersUnlock:             "Lift failed-login lockouts",
+	PermissionSessionsManage:          "List and revoke other users' sessions",
+	PermissionTenantsRead:             "Read the caller's tenant",
+	PermissionTenantsReadAll:          "List every tenant",
+	PermissionTenantsUpdate:           "Update the caller's tenant",
+	PermissionTenantsDelete:           "Delete the caller's tenant",
+	PermissionLicenceTypesRead:        "Read licence types",
+	PermissionLicenceTypesManage:      "Create, update and delete licence types",
+	PermissionAPIKeysManage:           "Create, list and revoke API keys",
+	PermissionPasswordPolicyManage:    "Override the tenant's password policy",
+	PermissionIdentityProvidersManage: "Configure the tenant's OpenID Connect provider",
+	PermissionLDAPManage:              "Configure the tenant's LDAP directory",
+	PermissionImpersonationManage:     "Impersonate users and read the impersonation audit trail",
+	PermissionRolesManage:             "Create, update and delete the tenant's custom roles",
+	PermissionPoliciesManage:          "Create, update, delete and test the tenant's access policies",
+	PermissionGroupsRead:              "List the tenant's groups and their members",
+	PermissionGroupsManage:            "Create, update and delete groups and manage their members",
+	PermissionChildTenantsRead:        "List the tenant's child tenants and its seat pool",
+	PermissionChildTenantsManage:      "Create, suspend and reactivate child tenants and manage their licences and seats",
+}
+
+// DefaultRolePermissions are the permissions of the built-in roles. They are granted at every
+// startup, so permissions added in later versions reach existing databases.
+var DefaultRolePermissions = map[UserRole][]string{
+	UserRoleSuperAdmin: {
+		PermissionUsersRead, PermissionUsersCreate, PermissionUsersUpdate, PermissionUsersDelete, PermissionUsersUnlock,
+		PermissionSessionsManage,
+		PermissionTenantsRead, PermissionTenantsReadAll, PermissionTenantsUpdate, PermissionTenantsDelete,
+		PermissionLicenceTypesRead, PermissionLicenceTypesManage,
+		PermissionAPIKeysManage, PermissionPasswordPolicyManage, PermissionIdentityProvidersManage, PermissionLDAPManage,
+		PermissionImpersonationManage, PermissionRolesManage, PermissionPoliciesManage,
+		PermissionGroupsRead, PermissionGroupsManage,
+		PermissionChildTenantsRead, PermissionChildTenantsManage,
+	},
+	UserRoleSuperUser: {
+		PermissionUsersRead, PermissionGroupsRead,
+		PermissionTenantsRead, PermissionTenantsReadAll,
+		PermissionLicenceTypesRead,
+	},
+	UserRoleTenantAdmin: {
+		PermissionUsersRead, PermissionUsersCreate, PermissionUsersUpdate, PermissionUsersDelete, PermissionUsersUnlock,
+		PermissionSessionsManage,
+		PermissionTenantsRead, PermissionTenantsUpdate, PermissionTenantsDelete,
+		PermissionAPIKeysManage, PermissionPasswordPolicyManage, PermissionIdentityProvidersManage, PermissionLDAPManage,
+		PermissionRolesManage, PermissionPoliciesManage,
+		PermissionGroupsRead, PermissionGroupsManage,
+	},
+	UserRoleResellerAdmin: {
+		PermissionUsersRead, PermissionUsersCreate, PermissionUsersUpdate, PermissionUsersDelete, PermissionUsersUnlock,
+		PermissionSessionsManage,
+		PermissionTenantsRead, PermissionTenantsUpdate, PermissionTenantsDelete,
+		PermissionAPIKeysManage, PermissionPasswordPolicyManage, PermissionIdentityProvidersManage, PermissionLDAPManage,
+		PermissionRolesManage, PermissionPoliciesManage,
+		PermissionGroupsRead, PermissionGroupsManage,
+		PermissionChildTenantsRead, PermissionChildTenantsManage,
+	},
+	UserRoleTenantUser: {
+		PermissionTenantsRead,
+	},
+}
+
+// RoleDescriptions describes the built-in roles
+var RoleDescriptions = map[UserRole]string{
+	UserRoleSuperAdmin:    "Operates the whole platform",
+	UserRoleSuperUser:     "Read-only access across the platform",
+	UserRoleTenantAdmin:   "Administers a tenant",
+	UserRoleResellerAdmin: "Administers a tenant and the child tenants it resells to",
+	UserRoleTenantUser:    "Regular tenant member",
+}
@@ -9,4 +9,38 @@ type TokenDTO struct {
 	Role      string `json:"role"`
 	Exp       int64  `json:"exp"`
 	Iat       int64  `json:"iat"`
+	Jti       string `json:"jti"`
+	Sid       string `json:"sid,omitempty"`
+	// PrincipalType is "service" for API keys and empty or "user" for user tokens
+	PrincipalType string   `json:"principal_type,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+	// AuthTime is when the user last proved their identity, by logging in or re-authenticating,
+	// and AMR lists how (pwd, otp, mfa, magic_link or oidc). Refreshing keeps both.
+	AuthTime int64    `json:"auth_time,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+	// Groups lists the user's groups, including the groups containing them, when the token was issued
+	Groups []uint `json:"groups,omitempty"`
+	// Act identifies the super admin acting as this user when the token was issued by impersonation
+	Act *ActorDTO `json:"act,omitempty"`
+	// Extra holds the claims added by the host application's ClaimsProvider, as decoded from JSON
+	Extra map[string]any `json:"extra,omitempty"`
+}
+
+// TokenSubjectDTO describes the user a token is being issued for, passed to a ClaimsProvider
+type TokenSubjectDTO struct {
+	UserID    uint   `json:"user_id"`
+	TenantID  uint   `json:"tenant_id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Role      string `json:"role"`
+}
+
+// ActorDTO is the act claim of an impersonation token
+type ActorDTO struct {
+	Sub             string `json:"sub"`
+	Email           string `json:"email"`
+	ImpersonationID uint   `json:"impersonation_id"`
+	// Sid is the super admin's session; the impersonation token is revoked with it
+	Sid string `json:"sid"`
 }
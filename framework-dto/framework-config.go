@@ -1,5 +1,11 @@
 package frameworkdto
 
+import (
+	"context"
+	"net"
+	"time"
+)
+
 type Environment int
 
 const (
@@ -15,12 +21,137 @@ const (
 	DatabaseTypeSQLite
 )
 
+type JWTAlgorithm string
+
+const (
+	JWTAlgorithmHS256 JWTAlgorithm = "HS256"
+	JWTAlgorithmRS256 JWTAlgorithm = "RS256"
+	JWTAlgorithmES256 JWTAlgorithm = "ES256"
+	JWTAlgorithmEdDSA JWTAlgorithm = "EdDSA"
+)
+
+type PasswordHashAlgorithm string
+
+const (
+	PasswordHashAlgorithmBcrypt   PasswordHashAlgorithm = "bcrypt"
+	PasswordHashAlgorithmArgon2id PasswordHashAlgorithm = "argon2id"
+)
+
+// TenantIsolationMode selects how the host application's tenant data is kept apart
+type TenantIsolationMode string
+
+const (
+	// TenantIsolationShared keeps every tenant's rows in the same tables
+	TenantIsolationShared TenantIsolationMode = "shared"
+	// TenantIsolationSchema gives each tenant its own PostgreSQL schema
+	TenantIsolationSchema TenantIsolationMode = "schema"
+	// TenantIsolationRowLevelSecurity keeps shared tables behind PostgreSQL row-level security
+	// policies
+	TenantIsolationRowLevelSecurity TenantIsolationMode = "row_level_security"
+)
+
 type FrameworkConfig struct {
 	Environment Environment    `json:"environment"`
 	JWTSecret   string         `json:"jwt_secret"`
 	DBType      DatabaseType   `json:"db_type"`
 	DbCfg       DatabaseConfig `json:"db_cfg"`
 	CORSCfg     CORSCfg        `json:"cors_cfg"`
+
+	// AccessTokenTTL and RefreshTokenTTL default to 10 hours and 30 days when zero
+	AccessTokenTTL  time.Duration `json:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `json:"refresh_token_ttl"`
+
+	// JWTKeys are additional signing/verification keys identified by the kid header.
+	// JWTSigningKeyID selects the key used to sign new tokens; when empty tokens are
+	// signed with HS256 and JWTSecret. Keys without a private key only verify tokens,
+	// which allows a retired key to be kept until the tokens it signed have expired.
+	JWTKeys         []JWTKeyConfig `json:"jwt_keys"`
+	JWTSigningKeyID string         `json:"jwt_signing_key_id"`
+
+	// MFAIssuer is shown in authenticator apps; defaults to the tenant name
+	MFAIssuer string `json:"mfa_issuer"`
+
+	// MaxFailedLoginAttempts bad passwords in a row lock an account for LockoutDuration. Each
+	// further lockout before a successful login doubles the window, up to MaxLockoutDuration.
+	// Zero values default to 3 attempts, 5 minutes and 24 hours.
+	MaxFailedLoginAttempts int           `json:"max_failed_login_attempts"`
+	LockoutDuration        time.Duration `json:"lockout_duration"`
+	MaxLockoutDuration     time.Duration `json:"max_lockout_duration"`
+
+	// ImpersonationTTL is the lifetime of tokens issued to super admins impersonating a user;
+	// defaults to 15 minutes when zero
+	ImpersonationTTL time.Duration `json:"impersonation_ttl"`
+
+	// ReauthenticationMaxAge is how recently users must have logged in or re-authenticated to
+	// delete users or tenants or change an email address; defaults to 5 minutes when zero
+	ReauthenticationMaxAge time.Duration `json:"reauthentication_max_age"`
+
+	// MagicLinkSender delivers magic-link sign-in tokens, typically by emailing a link to a page
+	// of the host application that posts the token to /authentication/magic-link. Magic links
+	// are only issued to tenants with MagicLinkEnabled set. MagicLinkTTL defaults to 15 minutes.
+	MagicLinkSender MagicLinkSender `json:"-"`
+	MagicLinkTTL    time.Duration   `json:"magic_link_ttl"`
+
+	// LDAPDialer opens connections to tenant LDAP directories; net.Dial with a 10 second
+	// timeout is used when nil. Set it to reach directories through a proxy or to point the
+	// framework at an in-process directory in tests.
+	LDAPDialer LDAPDialer `json:"-"`
+	// AllowInsecureLDAP lets tenants use ldap:// directories without StartTLS, which send
+	// passwords in the clear. Only set it for directories reached over a trusted network.
+	AllowInsecureLDAP bool `json:"allow_insecure_ldap"`
+
+	// PasswordPolicy applies to every tenant without its own policy. When nil, passwords need
+	// 8 characters and must not be common or contain the user's email address or name.
+	PasswordPolicy *PasswordPolicyDTO `json:"password_policy"`
+
+	// PasswordHashing selects how new passwords are hashed. Stored hashes made with another
+	// algorithm or weaker parameters still verify and are re-hashed on the next successful login.
+	PasswordHashing PasswordHashingConfig `json:"password_hashing"`
+
+	// TenantIsolation defaults to TenantIsolationShared. With TenantIsolationSchema, which needs
+	// PostgreSQL, the models registered with RegisterTenantModels are migrated into a schema per
+	// tenant and tenant database connections search that schema first. The framework's own
+	// tables stay shared. With TenantIsolationRowLevelSecurity, also PostgreSQL only, the
+	// framework's tenant-owned tables get row-level security policies and tenant database
+	// connections carry the tenant in the app.tenant_id setting. The policies fail closed:
+	// connections without the setting see no rows unless app.bypass_rls is on, as it is on the
+	// framework's own connections.
+	TenantIsolation TenantIsolationMode `json:"tenant_isolation"`
+
+	// TenantBaseDomain resolves requests to acme.<TenantBaseDomain> to the tenant with the slug
+	// acme. Requests to other hosts resolve through verified custom domains or the X-Tenant-ID
+	// header.
+	TenantBaseDomain string `json:"tenant_base_domain"`
+
+	// DomainVerificationLookup fetches the TXT records proving control of custom domains;
+	// net.DefaultResolver is used when nil. Set it to verify domains without DNS in tests.
+	DomainVerificationLookup DomainVerificationLookup `json:"-"`
+}
+
+// MagicLinkSender sends the single-use token to the user's email address. The token is only
+// stored hashed, so it cannot be sent again.
+type MagicLinkSender func(email, token string, expiresAt time.Time) error
+
+type LDAPDialer func(network, address string) (net.Conn, error)
+
+type DomainVerificationLookup func(ctx context.Context, name string) ([]string, error)
+
+// PasswordHashingConfig zero values default to bcrypt with cost 10, and for argon2id to
+// 19 MiB of memory, 2 iterations and 1 thread
+type PasswordHashingConfig struct {
+	Algorithm           PasswordHashAlgorithm `json:"algorithm"`
+	BcryptCost          int                   `json:"bcrypt_cost"`
+	Argon2idMemoryKiB   uint32                `json:"argon2id_memory_kib"`
+	Argon2idIterations  uint32                `json:"argon2id_iterations"`
+	Argon2idParallelism uint8                 `json:"argon2id_parallelism"`
+}
+
+type JWTKeyConfig struct {
+	KeyID         string       `json:"kid"`
+	Algorithm     JWTAlgorithm `json:"algorithm"`
+	Secret        string       `json:"secret"`
+	PrivateKeyPEM string       `json:"private_key_pem"`
+	PublicKeyPEM  string       `json:"public_key_pem"`
 }
 
 type DatabaseConfig struct {
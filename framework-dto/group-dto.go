@@ -0,0 +1,44 @@
+package frameworkdto
+
+import "time"
+
+type GroupDTO struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id,omitempty"`
+	// Roles are granted to the members of this group and of its subgroups
+	Roles     []string  `json:"roles"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GroupDetailDTO is a group with its direct members
+type GroupDetailDTO struct {
+	GroupDTO
+	Members []GroupMemberDTO `json:"members"`
+}
+
+type GroupMemberDTO struct {
+	UserID    uint   `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+}
+
+type CreateGroupDTO struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	ParentID    *uint    `json:"parent_id,omitempty"`
+	Roles       []string `json:"roles"`
+}
+
+type UpdateGroupDTO struct {
+	ID uint `json:"id"`
+	CreateGroupDTO
+}
+
+type AddGroupMembersDTO struct {
+	GroupID uint   `json:"group_id"`
+	UserIDs []uint `json:"user_ids"`
+}
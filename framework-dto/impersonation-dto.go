@@ -0,0 +1,30 @@
+package frameworkdto
+
+import "time"
+
+type StartImpersonationDTO struct {
+	TenantID uint `json:"tenant_id"`
+	UserID   uint `json:"user_id"`
+	// Reason is recorded in the audit trail
+	Reason string `json:"reason"`
+}
+
+type ImpersonationTokenDTO struct {
+	ImpersonationID uint        `json:"impersonation_id"`
+	Token           BearerToken `json:"token"`
+	ExpiresIn       int64       `json:"expires_in"`
+}
+
+type ImpersonationDTO struct {
+	ID                   uint       `json:"id"`
+	ImpersonatorID       uint       `json:"impersonator_id"`
+	ImpersonatorTenantID uint       `json:"impersonator_tenant_id"`
+	ImpersonatorEmail    string     `json:"impersonator_email"`
+	TargetUserID         uint       `json:"target_user_id"`
+	TargetTenantID       uint       `json:"target_tenant_id"`
+	Reason               string     `json:"reason"`
+	IPAddress            string     `json:"ip_address"`
+	StartedAt            time.Time  `json:"started_at"`
+	ExpiresAt            time.Time  `json:"expires_at"`
+	EndedAt              *time.Time `json:"ended_at,omitempty"`
+}
@@ -0,0 +1,18 @@
+package frameworkdto
+
+// JWKDTO is a public JSON Web Key as described in RFC 7517
+type JWKDTO struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSDTO struct {
+	Keys []JWKDTO `json:"keys"`
+}
@@ -0,0 +1,32 @@
+package frameworkdto
+
+type LDAPDirectoryDTO struct {
+	// URL is an ldap:// or ldaps:// URL; StartTLS upgrades an ldap:// connection
+	URL      string `json:"url"`
+	StartTLS bool   `json:"start_tls"`
+	// BindDN is the service account used to find users; leave empty for anonymous searches
+	BindDN string `json:"bind_dn"`
+	BaseDN string `json:"base_dn"`
+	// UserFilter finds the user by the email address they log in with, which replaces {email};
+	// "(mail={email})" is used when empty
+	UserFilter string `json:"user_filter"`
+	// Attribute names default to givenName, sn and memberOf
+	FirstNameAttribute string                    `json:"first_name_attribute"`
+	LastNameAttribute  string                    `json:"last_name_attribute"`
+	GroupAttribute     string                    `json:"group_attribute"`
+	GroupRoleMappings  []LDAPGroupRoleMappingDTO `json:"group_role_mappings"`
+	IsEnabled          bool                      `json:"is_enabled"`
+}
+
+// LDAPGroupRoleMappingDTO gives members of a directory group a role. The first mapping that
+// matches one of the user's groups wins; users in no mapped group keep their role.
+type LDAPGroupRoleMappingDTO struct {
+	Group string `json:"group"`
+	Role  string `json:"role"`
+}
+
+type UpdateLDAPDirectoryDTO struct {
+	LDAPDirectoryDTO
+	// BindPassword is write only; leave empty to keep the stored password
+	BindPassword string `json:"bind_password"`
+}
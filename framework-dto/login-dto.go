@@ -8,5 +8,46 @@ type LoginDTO struct {
 type BearerToken string
 
 type LoginResponseDTO struct {
-	Token BearerToken `json:"token"`
+	Token        BearerToken `json:"token,omitempty"`
+	ExpiresIn    int64       `json:"expires_in,omitempty"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+
+	// When MFARequired is set no token is issued; MFAToken must be exchanged at
+	// /authentication/mfa/verify together with a TOTP or recovery code
+	MFARequired           bool     `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool     `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string   `json:"mfa_token,omitempty"`
+	RecoveryCodes         []string `json:"recovery_codes,omitempty"`
+
+	// When PasswordChangeRequired is set the password has expired and no token is issued;
+	// PasswordChangeToken can only be used to set a new password at /authentication/password/change
+	PasswordChangeRequired bool   `json:"password_change_required,omitempty"`
+	PasswordChangeToken    string `json:"password_change_token,omitempty"`
+}
+
+type PasswordChangeRequestDTO struct {
+	PasswordChangeToken string `json:"password_change_token"`
+	NewPassword         string `json:"new_password"`
+}
+
+// ReauthenticateDTO proves the user's identity again with their password, a TOTP code, or both
+type ReauthenticateDTO struct {
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+type MagicLinkRequestDTO struct {
+	Email string `json:"email"`
+}
+
+type MagicLinkLoginDTO struct {
+	Token string `json:"token"`
+}
+
+type RefreshTokenRequestDTO struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequestDTO struct {
+	RefreshToken string `json:"refresh_token"`
 }
@@ -0,0 +1,24 @@
+package frameworkdto
+
+type MFAVerifyRequestDTO struct {
+	MFAToken string `json:"mfa_token"`
+	// Code is either a current TOTP code or an unused recovery code
+	Code string `json:"code"`
+}
+
+type MFAEnrollRequestDTO struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type MFACodeRequestDTO struct {
+	Code string `json:"code"`
+}
+
+type MFAEnrollmentDTO struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type MFARecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,28 @@
+package frameworkdto
+
+type IdentityProviderDTO struct {
+	Issuer      string `json:"issuer"`
+	ClientID    string `json:"client_id"`
+	RedirectURL string `json:"redirect_url"`
+	// Scopes is a space separated list; "openid email profile" is used when empty
+	Scopes               string `json:"scopes"`
+	DefaultRole          string `json:"default_role"`
+	AllowJITProvisioning bool   `json:"allow_jit_provisioning"`
+	IsEnabled            bool   `json:"is_enabled"`
+}
+
+type UpdateIdentityProviderDTO struct {
+	IdentityProviderDTO
+	// ClientSecret is write only; leave empty to keep the stored secret
+	ClientSecret string `json:"client_secret"`
+}
+
+type OIDCAuthorizeResponseDTO struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type OIDCCallbackRequestDTO struct {
+	State string `json:"state"`
+	Code  string `json:"code"`
+}
@@ -0,0 +1,26 @@
+package frameworkdto
+
+// PasswordPolicyDTO describes the rules new passwords must satisfy
+type PasswordPolicyDTO struct {
+	MinLength int `json:"min_length"`
+	// MaxLength is measured in bytes and cannot exceed 72, the most bcrypt will hash
+	MaxLength               int  `json:"max_length"`
+	RequireUppercase        bool `json:"require_uppercase"`
+	RequireLowercase        bool `json:"require_lowercase"`
+	RequireDigit            bool `json:"require_digit"`
+	RequireSymbol           bool `json:"require_symbol"`
+	DisallowCommonPasswords bool `json:"disallow_common_passwords"`
+	// DisallowUserInfo rejects passwords containing the user's email address or name
+	DisallowUserInfo bool `json:"disallow_user_info"`
+	// PasswordHistoryCount is how many recent passwords, including the current one, cannot be
+	// reused; zero allows reuse
+	PasswordHistoryCount int `json:"password_history_count"`
+	// MaxPasswordAgeDays forces a password change at the first login after the password
+	// reaches this age; zero means passwords never expire
+	MaxPasswordAgeDays int `json:"max_password_age_days"`
+}
+
+type PasswordPolicyViolationDTO struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
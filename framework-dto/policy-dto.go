@@ -0,0 +1,71 @@
+package frameworkdto
+
+import "time"
+
+type PolicyDTO struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Action      string    `json:"action"`
+	Effect      string    `json:"effect"`
+	Condition   string    `json:"condition"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreatePolicyDTO describes an access policy. Action is an action such as documents:update,
+// a prefix such as documents:* or *. Effect is allow or deny. Condition is an expression over
+// subject, request and resource attributes.
+type CreatePolicyDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Action      string `json:"action"`
+	Effect      string `json:"effect"`
+	Condition   string `json:"condition"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type UpdatePolicyDTO struct {
+	ID uint `json:"id"`
+	CreatePolicyDTO
+}
+
+// PolicyRequestDTO holds the request attributes policies can test
+type PolicyRequestDTO struct {
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	IP     string    `json:"ip"`
+	Time   time.Time `json:"time"`
+}
+
+// ExplainPolicyDTO asks how an action would be decided without performing it. The subject is
+// the caller unless UserID names another user of the tenant. Request attributes default to the
+// explain request's own. When Policies is given, those draft policies are evaluated instead of
+// the tenant's stored policies.
+type ExplainPolicyDTO struct {
+	Action   string            `json:"action"`
+	UserID   *uint             `json:"user_id,omitempty"`
+	Request  *PolicyRequestDTO `json:"request,omitempty"`
+	Resource map[string]any    `json:"resource,omitempty"`
+	Policies []CreatePolicyDTO `json:"policies,omitempty"`
+}
+
+// PolicyDecisionDTO is the outcome of evaluating a tenant's policies for an action
+type PolicyDecisionDTO struct {
+	Allowed bool   `json:"allowed"`
+	Action  string `json:"action"`
+	Reason  string `json:"reason"`
+	// Policies lists every policy that applied to the action and whether its condition held
+	Policies []PolicyEvaluationDTO `json:"policies"`
+}
+
+type PolicyEvaluationDTO struct {
+	ID      uint   `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Effect  string `json:"effect"`
+	Matched bool   `json:"matched"`
+	// Error is set when the condition could not be evaluated. A failing deny policy denies and a
+	// failing allow policy does not allow.
+	Error string `json:"error,omitempty"`
+}
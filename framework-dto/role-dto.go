@@ -0,0 +1,33 @@
+package frameworkdto
+
+import "time"
+
+type RoleDTO struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// IsSystem marks the built-in roles, which cannot be changed
+	IsSystem    bool      `json:"is_system"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateRoleDTO struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleDTO replaces a custom role's description and permissions. Roles cannot be renamed
+// because users reference them by name.
+type UpdateRoleDTO struct {
+	ID          uint     `json:"id"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type PermissionDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
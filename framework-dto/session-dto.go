@@ -0,0 +1,14 @@
+package frameworkdto
+
+import "time"
+
+type SessionDTO struct {
+	SessionID  string    `json:"session_id"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is true for the session the request was made with
+	Current bool `json:"current"`
+}
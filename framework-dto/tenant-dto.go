@@ -1,16 +1,96 @@
 package frameworkdto
 
+import "time"
+
 type GetTenantDTO struct {
-	TenantID      uint   `json:"tenant_id"`
-	TenantName    string `json:"tenant_name"`
-	TenantEmail   string `json:"tenant_email"`
-	TenantPhone   string `json:"tenant_phone"`
-	TenantAddress string `json:"tenant_address"`
+	TenantID         uint   `json:"tenant_id"`
+	TenantName       string `json:"tenant_name"`
+	TenantEmail      string `json:"tenant_email"`
+	TenantPhone      string `json:"tenant_phone"`
+	TenantAddress    string `json:"tenant_address"`
+	MFARequired      bool   `json:"mfa_required"`
+	MagicLinkEnabled bool   `json:"magic_link_enabled"`
+	Slug             string `json:"slug"`
 }
 
 type UpdateTenantDTO struct {
-	TenantName    string `json:"tenant_name"`
-	TenantEmail   string `json:"tenant_email"`
-	TenantPhone   string `json:"tenant_phone"`
-	TenantAddress string `json:"tenant_address"`
+	TenantName       string `json:"tenant_name"`
+	TenantEmail      string `json:"tenant_email"`
+	TenantPhone      string `json:"tenant_phone"`
+	TenantAddress    string `json:"tenant_address"`
+	MFARequired      bool   `json:"mfa_required"`
+	MagicLinkEnabled bool   `json:"magic_link_enabled"`
+	// Slug is left unchanged when omitted and removed when empty
+	Slug *string `json:"slug"`
+}
+
+// ResolvedTenantDTO is the tenant a request was addressed to before authentication. Source is
+// header, domain or subdomain.
+type ResolvedTenantDTO struct {
+	TenantID   uint   `json:"tenant_id"`
+	TenantName string `json:"tenant_name"`
+	Slug       string `json:"slug"`
+	Source     string `json:"source"`
+}
+
+type TenantDomainDTO struct {
+	ID         uint       `json:"id"`
+	Domain     string     `json:"domain"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	// VerificationRecord is the TXT record that must hold VerificationValue to verify the domain
+	VerificationRecord string    `json:"verification_record"`
+	VerificationValue  string    `json:"verification_value"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type AddTenantDomainDTO struct {
+	Domain string `json:"domain"`
+}
+
+// ChildTenantDTO is a tenant managed by the caller's tenant
+type ChildTenantDTO struct {
+	TenantID      uint                   `json:"tenant_id"`
+	TenantName    string                 `json:"tenant_name"`
+	TenantEmail   string                 `json:"tenant_email"`
+	TenantPhone   string                 `json:"tenant_phone"`
+	TenantAddress string                 `json:"tenant_address"`
+	Slug          string                 `json:"slug"`
+	IsActive      bool                   `json:"is_active"`
+	Licence       *ChildTenantLicenceDTO `json:"licence"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// ChildTenantLicenceDTO is a child tenant's licence. Seats are allocated from the parent's
+// licence; AllocatedSeats are the seats the child has allocated to its own children.
+type ChildTenantLicenceDTO struct {
+	LicenceTypeID  uint       `json:"licence_type_id"`
+	LicenceType    string     `json:"licence_type"`
+	ExpiryDate     *time.Time `json:"expiry_date"`
+	Seats          int        `json:"seats"`
+	UsedSeats      int        `json:"used_seats"`
+	AllocatedSeats int        `json:"allocated_seats"`
+}
+
+// CreateChildTenantDTO registers a child tenant and its first admin, allocating Seats from the
+// caller's licence
+type CreateChildTenantDTO struct {
+	TenantRegistrationDTO
+	Seats int `json:"seats"`
+}
+
+type UpdateChildTenantLicenceDTO struct {
+	TenantID      uint       `json:"tenant_id"`
+	LicenceTypeID uint       `json:"licence_type_id"`
+	ExpiryDate    *time.Time `json:"expiry_date"`
+	Seats         int        `json:"seats"`
+}
+
+// SeatPoolDTO describes a tenant's licence seats. AvailableSeats can be taken by new users or
+// allocated to child tenants.
+type SeatPoolDTO struct {
+	TotalSeats     int `json:"total_seats"`
+	UsedSeats      int `json:"used_seats"`
+	AllocatedSeats int `json:"allocated_seats"`
+	AvailableSeats int `json:"available_seats"`
 }
@@ -22,6 +22,11 @@ type ResetPasswordDTO struct {
 	NewPassword string `json:"new_password"`
 }
 
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
 type VerifyEmailDTO struct {
 	TenantID uint   `json:"tenant_id"`
 	UserID   uint   `json:"user_id"`
@@ -29,14 +34,16 @@ type VerifyEmailDTO struct {
 }
 
 type GetUsersResponseDTO struct {
-	UserID    uint      `json:"user_id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	Role      string    `json:"role"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint   `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	IsActive  bool   `json:"is_active"`
+	// LockedUntil is set while the account is locked after failed logins
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type GetUserRoles struct {
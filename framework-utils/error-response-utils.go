@@ -3,6 +3,7 @@ package frameworkutils
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
@@ -51,6 +52,17 @@ func InternalServerError(message string) *frameworkdto.ResponseErrorDTO {
 	return NewResponseError(frameworkconstants.ErrCodeInternalServer, message, http.StatusInternalServerError)
 }
 
+func ServiceUnavailable(message string) *frameworkdto.ResponseErrorDTO {
+	return NewResponseError(frameworkconstants.ErrCodeServiceUnavailable, message, http.StatusServiceUnavailable)
+}
+
+// ReauthenticationRequired asks the client to call /authentication/reauthenticate and retry
+func ReauthenticationRequired(maxAge time.Duration) *frameworkdto.ResponseErrorDTO {
+	responseErr := NewResponseError(frameworkconstants.ErrCodeReauthRequired, frameworkconstants.ErrReauthenticationRequired.Error(), http.StatusUnauthorized)
+	responseErr.Details["max_age_seconds"] = int64(maxAge.Seconds())
+	return responseErr
+}
+
 func DatabaseError(err error) *frameworkdto.ResponseErrorDTO {
 	return NewResponseError(
 		frameworkconstants.ErrCodeDatabase,
@@ -59,6 +71,13 @@ func DatabaseError(err error) *frameworkdto.ResponseErrorDTO {
 	)
 }
 
+// PasswordPolicyViolation reports each broken password rule in the error details
+func PasswordPolicyViolation(policyErr *PasswordPolicyError) *frameworkdto.ResponseErrorDTO {
+	responseErr := NewResponseError(frameworkconstants.ErrCodeValidation, "Password does not meet the password policy", http.StatusBadRequest)
+	responseErr.Details["violations"] = policyErr.Violations
+	return responseErr
+}
+
 func InvalidInput(field string, reason string) *frameworkdto.ResponseErrorDTO {
 	return NewResponseError(
 		frameworkconstants.ErrCodeInvalidInput,
@@ -99,6 +118,10 @@ func ForeignKeyViolation(message string) *frameworkdto.ResponseErrorDTO {
 	)
 }
 
+func AccountLocked(message string) *frameworkdto.ResponseErrorDTO {
+	return NewResponseError(frameworkconstants.ErrUserAccountLocked, message, http.StatusLocked)
+}
+
 func UnauthorizedError(message string) *frameworkdto.ResponseErrorDTO {
 	return NewResponseError(
 		frameworkconstants.ErrCodeUnauthorized,
@@ -0,0 +1,215 @@
+package frameworkutils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type jwtKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+// JWTKeySet holds the key used to sign new tokens and every key still accepted for
+// verification, looked up by the kid header of incoming tokens
+type JWTKeySet struct {
+	keys   map[string]*jwtKey
+	signer *jwtKey
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a single HS256 secret and no kid
+func NewHMACKeySet(jwtSecret []byte) *JWTKeySet {
+	key := &jwtKey{method: jwt.SigningMethodHS256, signKey: jwtSecret, verifyKey: jwtSecret}
+	return &JWTKeySet{keys: map[string]*jwtKey{"": key}, signer: key}
+}
+
+// NewJWTKeySet builds a key set from the framework configuration. JWTSecret, when set, is
+// registered as an HS256 key without a kid so tokens issued before key rotation was
+// configured remain valid.
+func NewJWTKeySet(cfg *frameworkdto.FrameworkConfig) (*JWTKeySet, error) {
+	ks := &JWTKeySet{keys: make(map[string]*jwtKey)}
+
+	if cfg.JWTSecret != "" {
+		ks.keys[""] = &jwtKey{method: jwt.SigningMethodHS256, signKey: []byte(cfg.JWTSecret), verifyKey: []byte(cfg.JWTSecret)}
+	}
+
+	for _, keyCfg := range cfg.JWTKeys {
+		if keyCfg.KeyID == "" {
+			return nil, errors.New("jwt key is missing a kid")
+		}
+		if _, exists := ks.keys[keyCfg.KeyID]; exists {
+			return nil, fmt.Errorf("duplicate jwt kid %q", keyCfg.KeyID)
+		}
+
+		key, err := parseJWTKey(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", keyCfg.KeyID, err)
+		}
+		ks.keys[keyCfg.KeyID] = key
+	}
+
+	signer, ok := ks.keys[cfg.JWTSigningKeyID]
+	if !ok {
+		if cfg.JWTSigningKeyID == "" {
+			return nil, errors.New("no jwt signing key configured")
+		}
+		return nil, fmt.Errorf("jwt signing key %q not found", cfg.JWTSigningKeyID)
+	}
+	if signer.signKey == nil {
+		return nil, fmt.Errorf("jwt signing key %q has no private key", cfg.JWTSigningKeyID)
+	}
+	ks.signer = signer
+
+	return ks, nil
+}
+
+func parseJWTKey(keyCfg frameworkdto.JWTKeyConfig) (*jwtKey, error) {
+	key := &jwtKey{kid: keyCfg.KeyID}
+
+	switch keyCfg.Algorithm {
+	case frameworkdto.JWTAlgorithmHS256:
+		if keyCfg.Secret == "" {
+			return nil, errors.New("secret is required for HS256")
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(keyCfg.Secret)
+		key.verifyKey = []byte(keyCfg.Secret)
+
+	case frameworkdto.JWTAlgorithmRS256:
+		key.method = jwt.SigningMethodRS256
+		if keyCfg.PrivateKeyPEM != "" {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(keyCfg.PrivateKeyPEM))
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+		} else if keyCfg.PublicKeyPEM != "" {
+			publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(keyCfg.PublicKeyPEM))
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = publicKey
+		}
+
+	case frameworkdto.JWTAlgorithmES256:
+		key.method = jwt.SigningMethodES256
+		var publicKey *ecdsa.PublicKey
+		if keyCfg.PrivateKeyPEM != "" {
+			privateKey, err := jwt.ParseECPrivateKeyFromPEM([]byte(keyCfg.PrivateKeyPEM))
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = privateKey
+			publicKey = &privateKey.PublicKey
+		} else if keyCfg.PublicKeyPEM != "" {
+			var err error
+			publicKey, err = jwt.ParseECPublicKeyFromPEM([]byte(keyCfg.PublicKeyPEM))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if publicKey != nil && publicKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		if publicKey != nil {
+			key.verifyKey = publicKey
+		}
+
+	case frameworkdto.JWTAlgorithmEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+		if keyCfg.PrivateKeyPEM != "" {
+			privateKey, err := jwt.ParseEdPrivateKeyFromPEM([]byte(keyCfg.PrivateKeyPEM))
+			if err != nil {
+				return nil, err
+			}
+			edPrivateKey, ok := privateKey.(ed25519.PrivateKey)
+			if !ok {
+				return nil, errors.New("EdDSA requires an Ed25519 key")
+			}
+			key.signKey = edPrivateKey
+			key.verifyKey = edPrivateKey.Public()
+		} else if keyCfg.PublicKeyPEM != "" {
+			publicKey, err := jwt.ParseEdPublicKeyFromPEM([]byte(keyCfg.PublicKeyPEM))
+			if err != nil {
+				return nil, err
+			}
+			key.verifyKey = publicKey
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", keyCfg.Algorithm)
+	}
+
+	if key.verifyKey == nil {
+		return nil, errors.New("a private or public key is required")
+	}
+
+	return key, nil
+}
+
+// Sign signs the claims with the active signing key, setting the kid header when it has one
+func (ks *JWTKeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signer.method, claims)
+	if ks.signer.kid != "" {
+		token.Header["kid"] = ks.signer.kid
+	}
+	return token.SignedString(ks.signer.signKey)
+}
+
+// Keyfunc resolves the verification key for a token from its kid header, rejecting tokens
+// whose alg does not match the algorithm configured for that key
+func (ks *JWTKeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public asymmetric keys in JSON Web Key Set form. Shared HS256 secrets are never published.
+func (ks *JWTKeySet) JWKS() frameworkdto.JWKSDTO {
+	jwks := frameworkdto.JWKSDTO{Keys: []frameworkdto.JWKDTO{}}
+	for _, key := range ks.keys {
+		jwk := frameworkdto.JWKDTO{Kid: key.kid, Alg: key.method.Alg(), Use: "sig"}
+
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = publicKey.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
@@ -5,32 +5,76 @@ import (
 	"fmt"
 	"time"
 
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// ClaimsProvider returns claims the host application wants in access tokens issued to a user,
+// such as a department or plan. It is called at login, refresh and impersonation. Reserved
+// claims cannot be overridden and are dropped; an error fails the request.
+type ClaimsProvider func(subject frameworkdto.TokenSubjectDTO) (map[string]any, error)
+
+// reservedClaims are set or validated by the framework and the JWT library
+var reservedClaims = map[string]bool{
+	"sub": true, "tenant_id": true, "email": true, "first_name": true, "last_name": true, "role": true,
+	"exp": true, "iat": true, "nbf": true, "iss": true, "aud": true, "jti": true, "sid": true, "act": true,
+	"auth_time": true, "amr": true, "groups": true,
+}
+
+// IsReservedClaim reports whether a claim name is used by the framework and so cannot be set
+// by a ClaimsProvider or appear in TokenDTO.Extra
+func IsReservedClaim(name string) bool {
+	return reservedClaims[name]
+}
+
 func GenerateJWT(userID, tenantID any, email, firstName, lastName, role string, jwtSecret []byte) (string, error) {
-	claims := jwt.MapClaims{
+	return GenerateJWTWithTTL(userID, tenantID, email, firstName, lastName, role, frameworkconstants.DefaultAccessTokenTTL, jwtSecret)
+}
+
+func GenerateJWTWithTTL(userID, tenantID any, email, firstName, lastName, role string, ttl time.Duration, jwtSecret []byte) (string, error) {
+	return NewHMACKeySet(jwtSecret).GenerateJWT(userID, tenantID, email, firstName, lastName, role, ttl)
+}
+
+// GenerateJWT issues a token signed with the key set's active signing key
+func (ks *JWTKeySet) GenerateJWT(userID, tenantID any, email, firstName, lastName, role string, ttl time.Duration) (string, error) {
+	return ks.GenerateJWTWithClaims(userID, tenantID, email, firstName, lastName, role, ttl, nil)
+}
+
+// GenerateJWTWithClaims issues a token like GenerateJWT carrying additional claims such as sid.
+// Extra claims cannot override the standard ones.
+func (ks *JWTKeySet) GenerateJWTWithClaims(userID, tenantID any, email, firstName, lastName, role string, ttl time.Duration, extraClaims map[string]any) (string, error) {
+	claims := jwt.MapClaims{}
+	for name, value := range extraClaims {
+		claims[name] = value
+	}
+
+	standardClaims := jwt.MapClaims{
 		"sub":        userID,
 		"tenant_id":  tenantID,
 		"email":      email,
 		"first_name": firstName,
 		"last_name":  lastName,
 		"role":       role,
-		"exp":        time.Now().Add(10 * time.Hour).Unix(),
+		"exp":        time.Now().Add(ttl).Unix(),
 		"iat":        time.Now().Unix(),
+		"jti":        uuid.New().String(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	for name, value := range standardClaims {
+		claims[name] = value
+	}
+
+	return ks.Sign(claims)
 }
 
 func ParseJWT(tokenString string, jwtSecret []byte) (frameworkdto.TokenDTO, error) {
-	tok, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+	return NewHMACKeySet(jwtSecret).ParseJWT(tokenString)
+}
+
+// ParseJWT verifies a token against the key named by its kid header and maps its claims
+func (ks *JWTKeySet) ParseJWT(tokenString string) (frameworkdto.TokenDTO, error) {
+	tok, err := jwt.Parse(tokenString, ks.Keyfunc)
 	if err != nil {
 		return frameworkdto.TokenDTO{}, err
 	}
@@ -103,6 +147,59 @@ func ParseJWT(tokenString string, jwtSecret []byte) (frameworkdto.TokenDTO, erro
 	}
 	iat := int64(iatFloat)
 
+	// Tokens issued before jti was introduced carry no ID and can only be revoked per user
+	jti, _ := claims["jti"].(string)
+	// Only tokens issued for a login session carry a sid
+	sid, _ := claims["sid"].(string)
+
+	// Tokens issued before step-up authentication carry neither and count as not recently authenticated
+	var authTime int64
+	if authTimeFloat, ok := claims["auth_time"].(float64); ok {
+		authTime = int64(authTimeFloat)
+	}
+	var amr []string
+	if amrClaim, ok := claims["amr"].([]any); ok {
+		for _, method := range amrClaim {
+			if methodString, ok := method.(string); ok {
+				amr = append(amr, methodString)
+			}
+		}
+	}
+
+	var groups []uint
+	if groupsClaim, ok := claims["groups"].([]any); ok {
+		for _, group := range groupsClaim {
+			if groupID, ok := group.(float64); ok {
+				groups = append(groups, uint(groupID))
+			}
+		}
+	}
+
+	var act *frameworkdto.ActorDTO
+	if actClaim, ok := claims["act"].(map[string]any); ok {
+		act = &frameworkdto.ActorDTO{}
+		act.Sub, _ = actClaim["sub"].(string)
+		act.Email, _ = actClaim["email"].(string)
+		act.Sid, _ = actClaim["sid"].(string)
+		if impersonationID, ok := actClaim["impersonation_id"].(float64); ok {
+			act.ImpersonationID = uint(impersonationID)
+		}
+		if act.Sub == "" {
+			return frameworkdto.TokenDTO{}, errors.New("act claim missing sub")
+		}
+	}
+
+	var extra map[string]any
+	for name, value := range claims {
+		if IsReservedClaim(name) {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]any)
+		}
+		extra[name] = value
+	}
+
 	return frameworkdto.TokenDTO{
 		Sub:       sub,
 		TenantID:  tenantID,
@@ -112,5 +209,12 @@ func ParseJWT(tokenString string, jwtSecret []byte) (frameworkdto.TokenDTO, erro
 		Role:      role,
 		Exp:       exp,
 		Iat:       iat,
+		Jti:       jti,
+		Sid:       sid,
+		AuthTime:  authTime,
+		AMR:       amr,
+		Groups:    groups,
+		Act:       act,
+		Extra:     extra,
 	}, nil
 }
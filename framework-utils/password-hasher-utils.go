@@ -0,0 +1,198 @@
+package frameworkutils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const argon2idHashPrefix = "$argon2id$"
+
+// PasswordHasher hashes new passwords with one algorithm and verifies hashes made by any
+// supported algorithm. Encoded hashes start with their algorithm, e.g. $2a$ for bcrypt and
+// $argon2id$ for argon2id, so one column can hold a mix of both.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify returns nil when the password matches and frameworkconstants.ErrInvalidPassword when it does not
+	Verify(password, encodedHash string) error
+	// NeedsRehash reports whether the hash was made with another algorithm or other parameters
+	NeedsRehash(encodedHash string) bool
+}
+
+// NewPasswordHasher returns the hasher selected by the framework configuration
+func NewPasswordHasher(cfg *frameworkdto.FrameworkConfig) (PasswordHasher, error) {
+	hashingCfg := cfg.PasswordHashing
+
+	switch hashingCfg.Algorithm {
+	case "", frameworkdto.PasswordHashAlgorithmBcrypt:
+		cost := hashingCfg.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return &BcryptPasswordHasher{Cost: cost}, nil
+	case frameworkdto.PasswordHashAlgorithmArgon2id:
+		hasher := &Argon2idPasswordHasher{
+			MemoryKiB:   hashingCfg.Argon2idMemoryKiB,
+			Iterations:  hashingCfg.Argon2idIterations,
+			Parallelism: hashingCfg.Argon2idParallelism,
+		}
+		if hasher.MemoryKiB == 0 {
+			hasher.MemoryKiB = frameworkconstants.DefaultArgon2idMemoryKiB
+		}
+		if hasher.Iterations == 0 {
+			hasher.Iterations = frameworkconstants.DefaultArgon2idIterations
+		}
+		if hasher.Parallelism == 0 {
+			hasher.Parallelism = frameworkconstants.DefaultArgon2idParallelism
+		}
+		if hasher.MemoryKiB < 8*uint32(hasher.Parallelism) {
+			return nil, fmt.Errorf("argon2id memory must be at least 8 KiB per thread")
+		}
+		return hasher, nil
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", hashingCfg.Algorithm)
+	}
+}
+
+type BcryptPasswordHasher struct {
+	Cost int
+}
+
+func (h *BcryptPasswordHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *BcryptPasswordHasher) Verify(password, encodedHash string) error {
+	return VerifyPasswordHash(password, encodedHash)
+}
+
+func (h *BcryptPasswordHasher) NeedsRehash(encodedHash string) bool {
+	if !isBcryptHash(encodedHash) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost != h.Cost
+}
+
+type Argon2idPasswordHasher struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// Hash returns the hash in the PHC string format: $argon2id$v=19$m=<KiB>,t=<iterations>,p=<threads>$<salt>$<key>
+func (h *Argon2idPasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, frameworkconstants.Argon2idSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.MemoryKiB, h.Parallelism, frameworkconstants.Argon2idKeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idHashPrefix,
+		argon2.Version,
+		h.MemoryKiB,
+		h.Iterations,
+		h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idPasswordHasher) Verify(password, encodedHash string) error {
+	return VerifyPasswordHash(password, encodedHash)
+}
+
+func (h *Argon2idPasswordHasher) NeedsRehash(encodedHash string) bool {
+	if !strings.HasPrefix(encodedHash, argon2idHashPrefix) {
+		return true
+	}
+	params, _, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.MemoryKiB != h.MemoryKiB ||
+		params.Iterations != h.Iterations ||
+		params.Parallelism != h.Parallelism ||
+		len(key) != frameworkconstants.Argon2idKeyLength
+}
+
+// VerifyPasswordHash checks a password against a bcrypt or argon2id hash, whichever
+// algorithm and parameters it was made with. An empty hash, as held by users who only sign
+// in through an identity provider, never matches.
+func VerifyPasswordHash(password, encodedHash string) error {
+	switch {
+	case encodedHash == "":
+		return frameworkconstants.ErrInvalidPassword
+	case strings.HasPrefix(encodedHash, argon2idHashPrefix):
+		params, salt, key, err := decodeArgon2idHash(encodedHash)
+		if err != nil {
+			return err
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.MemoryKiB, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return frameworkconstants.ErrInvalidPassword
+		}
+		return nil
+	case isBcryptHash(encodedHash):
+		if err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password)); err != nil {
+			if err == bcrypt.ErrMismatchedHashAndPassword {
+				return frameworkconstants.ErrInvalidPassword
+			}
+			return err
+		}
+		return nil
+	default:
+		return frameworkconstants.ErrUnsupportedPasswordHash
+	}
+}
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") ||
+		strings.HasPrefix(encodedHash, "$2b$") ||
+		strings.HasPrefix(encodedHash, "$2y$")
+}
+
+func decodeArgon2idHash(encodedHash string) (Argon2idPasswordHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 {
+		return Argon2idPasswordHasher{}, nil, nil, frameworkconstants.ErrUnsupportedPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idPasswordHasher{}, nil, nil, frameworkconstants.ErrUnsupportedPasswordHash
+	}
+
+	var params Argon2idPasswordHasher
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.MemoryKiB, &params.Iterations, &params.Parallelism); err != nil ||
+		params.Iterations == 0 || params.Parallelism == 0 {
+		return Argon2idPasswordHasher{}, nil, nil, frameworkconstants.ErrUnsupportedPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idPasswordHasher{}, nil, nil, frameworkconstants.ErrUnsupportedPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2idPasswordHasher{}, nil, nil, frameworkconstants.ErrUnsupportedPasswordHash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,118 @@
+package frameworkutils
+
+import (
+	"reflect"
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	hash := func(hasher PasswordHasher) string {
+		encodedHash, err := hasher.Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encodedHash
+	}
+
+	bcryptHasher := &BcryptPasswordHasher{Cost: bcrypt.MinCost}
+	argon2idHasher := &Argon2idPasswordHasher{MemoryKiB: 64, Iterations: 1, Parallelism: 1}
+	bcryptHash := hash(bcryptHasher)
+	argon2idHash := hash(argon2idHasher)
+
+	tests := []struct {
+		name        string
+		hasher      PasswordHasher
+		encodedHash string
+		want        bool
+	}{
+		{name: "bcrypt hash of the same cost", hasher: bcryptHasher, encodedHash: bcryptHash},
+		{name: "bcrypt hash of another cost", hasher: &BcryptPasswordHasher{Cost: bcrypt.MinCost + 1}, encodedHash: bcryptHash, want: true},
+		{name: "argon2id hash under bcrypt", hasher: bcryptHasher, encodedHash: argon2idHash, want: true},
+		{name: "argon2id hash of the same parameters", hasher: argon2idHasher, encodedHash: argon2idHash},
+		{name: "argon2id hash of another memory size", hasher: &Argon2idPasswordHasher{MemoryKiB: 128, Iterations: 1, Parallelism: 1}, encodedHash: argon2idHash, want: true},
+		{name: "argon2id hash of another iteration count", hasher: &Argon2idPasswordHasher{MemoryKiB: 64, Iterations: 2, Parallelism: 1}, encodedHash: argon2idHash, want: true},
+		{name: "argon2id hash of another thread count", hasher: &Argon2idPasswordHasher{MemoryKiB: 64, Iterations: 1, Parallelism: 2}, encodedHash: argon2idHash, want: true},
+		{name: "bcrypt hash under argon2id", hasher: argon2idHasher, encodedHash: bcryptHash, want: true},
+		{name: "malformed argon2id hash", hasher: argon2idHasher, encodedHash: "$argon2id$v=19$m=64,t=1,p=1$salt", want: true},
+		{name: "empty hash", hasher: bcryptHasher, encodedHash: "", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.hasher.NeedsRehash(tt.encodedHash); got != tt.want {
+			t.Errorf("%s: NeedsRehash() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyPasswordHash(t *testing.T) {
+	hash := func(hasher PasswordHasher) string {
+		encodedHash, err := hasher.Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encodedHash
+	}
+
+	bcryptHash := hash(&BcryptPasswordHasher{Cost: bcrypt.MinCost})
+	argon2idHash := hash(&Argon2idPasswordHasher{MemoryKiB: 64, Iterations: 1, Parallelism: 1})
+
+	tests := []struct {
+		name        string
+		password    string
+		encodedHash string
+		want        error
+	}{
+		{name: "bcrypt", password: "correct horse", encodedHash: bcryptHash},
+		{name: "bcrypt wrong password", password: "battery staple", encodedHash: bcryptHash, want: frameworkconstants.ErrInvalidPassword},
+		{name: "argon2id", password: "correct horse", encodedHash: argon2idHash},
+		{name: "argon2id wrong password", password: "battery staple", encodedHash: argon2idHash, want: frameworkconstants.ErrInvalidPassword},
+		{name: "no password set", password: "", encodedHash: "", want: frameworkconstants.ErrInvalidPassword},
+		{name: "unknown algorithm", password: "correct horse", encodedHash: "$1$md5crypt", want: frameworkconstants.ErrUnsupportedPasswordHash},
+		{name: "unknown argon2 version", password: "correct horse", encodedHash: "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5", want: frameworkconstants.ErrUnsupportedPasswordHash},
+	}
+
+	for _, tt := range tests {
+		if got := VerifyPasswordHash(tt.password, tt.encodedHash); got != tt.want {
+			t.Errorf("%s: VerifyPasswordHash() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	tests := []struct {
+		name    string
+		hashing frameworkdto.PasswordHashingConfig
+		want    PasswordHasher
+		wantErr bool
+	}{
+		{name: "default", want: &BcryptPasswordHasher{Cost: bcrypt.DefaultCost}},
+		{name: "bcrypt cost", hashing: frameworkdto.PasswordHashingConfig{Algorithm: frameworkdto.PasswordHashAlgorithmBcrypt, BcryptCost: 12}, want: &BcryptPasswordHasher{Cost: 12}},
+		{name: "bcrypt cost too high", hashing: frameworkdto.PasswordHashingConfig{BcryptCost: bcrypt.MaxCost + 1}, wantErr: true},
+		{
+			name:    "argon2id defaults",
+			hashing: frameworkdto.PasswordHashingConfig{Algorithm: frameworkdto.PasswordHashAlgorithmArgon2id},
+			want: &Argon2idPasswordHasher{
+				MemoryKiB:   frameworkconstants.DefaultArgon2idMemoryKiB,
+				Iterations:  frameworkconstants.DefaultArgon2idIterations,
+				Parallelism: frameworkconstants.DefaultArgon2idParallelism,
+			},
+		},
+		{name: "argon2id too little memory", hashing: frameworkdto.PasswordHashingConfig{Algorithm: frameworkdto.PasswordHashAlgorithmArgon2id, Argon2idMemoryKiB: 8, Argon2idParallelism: 2}, wantErr: true},
+		{name: "unknown algorithm", hashing: frameworkdto.PasswordHashingConfig{Algorithm: "md5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NewPasswordHasher(&frameworkdto.FrameworkConfig{PasswordHashing: tt.hashing})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: NewPasswordHasher() error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: NewPasswordHasher() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package frameworkutils
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+)
+
+//go:embed common-passwords.txt
+var commonPasswordList string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// PasswordPolicyError is returned when a password breaks one or more policy rules
+type PasswordPolicyError struct {
+	Violations []frameworkdto.PasswordPolicyViolationDTO
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password does not meet the password policy: " + strings.Join(messages, "; ")
+}
+
+// DefaultPasswordPolicy is used when FrameworkConfig.PasswordPolicy is not set
+func DefaultPasswordPolicy() frameworkdto.PasswordPolicyDTO {
+	return frameworkdto.PasswordPolicyDTO{
+		MinLength:               frameworkconstants.DefaultPasswordMinLength,
+		MaxLength:               frameworkconstants.MaxPasswordBytes,
+		DisallowCommonPasswords: true,
+		DisallowUserInfo:        true,
+	}
+}
+
+// NormalizePasswordPolicy fills in a missing maximum length and clamps the lengths, history and age to supported values
+func NormalizePasswordPolicy(policy frameworkdto.PasswordPolicyDTO) frameworkdto.PasswordPolicyDTO {
+	if policy.MaxLength <= 0 || policy.MaxLength > frameworkconstants.MaxPasswordBytes {
+		policy.MaxLength = frameworkconstants.MaxPasswordBytes
+	}
+	if policy.MinLength < 1 {
+		policy.MinLength = 1
+	}
+	if policy.PasswordHistoryCount < 0 {
+		policy.PasswordHistoryCount = 0
+	} else if policy.PasswordHistoryCount > frameworkconstants.MaxPasswordHistoryCount {
+		policy.PasswordHistoryCount = frameworkconstants.MaxPasswordHistoryCount
+	}
+	if policy.MaxPasswordAgeDays < 0 {
+		policy.MaxPasswordAgeDays = 0
+	}
+	return policy
+}
+
+// ValidatePassword checks a password against the policy. userInfo holds the email address and
+// names of the user the password is for. It returns nil when the password is acceptable.
+func ValidatePassword(policy frameworkdto.PasswordPolicyDTO, password string, userInfo ...string) error {
+	policy = NormalizePasswordPolicy(policy)
+	var violations []frameworkdto.PasswordPolicyViolationDTO
+	violate := func(rule, message string) {
+		violations = append(violations, frameworkdto.PasswordPolicyViolationDTO{Rule: rule, Message: message})
+	}
+
+	if length := len([]rune(password)); length < policy.MinLength {
+		violate("min_length", fmt.Sprintf("must be at least %d characters long", policy.MinLength))
+	}
+	if len(password) > policy.MaxLength {
+		violate("max_length", fmt.Sprintf("must be at most %d bytes long", policy.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUppercase && !hasUpper {
+		violate("require_uppercase", "must contain an uppercase letter")
+	}
+	if policy.RequireLowercase && !hasLower {
+		violate("require_lowercase", "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		violate("require_digit", "must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		violate("require_symbol", "must contain a symbol")
+	}
+
+	if policy.DisallowCommonPasswords && IsCommonPassword(password) {
+		violate("common_password", "is too common")
+	}
+	if policy.DisallowUserInfo && containsUserInfo(password, userInfo) {
+		violate("user_info", "must not contain your email address or name")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// IsCommonPassword reports whether the password, ignoring case and trailing digits or symbols,
+// is on the bundled list of commonly used passwords
+func IsCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		scanner := bufio.NewScanner(strings.NewReader(commonPasswordList))
+		for scanner.Scan() {
+			if entry := strings.TrimSpace(scanner.Text()); entry != "" {
+				commonPasswords[strings.ToLower(entry)] = struct{}{}
+			}
+		}
+	})
+
+	candidate := strings.ToLower(password)
+	if _, ok := commonPasswords[candidate]; ok {
+		return true
+	}
+
+	base := strings.TrimRightFunc(candidate, func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(base) >= 4 {
+		if _, ok := commonPasswords[base]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsUserInfo(password string, userInfo []string) bool {
+	candidate := strings.ToLower(password)
+	for _, info := range userInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if info == "" {
+			continue
+		}
+
+		parts := []string{info}
+		if local, _, found := strings.Cut(info, "@"); found {
+			parts = append(parts, local)
+		}
+
+		for _, part := range parts {
+			if len(part) >= 3 && strings.Contains(candidate, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
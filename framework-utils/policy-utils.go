@@ -0,0 +1,23 @@
+package frameworkutils
+
+import (
+	"time"
+
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyResourceLoader returns the resource a request acts on, for access policies to inspect
+// as resource attributes. The resource can be a struct or a map that encodes to a JSON object,
+// or nil. Returning a *frameworkdto.ResponseErrorDTO, such as NotFound, sends that error.
+type PolicyResourceLoader func(c *gin.Context) (any, error)
+
+// GetPolicyRequest returns the request attributes access policies can test
+func GetPolicyRequest(c *gin.Context) frameworkdto.PolicyRequestDTO {
+	return frameworkdto.PolicyRequestDTO{
+		Method: c.Request.Method,
+		Path:   c.Request.URL.Path,
+		IP:     c.ClientIP(),
+		Time:   time.Now(),
+	}
+}
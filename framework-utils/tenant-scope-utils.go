@@ -0,0 +1,149 @@
+package frameworkutils
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// TenantScoped is embedded in host application models whose rows belong to a tenant. Queries,
+// updates and deletes on such models only see the rows of the tenant in the query's context,
+// and inserts are stamped with that tenant.
+type TenantScoped struct {
+	TenantID uint `gorm:"index;not null" json:"tenant_id"`
+}
+
+func (TenantScoped) tenantScoped() {}
+
+type tenantScopedModel interface {
+	tenantScoped()
+}
+
+type tenantIDContextKey struct{}
+
+const allTenantsSetting = "serviceframework:all_tenants"
+
+// WithTenantID returns a context scoping tenant-scoped queries to the tenant. The auth
+// middleware sets it on every authenticated request; background jobs must set it themselves.
+func WithTenantID(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, tenantIDContextKey{}, tenantID)
+}
+
+// TenantIDFromContext returns the tenant queries made with the context are scoped to
+func TenantIDFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok := ctx.Value(tenantIDContextKey{}).(uint)
+	return tenantID, ok
+}
+
+// TenantSchemaName returns the PostgreSQL schema holding the tenant's data under
+// schema-per-tenant isolation
+func TenantSchemaName(tenantID uint) string {
+	return "tenant_" + strconv.FormatUint(uint64(tenantID), 10)
+}
+
+// AllTenants lifts tenant scoping from the queries made with the returned DB, for platform-wide
+// work such as super admin reports. Callers are responsible for checking the caller may see
+// every tenant's data.
+func AllTenants(db *gorm.DB) *gorm.DB {
+	return db.Set(allTenantsSetting, true)
+}
+
+// TenantScopePlugin is the GORM plugin enforcing TenantScoped. Raw SQL is never scoped.
+type TenantScopePlugin struct{}
+
+func (TenantScopePlugin) Name() string {
+	return "serviceframework:tenant_scope"
+}
+
+func (TenantScopePlugin) Initialize(db *gorm.DB) error {
+	const name = "serviceframework:tenant_scope"
+	if err := db.Callback().Create().Before("gorm:create").Register(name, stampTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register(name, filterTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register(name, func(db *gorm.DB) {
+		stampTenant(db)
+		filterTenant(db)
+	}); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register(name, filterTenant); err != nil {
+		return err
+	}
+	return db.Callback().Row().Before("gorm:row").Register(name, filterTenant)
+}
+
+// tenantScope returns the tenant field and tenant a statement must be limited to. Statements on
+// tenant-scoped models without a tenant in their context fail rather than see every tenant.
+func tenantScope(db *gorm.DB) (*schema.Field, uint, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, 0, false
+	}
+	if _, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(tenantScopedModel); !ok {
+		return nil, 0, false
+	}
+	if allTenants, _ := db.Get(allTenantsSetting); allTenants == true {
+		return nil, 0, false
+	}
+
+	tenantID, ok := TenantIDFromContext(db.Statement.Context)
+	if !ok {
+		db.AddError(frameworkconstants.ErrTenantScopeMissing)
+		return nil, 0, false
+	}
+	return db.Statement.Schema.LookUpField("TenantID"), tenantID, true
+}
+
+func filterTenant(db *gorm.DB) {
+	field, tenantID, ok := tenantScope(db)
+	if !ok {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// stampTenant sets the tenant on new or saved records, refusing records of another tenant
+func stampTenant(db *gorm.DB) {
+	field, tenantID, ok := tenantScope(db)
+	if !ok {
+		return
+	}
+
+	records := db.Statement.ReflectValue
+	switch records.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < records.Len(); i++ {
+			stampRecord(db, field, reflect.Indirect(records.Index(i)), tenantID)
+		}
+	case reflect.Struct:
+		stampRecord(db, field, records, tenantID)
+	}
+}
+
+func stampRecord(db *gorm.DB, field *schema.Field, record reflect.Value, tenantID uint) {
+	if record.Kind() != reflect.Struct {
+		return
+	}
+
+	value, isZero := field.ValueOf(db.Statement.Context, record)
+	if isZero {
+		if err := field.Set(db.Statement.Context, record, tenantID); err != nil {
+			db.AddError(err)
+		}
+	} else if value != tenantID {
+		db.AddError(frameworkconstants.ErrTenantMismatch)
+	}
+}
@@ -0,0 +1,246 @@
+package frameworkutils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type scopedNote struct {
+	gorm.Model
+	TenantScoped
+	Body string
+}
+
+type unscopedNote struct {
+	gorm.Model
+	TenantID uint
+	Body     string
+}
+
+// newTenantScopeTestDB returns a database using the plugin holding notes "a" and "b" of
+// tenant 1 and "c" of tenant 2, scoped and unscoped
+func newTenantScopeTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.Use(TenantScopePlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&scopedNote{}, &unscopedNote{}); err != nil {
+		t.Fatal(err)
+	}
+
+	notes := []scopedNote{
+		{TenantScoped: TenantScoped{TenantID: 1}, Body: "a"},
+		{TenantScoped: TenantScoped{TenantID: 1}, Body: "b"},
+		{TenantScoped: TenantScoped{TenantID: 2}, Body: "c"},
+	}
+	if err := AllTenants(db).Create(&notes).Error; err != nil {
+		t.Fatal(err)
+	}
+	unscoped := []unscopedNote{{TenantID: 1, Body: "a"}, {TenantID: 1, Body: "b"}, {TenantID: 2, Body: "c"}}
+	if err := db.Create(&unscoped).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// noteKeys returns the notes as tenant:body
+func noteKeys(notes []scopedNote) []string {
+	keys := make([]string, 0, len(notes))
+	for _, note := range notes {
+		keys = append(keys, fmt.Sprintf("%d:%s", note.TenantID, note.Body))
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestTenantScopePlugin(t *testing.T) {
+	tenant1 := WithTenantID(context.Background(), 1)
+
+	tests := []struct {
+		name string
+		// run performs the operation on db and returns the notes to compare, or nil to
+		// compare every tenant's notes afterwards
+		run     func(db *gorm.DB) ([]scopedNote, error)
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "query without a tenant",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var notes []scopedNote
+				return notes, db.Find(&notes).Error
+			},
+			wantErr: frameworkconstants.ErrTenantScopeMissing,
+		},
+		{
+			name: "query",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var notes []scopedNote
+				return notes, db.WithContext(tenant1).Where("body <> ?", "b").Find(&notes).Error
+			},
+			want: []string{"1:a"},
+		},
+		{
+			name: "query of another tenant's record",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var note scopedNote
+				return nil, db.WithContext(tenant1).Where("body = ?", "c").First(&note).Error
+			},
+			wantErr: gorm.ErrRecordNotFound,
+		},
+		{
+			name: "query of all tenants",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var notes []scopedNote
+				return notes, AllTenants(db).Find(&notes).Error
+			},
+			want: []string{"1:a", "1:b", "2:c"},
+		},
+		{
+			name: "count",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var count int64
+				if err := db.WithContext(tenant1).Model(&scopedNote{}).Count(&count).Error; err != nil {
+					return nil, err
+				}
+				if count != 2 {
+					return nil, fmt.Errorf("counted %d notes", count)
+				}
+				return []scopedNote{}, nil
+			},
+			want: []string{},
+		},
+		{
+			name: "row",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var count int64
+				if err := db.WithContext(tenant1).Model(&scopedNote{}).Select("count(*)").Row().Scan(&count); err != nil {
+					return nil, err
+				}
+				if count != 2 {
+					return nil, fmt.Errorf("counted %d notes", count)
+				}
+				return []scopedNote{}, nil
+			},
+			want: []string{},
+		},
+		{
+			name: "unscoped model",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var notes []unscopedNote
+				if err := db.Find(&notes).Error; err != nil {
+					return nil, err
+				}
+				if len(notes) != 3 {
+					return nil, fmt.Errorf("found %d notes", len(notes))
+				}
+				return []scopedNote{}, nil
+			},
+			want: []string{},
+		},
+		{
+			name: "create",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				return nil, db.WithContext(tenant1).Create(&scopedNote{Body: "d"}).Error
+			},
+			want: []string{"1:a", "1:b", "1:d", "2:c"},
+		},
+		{
+			name: "create of several",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				return nil, db.WithContext(tenant1).Create([]*scopedNote{{Body: "d"}, {Body: "e"}}).Error
+			},
+			want: []string{"1:a", "1:b", "1:d", "1:e", "2:c"},
+		},
+		{
+			name: "create for another tenant",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				return nil, db.WithContext(tenant1).Create(&scopedNote{TenantScoped: TenantScoped{TenantID: 2}, Body: "d"}).Error
+			},
+			wantErr: frameworkconstants.ErrTenantMismatch,
+		},
+		{
+			name: "create without a tenant",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				return nil, db.Create(&scopedNote{Body: "d"}).Error
+			},
+			wantErr: frameworkconstants.ErrTenantScopeMissing,
+		},
+		{
+			name: "update",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				return nil, db.WithContext(tenant1).Model(&scopedNote{}).Where("1 = 1").Update("body", "x").Error
+			},
+			want: []string{"1:x", "1:x", "2:c"},
+		},
+		{
+			name: "save of another tenant's record",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				var note scopedNote
+				if err := AllTenants(db).Where("body = ?", "c").First(&note).Error; err != nil {
+					return nil, err
+				}
+				note.Body = "x"
+				return nil, db.WithContext(tenant1).Save(&note).Error
+			},
+			wantErr: frameworkconstants.ErrTenantMismatch,
+		},
+		{
+			name: "delete",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				return nil, db.WithContext(WithTenantID(context.Background(), 2)).Where("1 = 1").Delete(&scopedNote{}).Error
+			},
+			want: []string{"1:a", "1:b"},
+		},
+		{
+			name: "delete without a tenant",
+			run: func(db *gorm.DB) ([]scopedNote, error) {
+				return nil, db.Where("1 = 1").Delete(&scopedNote{}).Error
+			},
+			wantErr: frameworkconstants.ErrTenantScopeMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTenantScopeTestDB(t)
+
+			notes, err := tt.run(db)
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				// A refused statement changes nothing
+				notes, tt.want = nil, []string{"1:a", "1:b", "2:c"}
+			}
+			if notes == nil {
+				if err := AllTenants(db).Find(&notes).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := noteKeys(notes); !slices.Equal(got, tt.want) {
+				t.Errorf("notes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package frameworkutils
 
 import (
 	"errors"
+	"time"
 
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
@@ -21,3 +22,104 @@ func GetTokenDTO(c *gin.Context) (frameworkdto.TokenDTO, error) {
 
 	return tokenDTO, nil
 }
+
+// GetResolvedTenant returns the tenant the request was addressed to through its host or the
+// X-Tenant-ID header. It is set before authentication, so it is available on public routes.
+func GetResolvedTenant(c *gin.Context) (frameworkdto.ResolvedTenantDTO, bool) {
+	tenantRaw, ok := c.Get(frameworkconstants.ResolvedTenantKey)
+	if !ok {
+		return frameworkdto.ResolvedTenantDTO{}, false
+	}
+
+	tenant, ok := tenantRaw.(frameworkdto.ResolvedTenantDTO)
+	return tenant, ok
+}
+
+// IsServicePrincipal reports whether the token was issued for an API key rather than a user
+func IsServicePrincipal(tokenDto frameworkdto.TokenDTO) bool {
+	return tokenDto.PrincipalType == frameworkconstants.PrincipalTypeService
+}
+
+// IsImpersonating reports whether the token was issued to a super admin acting as another user
+func IsImpersonating(tokenDto frameworkdto.TokenDTO) bool {
+	return tokenDto.Act != nil
+}
+
+// AllowDuringImpersonation returns middleware, to be placed before the auth middleware, that lets
+// impersonation tokens make changes on the route. Without it only GET, HEAD and OPTIONS
+// requests are accepted while impersonating.
+func AllowDuringImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(frameworkconstants.ImpersonationAllowedKey, true)
+		c.Next()
+	}
+}
+
+// IsRecentlyAuthenticated reports whether the user logged in or re-authenticated within maxAge.
+// API keys and impersonation tokens never count as recently authenticated.
+func IsRecentlyAuthenticated(tokenDto frameworkdto.TokenDTO, maxAge time.Duration) bool {
+	if IsServicePrincipal(tokenDto) || IsImpersonating(tokenDto) || tokenDto.AuthTime == 0 {
+		return false
+	}
+	return time.Since(time.Unix(tokenDto.AuthTime, 0)) <= maxAge
+}
+
+// RequireRecentAuthentication returns middleware, to be placed after the auth middleware, that
+// marks a route as sensitive. Callers who have not logged in or re-authenticated within maxAge
+// get HTTP 401 with the code REAUTHENTICATION_REQUIRED and must call
+// /authentication/reauthenticate before retrying.
+func RequireRecentAuthentication(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenDto, err := GetTokenDTO(c)
+		if err != nil {
+			ErrorResponse(c, UnauthorizedError("Unauthorized"))
+			c.Abort()
+			return
+		}
+
+		if !IsRecentlyAuthenticated(tokenDto, maxAge) {
+			ErrorResponse(c, ReauthenticationRequired(maxAge))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasScope reports whether the caller may use the given scope. User tokens are governed by
+// their role and are not restricted by scopes.
+func HasScope(tokenDto frameworkdto.TokenDTO, scope string) bool {
+	if !IsServicePrincipal(tokenDto) {
+		return true
+	}
+	for _, granted := range tokenDto.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScopes returns middleware, to be placed after the auth middleware, that rejects API
+// keys missing any of the given scopes
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenDto, err := GetTokenDTO(c)
+		if err != nil {
+			ErrorResponse(c, UnauthorizedError("Unauthorized"))
+			c.Abort()
+			return
+		}
+
+		for _, scope := range scopes {
+			if !HasScope(tokenDto, scope) {
+				ErrorResponse(c, Forbidden("API key is missing the "+scope+" scope"))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
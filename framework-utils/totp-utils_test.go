@@ -0,0 +1,80 @@
+package frameworkutils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// The RFC 6238 SHA-1 test vectors, truncated to six digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := GenerateTOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateTOTPCode(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// 1111111111 is step 37037037, one second into its period
+	now := time.Unix(1111111111, 0)
+	const step = 37037037
+
+	codeAt := func(offset time.Duration) string {
+		code, err := GenerateTOTPCode(rfc6238Secret, now.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current period", code: codeAt(0), wantStep: step, wantOK: true},
+		{name: "previous period", code: codeAt(-totpPeriod * time.Second), wantStep: step - 1, wantOK: true},
+		{name: "next period", code: codeAt(totpPeriod * time.Second), wantStep: step + 1, wantOK: true},
+		{name: "two periods ago", code: codeAt(-2 * totpPeriod * time.Second)},
+		{name: "two periods ahead", code: codeAt(2 * totpPeriod * time.Second)},
+		{name: "surrounding whitespace", code: " " + codeAt(0) + "\n", wantStep: step, wantOK: true},
+		{name: "lower-case padded secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq====", code: codeAt(0), wantStep: step, wantOK: true},
+		{name: "wrong code", code: "000000"},
+		{name: "too short", code: codeAt(0)[:5]},
+		{name: "too long", code: codeAt(0) + "0"},
+		{name: "empty", code: ""},
+		{name: "invalid secret", secret: "not base32!", code: codeAt(0)},
+	}
+
+	for _, tt := range tests {
+		secret := tt.secret
+		if secret == "" {
+			secret = rfc6238Secret
+		}
+
+		gotStep, gotOK := ValidateTOTP(secret, tt.code, now)
+		if gotOK != tt.wantOK || gotStep != tt.wantStep {
+			t.Errorf("%s: ValidateTOTP() = %d, %v, want %d, %v", tt.name, gotStep, gotOK, tt.wantStep, tt.wantOK)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
 	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"github.com/geekible-ltd/serviceframework/internal/repositories"
 	"github.com/gin-gonic/gin"
@@ -30,21 +32,102 @@ func NewFrameworkConfig(cfg *frameworkdto.FrameworkConfig) *FrameworkConfigurati
 		fc.db = connectToSQLite(cfg)
 	}
 
-	err := fc.db.AutoMigrate(&entities.Tenant{}, &entities.User{}, &entities.TenantLicence{}, &entities.LicenceType{})
-	if err != nil {
+	if err := MigrateDatabase(fc.db); err != nil {
 		panic(err)
 	}
 
-	licenceTypeRepo := repositories.NewLicenceTypeRepository(fc.db)
+	if cfg.TenantIsolation == frameworkdto.TenantIsolationRowLevelSecurity {
+		if err := enableRowLevelSecurity(repositories.NewTenantDatabaseRepository(fc.db)); err != nil {
+			panic(err)
+		}
+	}
+
+	fc.router = buildGinEngine()
+
+	return &fc
+}
+
+// MigrateDatabase installs the tenant scope plugin on the connection, creates the framework's
+// tables and seeds the default licence type and the built-in roles
+func MigrateDatabase(db *gorm.DB) error {
+	if err := db.Use(frameworkutils.TenantScopePlugin{}); err != nil {
+		return err
+	}
+
+	err := db.AutoMigrate(&entities.Tenant{}, &entities.User{}, &entities.TenantLicence{}, &entities.LicenceType{}, &entities.RefreshToken{}, &entities.RevokedToken{}, &entities.MFAChallenge{}, &entities.MFARecoveryCode{}, &entities.TenantIdentityProvider{}, &entities.OIDCLoginState{}, &entities.ExternalIdentity{}, &entities.APIKey{}, &entities.Session{}, &entities.TenantPasswordPolicy{}, &entities.PasswordHistory{}, &entities.PasswordChangeChallenge{}, &entities.Impersonation{}, &entities.MagicLinkToken{}, &entities.TenantLDAPDirectory{}, &entities.Role{}, &entities.Permission{}, &entities.AccessPolicy{}, &entities.Group{}, &entities.TenantDomain{})
+	if err != nil {
+		return err
+	}
+
+	licenceTypeRepo := repositories.NewLicenceTypeRepository(db)
 	licenceTypeRepo.Create(entities.LicenceType{
 		Name:        "Free",
 		Description: "Free licence type",
 		MaxSeats:    1,
 	}, true)
 
-	fc.router = buildGinEngine()
+	return seedRoles(repositories.NewRoleRepository(db))
+}
 
-	return &fc
+// seedRoles creates the built-in permissions and roles and grants the roles their defaults
+func seedRoles(roleRepo *repositories.RoleRepository) error {
+	permissions := make(map[string]entities.Permission, len(frameworkconstants.FrameworkPermissions))
+	for name, description := range frameworkconstants.FrameworkPermissions {
+		permission, err := roleRepo.SeedPermission(name, description)
+		if err != nil {
+			return err
+		}
+		permissions[name] = permission
+	}
+
+	for role, permissionNames := range frameworkconstants.DefaultRolePermissions {
+		rolePermissions := make([]entities.Permission, 0, len(permissionNames))
+		for _, name := range permissionNames {
+			rolePermissions = append(rolePermissions, permissions[name])
+		}
+		if err := roleRepo.SeedRole(string(role), frameworkconstants.RoleDescriptions[role], rolePermissions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enableRowLevelSecurity puts the framework's tenant-owned tables behind row-level security
+// policies. Built-in roles have no tenant and stay visible to every tenant.
+func enableRowLevelSecurity(tenantDatabaseRepo *repositories.TenantDatabaseRepository) error {
+	policies := []struct {
+		model       any
+		tenantField string
+		shared      bool
+	}{
+		{&entities.Tenant{}, "ID", false},
+		{&entities.User{}, "TenantID", false},
+		{&entities.TenantLicence{}, "TenantID", false},
+		{&entities.RefreshToken{}, "TenantID", false},
+		{&entities.MFAChallenge{}, "TenantID", false},
+		{&entities.TenantIdentityProvider{}, "TenantID", false},
+		{&entities.OIDCLoginState{}, "TenantID", false},
+		{&entities.ExternalIdentity{}, "TenantID", false},
+		{&entities.APIKey{}, "TenantID", false},
+		{&entities.Session{}, "TenantID", false},
+		{&entities.TenantPasswordPolicy{}, "TenantID", false},
+		{&entities.PasswordChangeChallenge{}, "TenantID", false},
+		{&entities.Impersonation{}, "TargetTenantID", false},
+		{&entities.MagicLinkToken{}, "TenantID", false},
+		{&entities.TenantLDAPDirectory{}, "TenantID", false},
+		{&entities.Role{}, "TenantID", true},
+		{&entities.AccessPolicy{}, "TenantID", false},
+		{&entities.Group{}, "TenantID", false},
+		{&entities.TenantDomain{}, "TenantID", false},
+	}
+
+	for _, policy := range policies {
+		if err := tenantDatabaseRepo.EnableRowLevelSecurity(policy.model, policy.tenantField, policy.shared); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func connectToMySQL(cfg *frameworkdto.FrameworkConfig) *gorm.DB {
@@ -115,6 +198,11 @@ func connectToPostgreSQL(cfg *frameworkdto.FrameworkConfig) *gorm.DB {
 		cfg.DbCfg.Password,
 		cfg.DbCfg.Database,
 		cfg.DbCfg.SSLMode)
+	if cfg.TenantIsolation == frameworkdto.TenantIsolationRowLevelSecurity {
+		// Row-level security policies fail closed, so the framework's own connections, which
+		// look users up across tenants, bypass them for the whole session
+		dsn += fmt.Sprintf(" options='-c %s=on'", frameworkconstants.BypassRowLevelSecuritySetting)
+	}
 	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		panic(err)
@@ -0,0 +1,19 @@
+package entities
+
+import "gorm.io/gorm"
+
+// AccessPolicy is a tenant's attribute-based rule for an action. Its condition is an
+// expression over the subject, the request and the resource.
+type AccessPolicy struct {
+	gorm.Model
+	TenantID    uint   `json:"tenant_id" gorm:"not null;index"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	// Action is the action the policy governs, such as documents:update, documents:* or *
+	Action    string `json:"action" gorm:"not null;index"`
+	Effect    string `json:"effect" gorm:"not null"`
+	Condition string `json:"condition" gorm:"type:text;not null"`
+	Enabled   bool   `json:"enabled"`
+
+	Tenant Tenant `json:"tenant" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
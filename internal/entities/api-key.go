@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKey struct {
+	gorm.Model
+	TenantID        uint   `json:"tenant_id" gorm:"not null;index"`
+	CreatedByUserID uint   `json:"created_by_user_id" gorm:"not null"`
+	Name            string `json:"name" gorm:"not null"`
+	// KeyPrefix is the first characters of the key, kept so admins can tell keys apart
+	KeyPrefix  string     `json:"key_prefix" gorm:"not null"`
+	KeyHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	Scopes     string     `json:"scopes" gorm:"not null"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+
+	Tenant Tenant `json:"tenant" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
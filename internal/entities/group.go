@@ -0,0 +1,18 @@
+package entities
+
+import "gorm.io/gorm"
+
+// Group is a team of users within a tenant. Groups can be nested: the members of a group also
+// belong to its ancestors, and hold the roles of the group and its ancestors on top of their own.
+type Group struct {
+	gorm.Model
+	TenantID    uint   `json:"tenant_id" gorm:"not null;uniqueIndex:idx_groups_tenant_name"`
+	Name        string `json:"name" gorm:"not null;uniqueIndex:idx_groups_tenant_name"`
+	Description string `json:"description"`
+	// ParentID nests the group inside another group of the same tenant
+	ParentID *uint `json:"parent_id" gorm:"index"`
+
+	Tenant  Tenant `json:"tenant" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Roles   []Role `json:"roles" gorm:"many2many:group_roles;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Members []User `json:"members" gorm:"many2many:group_members;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TenantIdentityProvider is the OpenID Connect provider a tenant's users can sign in with
+type TenantIdentityProvider struct {
+	gorm.Model
+	TenantID             uint   `json:"tenant_id" gorm:"not null;uniqueIndex"`
+	Issuer               string `json:"issuer" gorm:"not null"`
+	ClientID             string `json:"client_id" gorm:"not null"`
+	ClientSecret         string `json:"-"`
+	RedirectURL          string `json:"redirect_url" gorm:"not null"`
+	Scopes               string `json:"scopes"`
+	DefaultRole          string `json:"default_role"`
+	AllowJITProvisioning bool   `json:"allow_jit_provisioning"`
+	IsEnabled            bool   `json:"is_enabled"`
+
+	Tenant Tenant `json:"tenant" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// OIDCLoginState holds the server side half of an in-flight authorization code + PKCE flow
+type OIDCLoginState struct {
+	gorm.Model
+	TenantID     uint       `json:"tenant_id" gorm:"not null"`
+	StateHash    string     `json:"-" gorm:"not null;uniqueIndex"`
+	Nonce        string     `json:"-" gorm:"not null"`
+	CodeVerifier string     `json:"-" gorm:"not null"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt       *time.Time `json:"used_at"`
+}
+
+// ExternalIdentity links a user to the subject identifier issued by an external identity provider.
+// Providers such as Google or Entra ID are shared by many tenants, so one subject can be linked
+// to a user in each tenant it signs in to.
+type ExternalIdentity struct {
+	gorm.Model
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	TenantID uint   `json:"tenant_id" gorm:"not null;uniqueIndex:idx_external_identity_subject"`
+	Issuer   string `json:"issuer" gorm:"not null;uniqueIndex:idx_external_identity_subject"`
+	Subject  string `json:"subject" gorm:"not null;uniqueIndex:idx_external_identity_subject"`
+
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Impersonation is the audit record of a super admin acting as another user
+type Impersonation struct {
+	gorm.Model
+	ImpersonatorID       uint       `json:"impersonator_id" gorm:"not null;index"`
+	ImpersonatorTenantID uint       `json:"impersonator_tenant_id" gorm:"not null"`
+	ImpersonatorEmail    string     `json:"impersonator_email"`
+	TargetUserID         uint       `json:"target_user_id" gorm:"not null;index"`
+	TargetTenantID       uint       `json:"target_tenant_id" gorm:"not null;index"`
+	Reason               string     `json:"reason"`
+	IPAddress            string     `json:"ip_address"`
+	ExpiresAt            time.Time  `json:"expires_at" gorm:"not null"`
+	EndedAt              *time.Time `json:"ended_at"`
+}
@@ -0,0 +1,28 @@
+package entities
+
+import "gorm.io/gorm"
+
+// TenantLDAPDirectory is the LDAP or Active Directory server a tenant's users log in against
+// instead of their framework password
+type TenantLDAPDirectory struct {
+	gorm.Model
+	TenantID           uint                   `json:"tenant_id" gorm:"not null;uniqueIndex"`
+	URL                string                 `json:"url" gorm:"not null"`
+	StartTLS           bool                   `json:"start_tls"`
+	BindDN             string                 `json:"bind_dn"`
+	BindPassword       string                 `json:"-"`
+	BaseDN             string                 `json:"base_dn" gorm:"not null"`
+	UserFilter         string                 `json:"user_filter"`
+	FirstNameAttribute string                 `json:"first_name_attribute"`
+	LastNameAttribute  string                 `json:"last_name_attribute"`
+	GroupAttribute     string                 `json:"group_attribute"`
+	GroupRoleMappings  []LDAPGroupRoleMapping `json:"group_role_mappings" gorm:"serializer:json"`
+	IsEnabled          bool                   `json:"is_enabled"`
+
+	Tenant Tenant `json:"tenant" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type LDAPGroupRoleMapping struct {
+	Group string `json:"group"`
+	Role  string `json:"role"`
+}
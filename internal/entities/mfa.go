@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MFAChallenge is the pending second step of a login, identified by a hashed single-use token
+type MFAChallenge struct {
+	gorm.Model
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	TenantID       uint       `json:"tenant_id" gorm:"not null"`
+	TokenHash      string     `json:"-" gorm:"not null;uniqueIndex"`
+	IPAddress      string     `json:"ip_address"`
+	FailedAttempts int        `json:"failed_attempts"`
+	AuthMethods    string     `json:"auth_methods"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt         *time.Time `json:"used_at"`
+
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type MFARecoveryCode struct {
+	gorm.Model
+	UserID   uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash string     `json:"-" gorm:"not null;index"`
+	UsedAt   *time.Time `json:"used_at"`
+
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
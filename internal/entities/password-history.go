@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordHistory keeps the hash of a password the user has since replaced
+type PasswordHistory struct {
+	gorm.Model
+	UserID       uint   `json:"user_id" gorm:"not null;index"`
+	PasswordHash string `json:"-" gorm:"not null"`
+
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// PasswordChangeChallenge is a login paused because the password has expired, identified by a
+// hashed single-use token that can only be exchanged for a new password
+type PasswordChangeChallenge struct {
+	gorm.Model
+	UserID    uint   `json:"user_id" gorm:"not null;index"`
+	TenantID  uint   `json:"tenant_id" gorm:"not null"`
+	TokenHash string `json:"-" gorm:"not null;uniqueIndex"`
+	IPAddress string `json:"ip_address"`
+	// AuthMethods are the factors completed before the login was paused
+	AuthMethods string     `json:"auth_methods"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt      *time.Time `json:"used_at"`
+
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -0,0 +1,14 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RevokedToken struct {
+	gorm.Model
+	Jti       string    `json:"jti" gorm:"not null;uniqueIndex"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+}
@@ -0,0 +1,23 @@
+package entities
+
+import "gorm.io/gorm"
+
+// Role is a named set of permissions. Users reference their role by name. Built-in roles have
+// no tenant; custom roles belong to the tenant that created them.
+type Role struct {
+	gorm.Model
+	TenantID    *uint  `json:"tenant_id" gorm:"uniqueIndex:idx_roles_tenant_name"`
+	Name        string `json:"name" gorm:"not null;uniqueIndex:idx_roles_tenant_name"`
+	Description string `json:"description"`
+	// IsSystem marks the built-in roles, whose permissions are managed by the framework
+	IsSystem bool `json:"is_system"`
+
+	Tenant      *Tenant      `json:"tenant,omitempty" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+type Permission struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"not null;uniqueIndex"`
+	Description string `json:"description"`
+}
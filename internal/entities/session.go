@@ -0,0 +1,27 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a signed-in device. SessionID is carried in the sid claim of access tokens and
+// is also the family ID of the session's refresh tokens.
+type Session struct {
+	gorm.Model
+	SessionID  string     `json:"session_id" gorm:"not null;uniqueIndex"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	TenantID   uint       `json:"tenant_id" gorm:"not null;index"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	// AuthTime and AuthMethods describe the last login or re-authentication and are carried in
+	// the auth_time and amr claims of every token issued for the session
+	AuthTime    *time.Time `json:"auth_time"`
+	AuthMethods string     `json:"auth_methods"`
+
+	User User `json:"user" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -0,0 +1,21 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TenantDomain is a custom domain serving a tenant. Requests to the domain only resolve to the
+// tenant once a DNS TXT record has proved the tenant controls it. Several tenants may claim a
+// domain, but only one can hold it verified.
+type TenantDomain struct {
+	gorm.Model
+	TenantID          uint       `json:"tenant_id" gorm:"not null;uniqueIndex:idx_tenant_domains_tenant_domain"`
+	Domain            string     `json:"domain" gorm:"not null;uniqueIndex:idx_tenant_domains_tenant_domain;size:253"`
+	VerificationToken string     `json:"-" gorm:"not null"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	// VerifiedDomain repeats Domain once verified and is NULL until then, so its unique index
+	// only constrains verified claims
+	VerifiedDomain *string `json:"-" gorm:"uniqueIndex;size:253"`
+}
@@ -14,6 +14,12 @@ type TenantLicence struct {
 	UsedSeats     int        `json:"licenced_seats"`
 	ExpiryDate    *time.Time `json:"expiry_date"`
 
+	// SeatLimit replaces the licence type's MaxSeats for child tenants, whose seats are
+	// allocated from their parent's licence
+	SeatLimit *int `json:"seat_limit"`
+	// AllocatedSeats are the seats of this licence allocated to child tenants
+	AllocatedSeats int `json:"allocated_seats"`
+
 	Tenant      Tenant      `json:"tenant" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
 	LicenceType LicenceType `json:"licence_type" gorm:"foreignKey:LicenceTypeID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
 }
@@ -0,0 +1,21 @@
+package entities
+
+import "gorm.io/gorm"
+
+// TenantPasswordPolicy replaces the framework-wide password policy for one tenant
+type TenantPasswordPolicy struct {
+	gorm.Model
+	TenantID                uint `json:"tenant_id" gorm:"not null;uniqueIndex"`
+	MinLength               int  `json:"min_length"`
+	MaxLength               int  `json:"max_length"`
+	RequireUppercase        bool `json:"require_uppercase"`
+	RequireLowercase        bool `json:"require_lowercase"`
+	RequireDigit            bool `json:"require_digit"`
+	RequireSymbol           bool `json:"require_symbol"`
+	DisallowCommonPasswords bool `json:"disallow_common_passwords"`
+	DisallowUserInfo        bool `json:"disallow_user_info"`
+	PasswordHistoryCount    int  `json:"password_history_count"`
+	MaxPasswordAgeDays      int  `json:"max_password_age_days"`
+
+	Tenant Tenant `json:"tenant" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
@@ -12,6 +12,22 @@ type Tenant struct {
 	Address  string `json:"address"`
 	IsActive bool   `json:"is_active"`
 
+	// MFARequired forces every user of the tenant to complete TOTP verification at login
+	MFARequired bool `json:"mfa_required"`
+
+	// MagicLinkEnabled lets users of the tenant sign in with a single-use link sent by email
+	MagicLinkEnabled bool `json:"magic_link_enabled"`
+
+	// ParentID makes the tenant a child of a reseller tenant, which manages it and allocates its
+	// licence seats
+	ParentID *uint `json:"parent_id" gorm:"index"`
+
+	// Slug identifies the tenant in subdomains of the configured base domain and in the
+	// X-Tenant-ID header
+	Slug *string `json:"slug" gorm:"uniqueIndex;size:63"`
+
 	Users         []User         `json:"users" gorm:"foreignKey:TenantID"`
 	TenantLicence *TenantLicence `json:"tenant_licence,omitempty" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Children      []Tenant       `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	Domains       []TenantDomain `json:"domains,omitempty" gorm:"foreignKey:TenantID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
@@ -13,7 +13,10 @@ type User struct {
 	LastName                        string     `json:"last_name"`
 	Email                           string     `json:"email"`
 	PasswordHash                    string     `json:"password_hash"`
+	PasswordChangedAt               *time.Time `json:"password_changed_at"`
 	FailedLoginAttempts             int        `json:"failed_login_attempts"`
+	LockoutCount                    int        `json:"lockout_count"`
+	LockedUntil                     *time.Time `json:"locked_until"`
 	IsActive                        bool       `json:"is_active"`
 	Role                            string     `json:"role"`
 	LastLoginAt                     *time.Time `json:"last_login_at"`
@@ -23,6 +26,10 @@ type User struct {
 	IsEmailVerified                 bool       `json:"is_email_verified"`
 	EmailVerificationToken          string     `json:"email_verification_token"`
 	EmailVerificationTokenExpiresAt *time.Time `json:"email_verification_token_expires_at"`
+	TokensRevokedAt                 *time.Time `json:"tokens_revoked_at"`
+	MFAEnabled                      bool       `json:"mfa_enabled"`
+	MFASecret                       string     `json:"-"`
+	MFALastUsedStep                 int64      `json:"-"`
 
 	Tenant Tenant `json:"tenant" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	apiKeyService  *services.APIKeyService
+}
+
+func NewAPIKeyHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{authMiddleware: authMiddleware, rbacService: rbacService, apiKeyService: apiKeyService}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api-key")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.POST("/create", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionAPIKeysManage), h.Create)
+		protected.GET("/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionAPIKeysManage), h.GetAll)
+		protected.DELETE("/revoke", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionAPIKeysManage), h.Revoke)
+	}
+}
+
+// Create godoc
+// @Summary Create API key
+// @Description Mint a named API key with scopes and an optional expiry for the caller's tenant (requires the api_keys:manage permission; API keys cannot manage API keys). The key is returned once and only its hash is stored.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param createAPIKeyDTO body frameworkdto.CreateAPIKeyDTO true "API key details"
+// @Success 201 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.CreatedAPIKeyDTO} "API key created successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to create API keys"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /api-key/create [post]
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	userID, err := strconv.Atoi(tokenDto.Sub)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid User ID format"))
+		return
+	}
+
+	var createAPIKeyDTO frameworkdto.CreateAPIKeyDTO
+	if err := c.ShouldBindJSON(&createAPIKeyDTO); err != nil || strings.TrimSpace(createAPIKeyDTO.Name) == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	apiKey, err := h.apiKeyService.CreateAPIKey(tokenDto.TenantID, uint(userID), createAPIKeyDTO)
+	if err != nil {
+		apiKeyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusCreated, apiKey, "API key created successfully")
+}
+
+// GetAll godoc
+// @Summary Get all API keys
+// @Description List the API keys of the caller's tenant, including revoked and expired keys (requires the api_keys:manage permission; API keys cannot manage API keys)
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.APIKeyDTO} "API keys fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to get this resource"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /api-key/get-all [get]
+func (h *APIKeyHandler) GetAll(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	apiKeys, err := h.apiKeyService.GetAllAPIKeys(tokenDto.TenantID)
+	if err != nil {
+		apiKeyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, apiKeys, "API keys fetched successfully")
+}
+
+// Revoke godoc
+// @Summary Revoke API key
+// @Description Revoke an API key of the caller's tenant; it is rejected immediately (requires the api_keys:manage permission; API keys cannot manage API keys)
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "API key ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "API key revoked successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to revoke API keys"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "API key not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /api-key/revoke [delete]
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	id := c.Query("id")
+	if id == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
+		return
+	}
+
+	apiKeyID, err := strconv.Atoi(id)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(tokenDto.TenantID, uint(apiKeyID)); err != nil {
+		apiKeyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "API key revoked successfully")
+}
+
+func apiKeyErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case frameworkconstants.ErrInvalidAPIKeyScopes, frameworkconstants.ErrInvalidAPIKeyExpiry:
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+	case frameworkconstants.ErrAPIKeyNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("API key"))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
+}
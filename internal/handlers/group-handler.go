@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	groupService   *services.GroupService
+}
+
+func NewGroupHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, groupService *services.GroupService) *GroupHandler {
+	return &GroupHandler{authMiddleware: authMiddleware, rbacService: rbacService, groupService: groupService}
+}
+
+func (h *GroupHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/group")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.GET("/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionGroupsRead), h.GetAll)
+		protected.GET("/get-by-id", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionGroupsRead), h.GetByID)
+		protected.POST("/create", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionGroupsManage), h.Create)
+		protected.PUT("/update", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionGroupsManage), h.Update)
+		protected.DELETE("/delete", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionGroupsManage), h.Delete)
+		protected.POST("/members/add", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionGroupsManage), h.AddMembers)
+		protected.DELETE("/members/remove", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionGroupsManage), h.RemoveMember)
+	}
+}
+
+// GetAll godoc
+// @Summary Get all groups
+// @Description List the groups of the caller's tenant with the roles they grant (requires the groups:read permission)
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.GroupDTO} "Groups fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /group/get-all [get]
+func (h *GroupHandler) GetAll(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	groups, err := h.groupService.GetAllGroups(tokenDto.TenantID)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, groups, "Groups fetched successfully")
+}
+
+// GetByID godoc
+// @Summary Get a group
+// @Description Get one of the tenant's groups with its direct members (requires the groups:read permission)
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Group ID"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.GroupDetailDTO} "Group fetched successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Group not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /group/get-by-id [get]
+func (h *GroupHandler) GetByID(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	id := c.Query("id")
+	if id == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
+		return
+	}
+
+	groupID, err := strconv.Atoi(id)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return
+	}
+
+	group, err := h.groupService.GetGroup(tokenDto.TenantID, uint(groupID))
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, group, "Group fetched successfully")
+}
+
+// Create godoc
+// @Summary Create a group
+// @Description Create a group for the caller's tenant, optionally inside a parent group; the caller must be able to assign every role the group and its parents grant (requires the groups:manage permission)
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param createGroupDTO body frameworkdto.CreateGroupDTO true "Group details"
+// @Success 201 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.GroupDTO} "Group created successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, name, parent or role"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Group already exists"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /group/create [post]
+func (h *GroupHandler) Create(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var createGroupDTO frameworkdto.CreateGroupDTO
+	if err := c.ShouldBindJSON(&createGroupDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	group, err := h.groupService.CreateGroup(tokenDto, createGroupDTO)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusCreated, group, "Group created successfully")
+}
+
+// Update godoc
+// @Summary Update a group
+// @Description Rename or move a group and replace the roles it grants; a group cannot be moved inside one of its subgroups (requires the groups:manage permission)
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param updateGroupDTO body frameworkdto.UpdateGroupDTO true "Group details"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.GroupDTO} "Group updated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, name, parent or role"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Group not found"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Group already exists"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /group/update [put]
+func (h *GroupHandler) Update(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var updateGroupDTO frameworkdto.UpdateGroupDTO
+	if err := c.ShouldBindJSON(&updateGroupDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	group, err := h.groupService.UpdateGroup(tokenDto, updateGroupDTO)
+	if err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, group, "Group updated successfully")
+}
+
+// Delete godoc
+// @Summary Delete a group
+// @Description Delete a group without subgroups; its members lose the roles it granted when their tokens are next refreshed (requires the groups:manage permission)
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Group ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Group deleted successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Group not found"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Group has subgroups"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /group/delete [delete]
+func (h *GroupHandler) Delete(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	id := c.Query("id")
+	if id == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
+		return
+	}
+
+	groupID, err := strconv.Atoi(id)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return
+	}
+
+	if err := h.groupService.DeleteGroup(tokenDto, uint(groupID)); err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Group deleted successfully")
+}
+
+// AddMembers godoc
+// @Summary Add group members
+// @Description Add users of the caller's tenant to a group; they hold its roles once their tokens are next refreshed (requires the groups:manage permission)
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param addGroupMembersDTO body frameworkdto.AddGroupMembersDTO true "Group and users"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Members added successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Group or user not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /group/members/add [post]
+func (h *GroupHandler) AddMembers(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var addGroupMembersDTO frameworkdto.AddGroupMembersDTO
+	if err := c.ShouldBindJSON(&addGroupMembersDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.groupService.AddMembers(tokenDto, addGroupMembersDTO); err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Members added successfully")
+}
+
+// RemoveMember godoc
+// @Summary Remove a group member
+// @Description Remove a user from a group (requires the groups:manage permission)
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param group_id query int true "Group ID"
+// @Param user_id query int true "User ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Member removed successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Group ID and user ID are required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Group or user not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /group/members/remove [delete]
+func (h *GroupHandler) RemoveMember(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	groupIDParam, userIDParam := c.Query("group_id"), c.Query("user_id")
+	if groupIDParam == "" || userIDParam == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Group ID and user ID are required"))
+		return
+	}
+
+	groupID, err := strconv.Atoi(groupIDParam)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return
+	}
+	userID, err := strconv.Atoi(userIDParam)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return
+	}
+
+	if err := h.groupService.RemoveMember(tokenDto, uint(groupID), uint(userID)); err != nil {
+		groupErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Member removed successfully")
+}
+
+func groupErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case frameworkconstants.ErrInvalidGroup, frameworkconstants.ErrRoleNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+	case frameworkconstants.ErrPermissionNotHeld:
+		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden(err.Error()))
+	case frameworkconstants.ErrGroupNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Group"))
+	case frameworkconstants.ErrUserNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+	case frameworkconstants.ErrGroupAlreadyExists, frameworkconstants.ErrGroupHasSubgroups:
+		frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationHandler struct {
+	authMiddleware       gin.HandlerFunc
+	rbacService          *services.RBACService
+	impersonationService *services.ImpersonationService
+}
+
+func NewImpersonationHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, impersonationService *services.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{authMiddleware: authMiddleware, rbacService: rbacService, impersonationService: impersonationService}
+}
+
+func (h *ImpersonationHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/impersonation")
+	api.POST("/stop", frameworkutils.AllowDuringImpersonation(), h.authMiddleware, h.Stop)
+
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.POST("/start", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionImpersonationManage), h.Start)
+		protected.GET("/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionImpersonationManage), h.GetAll)
+	}
+}
+
+// Start godoc
+// @Summary Start impersonating a user
+// @Description Issue a short-lived token for a tenant user so support staff can see what the user sees (requires the impersonation:manage permission). The token carries an act claim naming the super admin, is revoked with the super admin's session, has no refresh token and cannot change data except on routes that allow it. The impersonation is recorded in the audit trail.
+// @Tags Impersonation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param startRequest body frameworkdto.StartImpersonationDTO true "Target user and reason"
+// @Success 201 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.ImpersonationTokenDTO} "Impersonation started"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized or user cannot be impersonated"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /impersonation/start [post]
+func (h *ImpersonationHandler) Start(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	if frameworkutils.IsImpersonating(tokenDto) {
+		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You cannot start an impersonation while impersonating"))
+		return
+	}
+
+	adminID, err := strconv.Atoi(tokenDto.Sub)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid User ID format"))
+		return
+	}
+
+	var startRequest frameworkdto.StartImpersonationDTO
+	if err := c.ShouldBindJSON(&startRequest); err != nil || startRequest.UserID == 0 || strings.TrimSpace(startRequest.Reason) == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body, user_id and reason are required"))
+		return
+	}
+
+	impersonationToken, err := h.impersonationService.StartImpersonation(tokenDto, uint(adminID), startRequest, c.ClientIP())
+	if err != nil {
+		switch err {
+		case frameworkconstants.ErrUserNotFound:
+			frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+		case frameworkconstants.ErrCannotImpersonate:
+			frameworkutils.ErrorResponse(c, frameworkutils.Forbidden(err.Error()))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusCreated, impersonationToken, "Impersonation started")
+}
+
+// Stop godoc
+// @Summary Stop impersonating
+// @Description End the impersonation and revoke the impersonation token used to call this endpoint
+// @Tags Impersonation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Impersonation stopped"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Not an impersonation token"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /impersonation/stop [post]
+func (h *ImpersonationHandler) Stop(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	if err := h.impersonationService.StopImpersonation(tokenDto); err != nil {
+		if err == frameworkconstants.ErrImpersonationNotFound {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("The request was not made with an impersonation token"))
+			return
+		}
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Impersonation stopped")
+}
+
+// GetAll godoc
+// @Summary List impersonations
+// @Description List the impersonation audit trail, newest first (requires the impersonation:manage permission)
+// @Tags Impersonation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tenant_id query int false "Only impersonations of this tenant's users"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.ImpersonationDTO} "Impersonations fetched successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid Tenant ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to get this resource"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /impersonation/get-all [get]
+func (h *ImpersonationHandler) GetAll(c *gin.Context) {
+	var tenantID uint
+	if tenantIDParam := c.Query("tenant_id"); tenantIDParam != "" {
+		parsed, err := strconv.Atoi(tenantIDParam)
+		if err != nil || parsed <= 0 {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid Tenant ID"))
+			return
+		}
+		tenantID = uint(parsed)
+	}
+
+	impersonations, err := h.impersonationService.GetImpersonations(tenantID)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, impersonations, "Impersonations fetched successfully")
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keySet *frameworkutils.JWTKeySet
+}
+
+func NewJWKSHandler(keySet *frameworkutils.JWTKeySet) *JWKSHandler {
+	return &JWKSHandler{keySet: keySet}
+}
+
+func (h *JWKSHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/.well-known/jwks.json", h.GetJWKS)
+}
+
+// GetJWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify framework issued tokens, keyed by the kid token header. Returned in standard JWKS form rather than the framework response envelope.
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} frameworkdto.JWKSDTO "JSON Web Key Set"
+// @Router /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keySet.JWKS())
+}
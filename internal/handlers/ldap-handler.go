@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type LDAPHandler struct {
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	ldapService    *services.LDAPService
+}
+
+func NewLDAPHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, ldapService *services.LDAPService) *LDAPHandler {
+	return &LDAPHandler{authMiddleware: authMiddleware, rbacService: rbacService, ldapService: ldapService}
+}
+
+func (h *LDAPHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/ldap")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.GET("/directory", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLDAPManage), h.GetDirectory)
+		protected.PUT("/directory", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLDAPManage), h.UpdateDirectory)
+		protected.DELETE("/directory", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLDAPManage), h.DeleteDirectory)
+	}
+}
+
+// GetDirectory godoc
+// @Summary Get LDAP directory
+// @Description Get the LDAP directory configured for the caller's tenant (requires the ldap:manage permission). The bind password is never returned.
+// @Tags LDAP
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LDAPDirectoryDTO} "LDAP directory fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to get this resource"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "LDAP directory not configured"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /ldap/directory [get]
+func (h *LDAPHandler) GetDirectory(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	directory, err := h.ldapService.GetDirectory(tokenDto.TenantID)
+	if err != nil {
+		if err == frameworkconstants.ErrLDAPDirectoryNotFound {
+			frameworkutils.ErrorResponse(c, frameworkutils.NotFound("LDAP directory"))
+			return
+		}
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, directory, "LDAP directory fetched successfully")
+}
+
+// UpdateDirectory godoc
+// @Summary Configure LDAP directory
+// @Description Create or update the LDAP or Active Directory server the caller's tenant logs in against (requires the ldap:manage permission). While enabled, passwords are checked by binding to the directory and names and mapped roles are copied from it at every login. Leave bind_password empty to keep the stored password.
+// @Tags LDAP
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param directoryRequest body frameworkdto.UpdateLDAPDirectoryDTO true "LDAP directory settings"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "LDAP directory updated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or directory settings"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to update this resource"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /ldap/directory [put]
+func (h *LDAPHandler) UpdateDirectory(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var directoryRequest frameworkdto.UpdateLDAPDirectoryDTO
+	if err := c.ShouldBindJSON(&directoryRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.ldapService.UpdateDirectory(tokenDto.TenantID, directoryRequest); err != nil {
+		if err == frameworkconstants.ErrInvalidLDAPDirectory || err == frameworkconstants.ErrInsecureLDAPDirectory {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+			return
+		}
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "LDAP directory updated successfully")
+}
+
+// DeleteDirectory godoc
+// @Summary Remove LDAP directory
+// @Description Remove the caller's tenant LDAP directory so its users log in with their framework password again (requires the ldap:manage permission)
+// @Tags LDAP
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "LDAP directory removed successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to update this resource"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /ldap/directory [delete]
+func (h *LDAPHandler) DeleteDirectory(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	if err := h.ldapService.DeleteDirectory(tokenDto.TenantID); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "LDAP directory removed successfully")
+}
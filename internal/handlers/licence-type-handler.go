@@ -13,29 +13,30 @@ import (
 )
 
 type LicenceTypeHandler struct {
-	jwtSecret          string
+	authMiddleware     gin.HandlerFunc
+	rbacService        *services.RBACService
 	licenceTypeService *services.LicenceTypeService
 }
 
-func NewLicenceTypeHandler(jwtSecret string, licenceTypeService *services.LicenceTypeService) *LicenceTypeHandler {
-	return &LicenceTypeHandler{jwtSecret: jwtSecret, licenceTypeService: licenceTypeService}
+func NewLicenceTypeHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, licenceTypeService *services.LicenceTypeService) *LicenceTypeHandler {
+	return &LicenceTypeHandler{authMiddleware: authMiddleware, rbacService: rbacService, licenceTypeService: licenceTypeService}
 }
 
 func (h *LicenceTypeHandler) RegisterRoutes(router *gin.Engine) {
 	api := router.Group("/licence-type")
-	protected := api.Use(middleware.BearerAuthMiddleware(h.jwtSecret))
+	protected := api.Use(h.authMiddleware)
 	{
-		protected.GET("/get-all", h.GetAll)
-		protected.GET("/get-by-id", h.GetById)
-		protected.POST("/create", h.Create)
-		protected.PUT("/update", h.Update)
-		protected.DELETE("/delete", h.Delete)
+		protected.GET("/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLicenceTypesRead), h.GetAll)
+		protected.GET("/get-by-id", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLicenceTypesRead), h.GetById)
+		protected.POST("/create", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLicenceTypesManage), h.Create)
+		protected.PUT("/update", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLicenceTypesManage), h.Update)
+		protected.DELETE("/delete", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionLicenceTypesManage), h.Delete)
 	}
 }
 
 // GetAll godoc
 // @Summary Get all licence types
-// @Description Get list of all licence types (requires authentication, super admin only)
+// @Description Get list of all licence types (requires the licence_types:read permission)
 // @Tags Licence Type
 // @Accept json
 // @Produce json
@@ -46,17 +47,6 @@ func (h *LicenceTypeHandler) RegisterRoutes(router *gin.Engine) {
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /licence-type/get-all [get]
 func (h *LicenceTypeHandler) GetAll(c *gin.Context) {
-	tokenDto, err := frameworkutils.GetTokenDTO(c)
-	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
-		return
-	}
-
-	if tokenDto.Role == string(frameworkconstants.UserRoleTenantAdmin) || tokenDto.Role == string(frameworkconstants.UserRoleTenantUser) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("Forbidden"))
-		return
-	}
-
 	licenceTypes, err := h.licenceTypeService.GetAll()
 	if err != nil {
 		frameworkutils.ErrorResponse(c, err)
@@ -68,7 +58,7 @@ func (h *LicenceTypeHandler) GetAll(c *gin.Context) {
 
 // GetById godoc
 // @Summary Get licence type by ID
-// @Description Get a specific licence type by ID (requires authentication, super admin only)
+// @Description Get a specific licence type by ID (requires the licence_types:read permission)
 // @Tags Licence Type
 // @Accept json
 // @Produce json
@@ -81,17 +71,6 @@ func (h *LicenceTypeHandler) GetAll(c *gin.Context) {
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /licence-type/get-by-id [get]
 func (h *LicenceTypeHandler) GetById(c *gin.Context) {
-	tokenDto, err := frameworkutils.GetTokenDTO(c)
-	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
-		return
-	}
-
-	if tokenDto.Role == string(frameworkconstants.UserRoleTenantAdmin) || tokenDto.Role == string(frameworkconstants.UserRoleTenantUser) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("Forbidden"))
-		return
-	}
-
 	id := c.Query("id")
 	if id == "" {
 		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
@@ -115,7 +94,7 @@ func (h *LicenceTypeHandler) GetById(c *gin.Context) {
 
 // Create godoc
 // @Summary Create a new licence type
-// @Description Create a new licence type (requires authentication, super admin only)
+// @Description Create a new licence type (requires the licence_types:manage permission)
 // @Tags Licence Type
 // @Accept json
 // @Produce json
@@ -128,24 +107,13 @@ func (h *LicenceTypeHandler) GetById(c *gin.Context) {
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /licence-type/create [post]
 func (h *LicenceTypeHandler) Create(c *gin.Context) {
-	tokenDto, err := frameworkutils.GetTokenDTO(c)
-	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
-		return
-	}
-
-	if tokenDto.Role != string(frameworkconstants.UserRoleSuperAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("Forbidden"))
-		return
-	}
-
 	var createLicenceTypeDTO frameworkdto.LicenceTypeCreateRequestDTO
 	if err := c.ShouldBindJSON(&createLicenceTypeDTO); err != nil {
 		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
 		return
 	}
 
-	err = h.licenceTypeService.Create(createLicenceTypeDTO)
+	err := h.licenceTypeService.Create(createLicenceTypeDTO)
 	if err != nil {
 		frameworkutils.ErrorResponse(c, err)
 		return
@@ -156,7 +124,7 @@ func (h *LicenceTypeHandler) Create(c *gin.Context) {
 
 // Update godoc
 // @Summary Update a licence type
-// @Description Update an existing licence type (requires authentication, super admin only)
+// @Description Update an existing licence type (requires the licence_types:manage permission)
 // @Tags Licence Type
 // @Accept json
 // @Produce json
@@ -169,24 +137,13 @@ func (h *LicenceTypeHandler) Create(c *gin.Context) {
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /licence-type/update [put]
 func (h *LicenceTypeHandler) Update(c *gin.Context) {
-	tokenDto, err := frameworkutils.GetTokenDTO(c)
-	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
-		return
-	}
-
-	if tokenDto.Role != string(frameworkconstants.UserRoleSuperAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("Forbidden"))
-		return
-	}
-
 	var dto frameworkdto.LicenceTypeUpdateRequestDTO
 	if err := c.ShouldBindJSON(&dto); err != nil {
 		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
 		return
 	}
 
-	err = h.licenceTypeService.Update(dto)
+	err := h.licenceTypeService.Update(dto)
 	if err != nil {
 		frameworkutils.ErrorResponse(c, err)
 		return
@@ -197,7 +154,7 @@ func (h *LicenceTypeHandler) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary Delete a licence type
-// @Description Delete a licence type by ID (requires authentication, super admin only)
+// @Description Delete a licence type by ID (requires the licence_types:manage permission)
 // @Tags Licence Type
 // @Accept json
 // @Produce json
@@ -210,17 +167,6 @@ func (h *LicenceTypeHandler) Update(c *gin.Context) {
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /licence-type/delete [delete]
 func (h *LicenceTypeHandler) Delete(c *gin.Context) {
-	tokenDto, err := frameworkutils.GetTokenDTO(c)
-	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
-		return
-	}
-
-	if tokenDto.Role != string(frameworkconstants.UserRoleSuperAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("Forbidden"))
-		return
-	}
-
 	id := c.Query("id")
 	if id == "" {
 		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
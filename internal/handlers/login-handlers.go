@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
 	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
 	"github.com/geekible-ltd/serviceframework/internal/services"
@@ -10,29 +13,42 @@ import (
 )
 
 type LoginHandlers struct {
-	loginService *services.LoginService
+	authMiddleware gin.HandlerFunc
+	loginService   *services.LoginService
 }
 
-func NewLoginHandlers(loginService *services.LoginService) *LoginHandlers {
-	return &LoginHandlers{loginService: loginService}
+func NewLoginHandlers(authMiddleware gin.HandlerFunc, loginService *services.LoginService) *LoginHandlers {
+	return &LoginHandlers{authMiddleware: authMiddleware, loginService: loginService}
 }
 
 func (h *LoginHandlers) RegisterRoutes(router *gin.Engine) {
 	api := router.Group("/authentication")
 	api.POST("/login", h.Login)
+	api.POST("/refresh", h.Refresh)
+	api.POST("/password/change", h.ChangeExpiredPassword)
+	api.POST("/magic-link/request", h.RequestMagicLink)
+	api.POST("/magic-link", h.MagicLinkLogin)
+
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.POST("/logout", h.Logout)
+		protected.POST("/reauthenticate", h.Reauthenticate)
+	}
 }
 
 // Login godoc
 // @Summary User login
-// @Description Authenticate user with email and password, returns JWT token
+// @Description Authenticate user with email and password, returns JWT token. Requests addressed to a tenant through its subdomain, a verified custom domain or the X-Tenant-ID header only sign in that tenant's users. Users of tenants with an enabled LDAP directory are checked against the directory. When MFA is enabled for the user or required by the tenant, an mfa_token is returned instead to be completed at /authentication/mfa/verify. When the password has expired, a password_change_token is returned instead to be completed at /authentication/password/change.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param loginRequest body frameworkdto.LoginDTO true "Login credentials"
 // @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LoginResponseDTO} "Login successful"
-// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, or an email address shared by several tenants without a tenant"
 // @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid credentials"
+// @Failure 423 {object} frameworkdto.ErrorResponseDTO "Account temporarily locked (code ACCOUNT_LOCKED)"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Failure 503 {object} frameworkdto.ErrorResponseDTO "LDAP directory unavailable"
 // @Router /authentication/login [post]
 func (h *LoginHandlers) Login(c *gin.Context) {
 	var loginRequest frameworkdto.LoginDTO
@@ -40,11 +56,296 @@ func (h *LoginHandlers) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	loginResponse, err := h.loginService.Login(loginRequest, c.ClientIP())
+	loginResponse, err := h.loginService.Login(loginRequest, resolvedTenantID(c), c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		frameworkutils.ErrorResponse(c, err)
+		// Directory errors can reveal its layout, so only the sentinel message is returned
+		if errors.Is(err, frameworkconstants.ErrLDAPUnavailable) {
+			frameworkutils.ErrorResponse(c, frameworkutils.ServiceUnavailable(frameworkconstants.ErrLDAPUnavailable.Error()))
+			return
+		}
+
+		switch err {
+		case frameworkconstants.ErrUserNotFound, frameworkconstants.ErrInvalidPassword:
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Invalid email or password"))
+		case frameworkconstants.ErrUserAccountInactive, frameworkconstants.ErrTenantNotFound, frameworkconstants.ErrTenantSuspended:
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError(err.Error()))
+		case frameworkconstants.ErrAccountLocked:
+			frameworkutils.ErrorResponse(c, frameworkutils.AccountLocked(err.Error()))
+		case frameworkconstants.ErrTenantRequired:
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	if loginResponse.MFARequired {
+		frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "MFA verification required")
+		return
+	}
+
+	if loginResponse.PasswordChangeRequired {
+		frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Password change required")
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Login successful")
+}
+
+// ChangeExpiredPassword godoc
+// @Summary Change an expired password
+// @Description Exchange the password_change_token returned by login and a new password for a JWT. The token cannot be used for anything else.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param changeRequest body frameworkdto.PasswordChangeRequestDTO true "Password change token and new password"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LoginResponseDTO} "Login successful"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or password policy violation"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid or expired password change token"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/password/change [post]
+func (h *LoginHandlers) ChangeExpiredPassword(c *gin.Context) {
+	var changeRequest frameworkdto.PasswordChangeRequestDTO
+	if err := c.ShouldBindJSON(&changeRequest); err != nil || changeRequest.PasswordChangeToken == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	loginResponse, err := h.loginService.ChangeExpiredPassword(changeRequest, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		var policyErr *frameworkutils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			frameworkutils.ErrorResponse(c, frameworkutils.PasswordPolicyViolation(policyErr))
+			return
+		}
+
+		switch err {
+		case frameworkconstants.ErrInvalidPasswordChange,
+			frameworkconstants.ErrUserNotFound,
+			frameworkconstants.ErrUserAccountInactive,
+			frameworkconstants.ErrTenantSuspended:
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError(err.Error()))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Login successful")
+}
+
+// RequestMagicLink godoc
+// @Summary Request a magic sign-in link
+// @Description Send a single-use, short-lived sign-in link to the email address when it belongs to an active user of a tenant with magic links enabled, limited to the tenant the request is addressed to when there is one. The response is the same whether or not a link was sent.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param magicLinkRequest body frameworkdto.MagicLinkRequestDTO true "Email address"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Magic link requested"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/magic-link/request [post]
+func (h *LoginHandlers) RequestMagicLink(c *gin.Context) {
+	var magicLinkRequest frameworkdto.MagicLinkRequestDTO
+	if err := c.ShouldBindJSON(&magicLinkRequest); err != nil || magicLinkRequest.Email == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.loginService.RequestMagicLink(magicLinkRequest, resolvedTenantID(c), c.ClientIP()); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "If the email address belongs to an account that can use magic links, a sign-in link has been sent")
+}
+
+// MagicLinkLogin godoc
+// @Summary Sign in with a magic link
+// @Description Exchange the token from a magic link for a JWT. The token can only be used once. When MFA is enabled for the user or required by the tenant, an mfa_token is returned instead to be completed at /authentication/mfa/verify, and when the password has expired a password_change_token is returned.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param magicLinkLogin body frameworkdto.MagicLinkLoginDTO true "Magic link token"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LoginResponseDTO} "Login successful"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid or expired magic link"
+// @Failure 423 {object} frameworkdto.ErrorResponseDTO "Account temporarily locked (code ACCOUNT_LOCKED)"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/magic-link [post]
+func (h *LoginHandlers) MagicLinkLogin(c *gin.Context) {
+	var magicLinkLogin frameworkdto.MagicLinkLoginDTO
+	if err := c.ShouldBindJSON(&magicLinkLogin); err != nil || magicLinkLogin.Token == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	loginResponse, err := h.loginService.MagicLinkLogin(magicLinkLogin, resolvedTenantID(c), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch err {
+		case frameworkconstants.ErrInvalidMagicLink,
+			frameworkconstants.ErrUserNotFound,
+			frameworkconstants.ErrUserAccountInactive,
+			frameworkconstants.ErrTenantNotFound,
+			frameworkconstants.ErrTenantSuspended:
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError(err.Error()))
+		case frameworkconstants.ErrAccountLocked:
+			frameworkutils.ErrorResponse(c, frameworkutils.AccountLocked(err.Error()))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	if loginResponse.MFARequired {
+		frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "MFA verification required")
+		return
+	}
+
+	if loginResponse.PasswordChangeRequired {
+		frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Password change required")
 		return
 	}
 
 	frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Login successful")
 }
+
+// Refresh godoc
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access and refresh token pair. Refresh tokens are single use; replaying a used token revokes every token issued from the same login.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param refreshRequest body frameworkdto.RefreshTokenRequestDTO true "Refresh token"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LoginResponseDTO} "Token refreshed successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid, expired or reused refresh token"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/refresh [post]
+func (h *LoginHandlers) Refresh(c *gin.Context) {
+	var refreshRequest frameworkdto.RefreshTokenRequestDTO
+	if err := c.ShouldBindJSON(&refreshRequest); err != nil || refreshRequest.RefreshToken == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	loginResponse, err := h.loginService.RefreshToken(refreshRequest, c.ClientIP())
+	if err != nil {
+		switch err {
+		case frameworkconstants.ErrInvalidRefreshToken,
+			frameworkconstants.ErrRefreshTokenExpired,
+			frameworkconstants.ErrRefreshTokenReused,
+			frameworkconstants.ErrUserNotFound,
+			frameworkconstants.ErrUserAccountInactive,
+			frameworkconstants.ErrTenantSuspended:
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError(err.Error()))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Token refreshed successfully")
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Revoke the current access token and, if supplied, the refresh token issued with it
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param logoutRequest body frameworkdto.LogoutRequestDTO false "Refresh token to revoke"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Logout successful"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/logout [post]
+func (h *LoginHandlers) Logout(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var logoutRequest frameworkdto.LogoutRequestDTO
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&logoutRequest); err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+			return
+		}
+	}
+
+	if err := h.loginService.Logout(tokenDto, logoutRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Logout successful")
+}
+
+// Reauthenticate godoc
+// @Summary Re-authenticate the current session
+// @Description Confirm the signed-in user's identity with their password, a TOTP code, or both, and receive a new access token for the same session with a fresh auth_time. Routes that delete users or tenants or change an email address answer 401 with code REAUTHENTICATION_REQUIRED until this has been done recently. Failed attempts count towards account lockout. API keys and impersonation tokens cannot be re-authenticated.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param reauthenticateRequest body frameworkdto.ReauthenticateDTO true "Password and/or TOTP code"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LoginResponseDTO} "Re-authentication successful"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or MFA not enrolled"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid password or code"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Token cannot be re-authenticated"
+// @Failure 423 {object} frameworkdto.ErrorResponseDTO "Account temporarily locked (code ACCOUNT_LOCKED)"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Failure 503 {object} frameworkdto.ErrorResponseDTO "LDAP directory unavailable"
+// @Router /authentication/reauthenticate [post]
+func (h *LoginHandlers) Reauthenticate(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	userID, err := strconv.Atoi(tokenDto.Sub)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var reauthenticateRequest frameworkdto.ReauthenticateDTO
+	if err := c.ShouldBindJSON(&reauthenticateRequest); err != nil || (reauthenticateRequest.Password == "" && reauthenticateRequest.Code == "") {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	loginResponse, err := h.loginService.Reauthenticate(tokenDto, uint(userID), reauthenticateRequest)
+	if err != nil {
+		if errors.Is(err, frameworkconstants.ErrLDAPUnavailable) {
+			frameworkutils.ErrorResponse(c, frameworkutils.ServiceUnavailable(frameworkconstants.ErrLDAPUnavailable.Error()))
+			return
+		}
+
+		switch err {
+		case frameworkconstants.ErrInvalidPassword, frameworkconstants.ErrInvalidMFACode,
+			frameworkconstants.ErrUserNotFound, frameworkconstants.ErrUserAccountInactive, frameworkconstants.ErrTenantSuspended:
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError(err.Error()))
+		case frameworkconstants.ErrMFANotEnrolled:
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+		case frameworkconstants.ErrCannotReauthenticate:
+			frameworkutils.ErrorResponse(c, frameworkutils.Forbidden(err.Error()))
+		case frameworkconstants.ErrAccountLocked:
+			frameworkutils.ErrorResponse(c, frameworkutils.AccountLocked(err.Error()))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Re-authentication successful")
+}
+
+// resolvedTenantID returns the tenant the request was addressed to, or zero when there is none
+func resolvedTenantID(c *gin.Context) uint {
+	tenant, _ := frameworkutils.GetResolvedTenant(c)
+	return tenant.TenantID
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	authMiddleware gin.HandlerFunc
+	mfaService     *services.MFAService
+}
+
+func NewMFAHandler(authMiddleware gin.HandlerFunc, mfaService *services.MFAService) *MFAHandler {
+	return &MFAHandler{authMiddleware: authMiddleware, mfaService: mfaService}
+}
+
+func (h *MFAHandler) RegisterRoutes(router *gin.Engine) {
+	challenge := router.Group("/authentication/mfa")
+	challenge.POST("/verify", h.Verify)
+	challenge.POST("/enroll", h.EnrollFromChallenge)
+
+	api := router.Group("/mfa")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.POST("/enroll", h.Enroll)
+		protected.POST("/confirm", h.Confirm)
+		protected.POST("/disable", h.Disable)
+		protected.POST("/recovery-codes", h.RegenerateRecoveryCodes)
+	}
+}
+
+// Verify godoc
+// @Summary Complete MFA login
+// @Description Exchange the mfa_token returned by login and a TOTP or recovery code for a JWT. Users enrolling at login receive their recovery codes in the response. When the password has expired, a password_change_token is returned instead of a JWT.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param verifyRequest body frameworkdto.MFAVerifyRequestDTO true "MFA token and code"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LoginResponseDTO} "Login successful"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid challenge or code"
+// @Failure 423 {object} frameworkdto.ErrorResponseDTO "Account temporarily locked (code ACCOUNT_LOCKED)"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var verifyRequest frameworkdto.MFAVerifyRequestDTO
+	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	loginResponse, err := h.mfaService.VerifyChallenge(verifyRequest, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		mfaErrorResponse(c, err)
+		return
+	}
+
+	if loginResponse.PasswordChangeRequired {
+		frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Password change required")
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Login successful")
+}
+
+// EnrollFromChallenge godoc
+// @Summary Enroll in MFA during login
+// @Description Start TOTP enrollment using the mfa_token returned by login when the tenant requires MFA and the user has not enrolled yet
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param enrollRequest body frameworkdto.MFAEnrollRequestDTO true "MFA token"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.MFAEnrollmentDTO} "MFA enrollment started"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid challenge"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "MFA already enabled"
+// @Failure 423 {object} frameworkdto.ErrorResponseDTO "Account temporarily locked (code ACCOUNT_LOCKED)"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/mfa/enroll [post]
+func (h *MFAHandler) EnrollFromChallenge(c *gin.Context) {
+	var enrollRequest frameworkdto.MFAEnrollRequestDTO
+	if err := c.ShouldBindJSON(&enrollRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	enrollment, err := h.mfaService.EnrollFromChallenge(enrollRequest)
+	if err != nil {
+		mfaErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, enrollment, "MFA enrollment started")
+}
+
+// Enroll godoc
+// @Summary Start MFA enrollment
+// @Description Generate a new TOTP secret and otpauth URI for the authenticated user. MFA is not enabled until confirmed with a code.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.MFAEnrollmentDTO} "MFA enrollment started"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "MFA already enabled"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	tokenDto, userID, ok := mfaCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.mfaService.StartEnrollment(tokenDto.TenantID, userID)
+	if err != nil {
+		mfaErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, enrollment, "MFA enrollment started")
+}
+
+// Confirm godoc
+// @Summary Confirm MFA enrollment
+// @Description Enable MFA for the authenticated user by submitting a code from the authenticator app. Returns single-use recovery codes which are only shown once.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param codeRequest body frameworkdto.MFACodeRequestDTO true "TOTP code"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.MFARecoveryCodesDTO} "MFA enabled"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or enrollment not started"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized or invalid code"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "MFA already enabled"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /mfa/confirm [post]
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	tokenDto, userID, ok := mfaCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var codeRequest frameworkdto.MFACodeRequestDTO
+	if err := c.ShouldBindJSON(&codeRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.ConfirmEnrollment(tokenDto.TenantID, userID, codeRequest.Code)
+	if err != nil {
+		mfaErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, recoveryCodes, "MFA enabled")
+}
+
+// Disable godoc
+// @Summary Disable MFA
+// @Description Disable MFA for the authenticated user using a TOTP or recovery code. Not allowed when the tenant requires MFA.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param codeRequest body frameworkdto.MFACodeRequestDTO true "TOTP or recovery code"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "MFA disabled"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or MFA not enabled"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized or invalid code"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "MFA is required by the tenant"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	tokenDto, userID, ok := mfaCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var codeRequest frameworkdto.MFACodeRequestDTO
+	if err := c.ShouldBindJSON(&codeRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.mfaService.Disable(tokenDto.TenantID, userID, codeRequest.Code); err != nil {
+		mfaErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "MFA disabled")
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate MFA recovery codes
+// @Description Replace all recovery codes of the authenticated user. Requires a current TOTP code.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param codeRequest body frameworkdto.MFACodeRequestDTO true "TOTP code"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.MFARecoveryCodesDTO} "Recovery codes regenerated"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or MFA not enabled"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized or invalid code"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	tokenDto, userID, ok := mfaCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var codeRequest frameworkdto.MFACodeRequestDTO
+	if err := c.ShouldBindJSON(&codeRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	recoveryCodes, err := h.mfaService.RegenerateRecoveryCodes(tokenDto.TenantID, userID, codeRequest.Code)
+	if err != nil {
+		mfaErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, recoveryCodes, "Recovery codes regenerated")
+}
+
+func mfaCurrentUser(c *gin.Context) (frameworkdto.TokenDTO, uint, bool) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return frameworkdto.TokenDTO{}, 0, false
+	}
+
+	userID, err := strconv.Atoi(tokenDto.Sub)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid User ID format"))
+		return frameworkdto.TokenDTO{}, 0, false
+	}
+
+	return tokenDto, uint(userID), true
+}
+
+func mfaErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case frameworkconstants.ErrInvalidMFAChallenge,
+		frameworkconstants.ErrInvalidMFACode,
+		frameworkconstants.ErrUserAccountInactive,
+		frameworkconstants.ErrTenantSuspended:
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError(err.Error()))
+	case frameworkconstants.ErrMFANotEnrolled, frameworkconstants.ErrMFANotEnabled:
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+	case frameworkconstants.ErrMFAAlreadyEnabled:
+		frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
+	case frameworkconstants.ErrMFARequiredByTenant:
+		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden(err.Error()))
+	case frameworkconstants.ErrAccountLocked:
+		frameworkutils.ErrorResponse(c, frameworkutils.AccountLocked(err.Error()))
+	case frameworkconstants.ErrUserNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
+}
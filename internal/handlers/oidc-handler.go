@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	oidcService    *services.OIDCService
+}
+
+func NewOIDCHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, oidcService *services.OIDCService) *OIDCHandler {
+	return &OIDCHandler{authMiddleware: authMiddleware, rbacService: rbacService, oidcService: oidcService}
+}
+
+func (h *OIDCHandler) RegisterRoutes(router *gin.Engine) {
+	login := router.Group("/authentication/oidc")
+	login.GET("/authorize", h.Authorize)
+	login.POST("/callback", h.Callback)
+
+	api := router.Group("/oidc")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.GET("/provider", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionIdentityProvidersManage), h.GetIdentityProvider)
+		protected.PUT("/provider", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionIdentityProvidersManage), h.UpdateIdentityProvider)
+	}
+}
+
+// Authorize godoc
+// @Summary Start OIDC login
+// @Description Start an authorization code flow with PKCE against the tenant's identity provider. Redirect the user to the returned authorization_url. The tenant ID can be omitted on requests addressed to a tenant through its subdomain, a verified custom domain or the X-Tenant-ID header.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param tenant_id query int false "Tenant ID"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.OIDCAuthorizeResponseDTO} "Authorization started"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid tenant ID"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Identity provider not configured"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Identity provider unavailable"
+// @Router /authentication/oidc/authorize [get]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	// The tenant the request is addressed to is used when no tenant_id is given
+	tenantID := resolvedTenantID(c)
+	if tenantID == 0 || c.Query("tenant_id") != "" {
+		queryTenantID, err := strconv.Atoi(c.Query("tenant_id"))
+		if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid Tenant ID format"))
+			return
+		}
+		tenantID = uint(queryTenantID)
+	}
+
+	authorizeResponse, err := h.oidcService.Authorize(tenantID)
+	if err != nil {
+		oidcErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, authorizeResponse, "Authorization started")
+}
+
+// Callback godoc
+// @Summary Complete OIDC login
+// @Description Exchange the state and authorization code returned by the identity provider for a JWT. Unknown users are linked by verified email or provisioned when the provider allows it.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param callbackRequest body frameworkdto.OIDCCallbackRequestDTO true "State and authorization code"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.LoginResponseDTO} "Login successful"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Invalid state or authentication failed"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "User not provisioned"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /authentication/oidc/callback [post]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var callbackRequest frameworkdto.OIDCCallbackRequestDTO
+	if err := c.ShouldBindJSON(&callbackRequest); err != nil || callbackRequest.State == "" || callbackRequest.Code == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	loginResponse, err := h.oidcService.Callback(callbackRequest, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		oidcErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, loginResponse, "Login successful")
+}
+
+// GetIdentityProvider godoc
+// @Summary Get identity provider
+// @Description Get the OIDC identity provider configured for the caller's tenant (requires the identity_providers:manage permission). The client secret is never returned.
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.IdentityProviderDTO} "Identity provider fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to get this resource"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Identity provider not configured"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /oidc/provider [get]
+func (h *OIDCHandler) GetIdentityProvider(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	provider, err := h.oidcService.GetIdentityProvider(tokenDto.TenantID)
+	if err != nil {
+		oidcErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, provider, "Identity provider fetched successfully")
+}
+
+// UpdateIdentityProvider godoc
+// @Summary Configure identity provider
+// @Description Create or update the OIDC identity provider for the caller's tenant (requires the identity_providers:manage permission). Leave client_secret empty to keep the stored secret. The caller must hold every permission of default_role.
+// @Tags OIDC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param providerRequest body frameworkdto.UpdateIdentityProviderDTO true "Identity provider settings"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Identity provider updated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, issuer not an https URL or role not found"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to update this resource or assign the default role"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /oidc/provider [put]
+func (h *OIDCHandler) UpdateIdentityProvider(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var providerRequest frameworkdto.UpdateIdentityProviderDTO
+	if err := c.ShouldBindJSON(&providerRequest); err != nil || providerRequest.Issuer == "" || providerRequest.ClientID == "" || providerRequest.RedirectURL == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.oidcService.UpdateIdentityProvider(tokenDto, providerRequest); err != nil {
+		oidcErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Identity provider updated successfully")
+}
+
+func oidcErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case frameworkconstants.ErrInvalidOIDCState,
+		frameworkconstants.ErrOIDCAuthenticationFailed,
+		frameworkconstants.ErrOIDCEmailNotVerified,
+		frameworkconstants.ErrUserAccountInactive,
+		frameworkconstants.ErrTenantSuspended:
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError(err.Error()))
+	case frameworkconstants.ErrRoleNotFound,
+		frameworkconstants.ErrInvalidOIDCIssuer:
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+	case frameworkconstants.ErrOIDCUserNotProvisioned,
+		frameworkconstants.ErrTenantLicenceExceeded,
+		frameworkconstants.ErrTenantLicenceExpired,
+		frameworkconstants.ErrPermissionNotHeld:
+		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden(err.Error()))
+	case frameworkconstants.ErrIdentityProviderNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Identity provider"))
+	case frameworkconstants.ErrUserNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+	case frameworkconstants.ErrFailedToCreateUser:
+		frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
+}
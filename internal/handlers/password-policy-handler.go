@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordPolicyHandler struct {
+	authMiddleware        gin.HandlerFunc
+	rbacService           *services.RBACService
+	passwordPolicyService *services.PasswordPolicyService
+}
+
+func NewPasswordPolicyHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, passwordPolicyService *services.PasswordPolicyService) *PasswordPolicyHandler {
+	return &PasswordPolicyHandler{authMiddleware: authMiddleware, rbacService: rbacService, passwordPolicyService: passwordPolicyService}
+}
+
+func (h *PasswordPolicyHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/password-policy")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.GET("", h.GetPasswordPolicy)
+		protected.PUT("", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionPasswordPolicyManage), h.UpdatePasswordPolicy)
+		protected.DELETE("", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionPasswordPolicyManage), h.ResetPasswordPolicy)
+	}
+}
+
+// GetPasswordPolicy godoc
+// @Summary Get password policy
+// @Description Get the password policy in force for the caller's tenant, so clients can show the rules before a password is submitted
+// @Tags Password Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.PasswordPolicyDTO} "Password policy fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /password-policy [get]
+func (h *PasswordPolicyHandler) GetPasswordPolicy(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	policy, err := h.passwordPolicyService.GetPasswordPolicy(tokenDto.TenantID)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, policy, "Password policy fetched successfully")
+}
+
+// UpdatePasswordPolicy godoc
+// @Summary Update password policy
+// @Description Set a password policy for the caller's tenant that replaces the framework-wide policy (requires the password_policy:manage permission). Existing passwords are checked against it the next time they change.
+// @Tags Password Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param policyRequest body frameworkdto.PasswordPolicyDTO true "Password policy"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Password policy updated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or policy"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to update this resource"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /password-policy [put]
+func (h *PasswordPolicyHandler) UpdatePasswordPolicy(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var policyRequest frameworkdto.PasswordPolicyDTO
+	if err := c.ShouldBindJSON(&policyRequest); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	if err := h.passwordPolicyService.UpdateTenantPasswordPolicy(tokenDto.TenantID, policyRequest); err != nil {
+		if err == frameworkconstants.ErrInvalidPasswordPolicy {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+			return
+		}
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Password policy updated successfully")
+}
+
+// ResetPasswordPolicy godoc
+// @Summary Reset password policy
+// @Description Remove the caller's tenant password policy so the framework-wide policy applies again (requires the password_policy:manage permission)
+// @Tags Password Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Password policy reset successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to update this resource"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /password-policy [delete]
+func (h *PasswordPolicyHandler) ResetPasswordPolicy(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	if err := h.passwordPolicyService.ResetTenantPasswordPolicy(tokenDto.TenantID); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Password policy reset successfully")
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type PolicyHandler struct {
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	policyService  *services.PolicyService
+}
+
+func NewPolicyHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, policyService *services.PolicyService) *PolicyHandler {
+	return &PolicyHandler{authMiddleware: authMiddleware, rbacService: rbacService, policyService: policyService}
+}
+
+func (h *PolicyHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/policy")
+	protected := api.Use(h.authMiddleware, middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionPoliciesManage))
+	{
+		protected.GET("/get-all", h.GetAll)
+		protected.POST("/create", h.Create)
+		protected.PUT("/update", h.Update)
+		protected.DELETE("/delete", h.Delete)
+		protected.POST("/explain", h.Explain)
+	}
+}
+
+// GetAll godoc
+// @Summary Get all access policies
+// @Description List the access policies of the caller's tenant (requires the policies:manage permission)
+// @Tags Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.PolicyDTO} "Access policies fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /policy/get-all [get]
+func (h *PolicyHandler) GetAll(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	policies, err := h.policyService.GetAllPolicies(tokenDto.TenantID)
+	if err != nil {
+		policyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, policies, "Access policies fetched successfully")
+}
+
+// Create godoc
+// @Summary Create an access policy
+// @Description Create an allow or deny policy for an action, with a condition over subject, request and resource attributes (requires the policies:manage permission)
+// @Tags Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param createPolicyDTO body frameworkdto.CreatePolicyDTO true "Policy details"
+// @Success 201 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.PolicyDTO} "Access policy created successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or policy"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /policy/create [post]
+func (h *PolicyHandler) Create(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var createPolicyDTO frameworkdto.CreatePolicyDTO
+	if err := c.ShouldBindJSON(&createPolicyDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	policy, err := h.policyService.CreatePolicy(tokenDto.TenantID, createPolicyDTO)
+	if err != nil {
+		policyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusCreated, policy, "Access policy created successfully")
+}
+
+// Update godoc
+// @Summary Update an access policy
+// @Description Replace an access policy of the caller's tenant (requires the policies:manage permission)
+// @Tags Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param updatePolicyDTO body frameworkdto.UpdatePolicyDTO true "Policy details"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.PolicyDTO} "Access policy updated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or policy"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Access policy not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /policy/update [put]
+func (h *PolicyHandler) Update(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var updatePolicyDTO frameworkdto.UpdatePolicyDTO
+	if err := c.ShouldBindJSON(&updatePolicyDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	policy, err := h.policyService.UpdatePolicy(tokenDto.TenantID, updatePolicyDTO)
+	if err != nil {
+		policyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, policy, "Access policy updated successfully")
+}
+
+// Delete godoc
+// @Summary Delete an access policy
+// @Description Delete an access policy of the caller's tenant (requires the policies:manage permission)
+// @Tags Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Policy ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Access policy deleted successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Access policy not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /policy/delete [delete]
+func (h *PolicyHandler) Delete(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	id := c.Query("id")
+	if id == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
+		return
+	}
+
+	policyID, err := strconv.Atoi(id)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return
+	}
+
+	if err := h.policyService.DeletePolicy(tokenDto.TenantID, uint(policyID)); err != nil {
+		policyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Access policy deleted successfully")
+}
+
+// Explain godoc
+// @Summary Explain an access decision
+// @Description Dry-run the tenant's access policies, or draft policies, for an action and resource as the caller or another user of the tenant, and report how each applicable policy evaluated (requires the policies:manage permission)
+// @Tags Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param explainPolicyDTO body frameworkdto.ExplainPolicyDTO true "Action, subject, request and resource to evaluate"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.PolicyDecisionDTO} "Access decision explained successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or draft policy"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /policy/explain [post]
+func (h *PolicyHandler) Explain(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var explainPolicyDTO frameworkdto.ExplainPolicyDTO
+	if err := c.ShouldBindJSON(&explainPolicyDTO); err != nil || strings.TrimSpace(explainPolicyDTO.Action) == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	request := frameworkutils.GetPolicyRequest(c)
+	request.Method, request.Path = "", ""
+
+	decision, err := h.policyService.Explain(tokenDto, explainPolicyDTO, request)
+	if err != nil {
+		policyErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, decision, "Access decision explained successfully")
+}
+
+func policyErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, frameworkconstants.ErrInvalidPolicy):
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+	case err == frameworkconstants.ErrPolicyNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Access policy"))
+	case err == frameworkconstants.ErrUserNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
+}
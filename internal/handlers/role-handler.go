@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/middleware"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	roleService    *services.RoleService
+}
+
+func NewRoleHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, roleService *services.RoleService) *RoleHandler {
+	return &RoleHandler{authMiddleware: authMiddleware, rbacService: rbacService, roleService: roleService}
+}
+
+func (h *RoleHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/role")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.GET("/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionUsersRead), h.GetAll)
+		protected.GET("/permissions", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionRolesManage), h.GetPermissions)
+		protected.POST("/create", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionRolesManage), h.Create)
+		protected.PUT("/update", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionRolesManage), h.Update)
+		protected.DELETE("/delete", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionRolesManage), h.Delete)
+	}
+}
+
+// GetAll godoc
+// @Summary Get all roles
+// @Description List the built-in roles and the custom roles of the caller's tenant with their permissions (requires the users:read permission)
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.RoleDTO} "Roles fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /role/get-all [get]
+func (h *RoleHandler) GetAll(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	roles, err := h.roleService.GetAllRoles(tokenDto.TenantID)
+	if err != nil {
+		roleErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, roles, "Roles fetched successfully")
+}
+
+// GetPermissions godoc
+// @Summary Get all permissions
+// @Description List the framework and host application permissions that roles can grant (requires the roles:manage permission)
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.PermissionDTO} "Permissions fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /role/permissions [get]
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	permissions, err := h.roleService.GetAllPermissions()
+	if err != nil {
+		roleErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, permissions, "Permissions fetched successfully")
+}
+
+// Create godoc
+// @Summary Create a custom role
+// @Description Create a role for the caller's tenant from permissions the caller holds (requires the roles:manage permission)
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param createRoleDTO body frameworkdto.CreateRoleDTO true "Role details"
+// @Success 201 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.RoleDTO} "Role created successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, name or permission"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Role already exists"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /role/create [post]
+func (h *RoleHandler) Create(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var createRoleDTO frameworkdto.CreateRoleDTO
+	if err := c.ShouldBindJSON(&createRoleDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	role, err := h.roleService.CreateRole(tokenDto, createRoleDTO)
+	if err != nil {
+		roleErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusCreated, role, "Role created successfully")
+}
+
+// Update godoc
+// @Summary Update a custom role
+// @Description Replace the description and permissions of one of the tenant's custom roles; built-in roles cannot be changed and roles cannot be renamed (requires the roles:manage permission)
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param updateRoleDTO body frameworkdto.UpdateRoleDTO true "Role details"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.RoleDTO} "Role updated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or permission"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Role not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /role/update [put]
+func (h *RoleHandler) Update(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var updateRoleDTO frameworkdto.UpdateRoleDTO
+	if err := c.ShouldBindJSON(&updateRoleDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(tokenDto, updateRoleDTO)
+	if err != nil {
+		roleErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, role, "Role updated successfully")
+}
+
+// Delete godoc
+// @Summary Delete a custom role
+// @Description Delete one of the tenant's custom roles once no user or group holds it (requires the roles:manage permission)
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Role ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Role deleted successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or permission not held"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Role not found"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Role is assigned to users or groups"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /role/delete [delete]
+func (h *RoleHandler) Delete(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	id := c.Query("id")
+	if id == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
+		return
+	}
+
+	roleID, err := strconv.Atoi(id)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return
+	}
+
+	if err := h.roleService.DeleteRole(tokenDto, uint(roleID)); err != nil {
+		roleErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Role deleted successfully")
+}
+
+func roleErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case frameworkconstants.ErrInvalidRole:
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+	case frameworkconstants.ErrPermissionNotHeld:
+		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden(err.Error()))
+	case frameworkconstants.ErrRoleNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Role"))
+	case frameworkconstants.ErrRoleAlreadyExists, frameworkconstants.ErrRoleInUse:
+		frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
+}
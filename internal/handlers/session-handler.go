@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	sessionService *services.SessionService
+}
+
+func NewSessionHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, sessionService *services.SessionService) *SessionHandler {
+	return &SessionHandler{authMiddleware: authMiddleware, rbacService: rbacService, sessionService: sessionService}
+}
+
+func (h *SessionHandler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/session")
+	protected := api.Use(h.authMiddleware)
+	{
+		protected.GET("/get-all", h.GetAll)
+		protected.DELETE("/revoke", h.Revoke)
+		protected.DELETE("/revoke-all", h.RevokeAll)
+	}
+}
+
+// GetAll godoc
+// @Summary List active sessions
+// @Description List where the caller is signed in. Callers with the sessions:manage permission can pass user_id to list another user's sessions.
+// @Tags Session
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "User ID (requires the sessions:manage permission)"
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.SessionDTO} "Sessions fetched successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid User ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to get this resource"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /session/get-all [get]
+func (h *SessionHandler) GetAll(c *gin.Context) {
+	tokenDto, userID, ok := h.sessionTargetUser(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessionService.GetSessions(tokenDto.TenantID, userID, tokenDto.Sid)
+	if err != nil {
+		sessionErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, sessions, "Sessions fetched successfully")
+}
+
+// Revoke godoc
+// @Summary Revoke a session
+// @Description Sign out one session; its access and refresh tokens stop working immediately. Callers with the sessions:manage permission can pass user_id to revoke another user's session.
+// @Tags Session
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query string true "Session ID"
+// @Param user_id query int false "User ID (requires the sessions:manage permission)"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Session revoked successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to revoke this session"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Session not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /session/revoke [delete]
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	tokenDto, userID, ok := h.sessionTargetUser(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Query("id")
+	if sessionID == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
+		return
+	}
+
+	if err := h.sessionService.RevokeSession(tokenDto.TenantID, userID, sessionID); err != nil {
+		sessionErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Session revoked successfully")
+}
+
+// RevokeAll godoc
+// @Summary Revoke all sessions
+// @Description Sign out of every session. Pass keep_current=true to stay signed in on the calling session. Callers with the sessions:manage permission can pass user_id to sign another user out everywhere.
+// @Tags Session
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param keep_current query bool false "Keep the calling session"
+// @Param user_id query int false "User ID (requires the sessions:manage permission)"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Sessions revoked successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid User ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to revoke these sessions"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /session/revoke-all [delete]
+func (h *SessionHandler) RevokeAll(c *gin.Context) {
+	tokenDto, userID, ok := h.sessionTargetUser(c)
+	if !ok {
+		return
+	}
+
+	exceptSessionID := ""
+	if c.Query("keep_current") == "true" && strconv.FormatUint(uint64(userID), 10) == tokenDto.Sub {
+		exceptSessionID = tokenDto.Sid
+	}
+
+	if err := h.sessionService.RevokeAllSessions(tokenDto.TenantID, userID, exceptSessionID); err != nil {
+		sessionErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Sessions revoked successfully")
+}
+
+// sessionTargetUser resolves whose sessions the request is about: the caller, or the user_id
+// query parameter when the caller has the sessions:manage permission
+func (h *SessionHandler) sessionTargetUser(c *gin.Context) (frameworkdto.TokenDTO, uint, bool) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return frameworkdto.TokenDTO{}, 0, false
+	}
+
+	callerID, err := strconv.Atoi(tokenDto.Sub)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid User ID format"))
+		return frameworkdto.TokenDTO{}, 0, false
+	}
+
+	userIDParam := c.Query("user_id")
+	if userIDParam == "" {
+		return tokenDto, uint(callerID), true
+	}
+
+	userID, err := strconv.Atoi(userIDParam)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid User ID format"))
+		return frameworkdto.TokenDTO{}, 0, false
+	}
+
+	if userID != callerID {
+		granted, err := h.rbacService.HasPermission(tokenDto, frameworkconstants.PermissionSessionsManage)
+		if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+			return frameworkdto.TokenDTO{}, 0, false
+		}
+		if !granted {
+			frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to manage this user's sessions"))
+			return frameworkdto.TokenDTO{}, 0, false
+		}
+	}
+
+	return tokenDto, uint(userID), true
+}
+
+func sessionErrorResponse(c *gin.Context, err error) {
+	switch err {
+	case frameworkconstants.ErrSessionNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Session"))
+	case frameworkconstants.ErrUserNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
+}
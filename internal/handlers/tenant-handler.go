@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
@@ -12,28 +16,41 @@ import (
 )
 
 type TenantHandler struct {
-	jwtSecret     string
-	tenantService *services.TenantService
+	authMiddleware         gin.HandlerFunc
+	rbacService            *services.RBACService
+	reauthenticationMaxAge time.Duration
+	tenantService          *services.TenantService
+	tenantDomainService    *services.TenantDomainService
 }
 
-func NewTenantHandler(jwtSecret string, tenantService *services.TenantService) *TenantHandler {
-	return &TenantHandler{jwtSecret: jwtSecret, tenantService: tenantService}
+func NewTenantHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, reauthenticationMaxAge time.Duration, tenantService *services.TenantService, tenantDomainService *services.TenantDomainService) *TenantHandler {
+	return &TenantHandler{authMiddleware: authMiddleware, rbacService: rbacService, reauthenticationMaxAge: reauthenticationMaxAge, tenantService: tenantService, tenantDomainService: tenantDomainService}
 }
 
 func (h *TenantHandler) RegisterRoutes(router *gin.Engine) {
 	api := router.Group("/tenant")
-	protected := api.Use(middleware.BearerAuthMiddleware(h.jwtSecret))
+	protected := api.Use(h.authMiddleware)
 	{
-		protected.GET("/get-by-id", h.GetTenantByID)
-		protected.GET("/get-all", h.GetAllTenants)
-		protected.PUT("/update", h.UpdateTenant)
-		protected.DELETE("/delete", h.DeleteTenant)
+		protected.GET("/get-by-id", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsRead), h.GetTenantByID)
+		protected.GET("/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsReadAll), h.GetAllTenants)
+		protected.PUT("/update", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsUpdate), h.UpdateTenant)
+		protected.DELETE("/delete", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsDelete), frameworkutils.RequireRecentAuthentication(h.reauthenticationMaxAge), h.DeleteTenant)
+		protected.GET("/domain/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsRead), h.GetDomains)
+		protected.POST("/domain/add", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsUpdate), h.AddDomain)
+		protected.POST("/domain/verify", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsUpdate), h.VerifyDomain)
+		protected.DELETE("/domain/delete", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionTenantsUpdate), h.DeleteDomain)
+		protected.GET("/children/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionChildTenantsRead), h.GetChildTenants)
+		protected.GET("/children/seat-pool", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionChildTenantsRead), h.GetSeatPool)
+		protected.POST("/children/create", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionChildTenantsManage), h.CreateChildTenant)
+		protected.PUT("/children/licence", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionChildTenantsManage), h.UpdateChildTenantLicence)
+		protected.POST("/children/suspend", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionChildTenantsManage), h.SuspendChildTenant)
+		protected.POST("/children/reactivate", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionChildTenantsManage), h.ReactivateChildTenant)
 	}
 }
 
 // GetTenantByID godoc
 // @Summary Get tenant by ID
-// @Description Get tenant details by ID (requires authentication)
+// @Description Get the caller's tenant (requires the tenants:read permission)
 // @Tags Tenant
 // @Accept json
 // @Produce json
@@ -50,11 +67,6 @@ func (h *TenantHandler) GetTenantByID(c *gin.Context) {
 		return
 	}
 
-	if tokenDto.Role == string(frameworkconstants.UserRoleTenantAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to get this resource"))
-		return
-	}
-
 	tenant, err := h.tenantService.GetTenantByID(tokenDto.TenantID)
 	if err != nil {
 		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
@@ -66,7 +78,7 @@ func (h *TenantHandler) GetTenantByID(c *gin.Context) {
 
 // GetAllTenants godoc
 // @Summary Get all tenants
-// @Description Get list of all tenants (requires authentication, super admin only)
+// @Description Get list of all tenants (requires the tenants:read_all permission)
 // @Tags Tenant
 // @Accept json
 // @Produce json
@@ -77,17 +89,6 @@ func (h *TenantHandler) GetTenantByID(c *gin.Context) {
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /tenant/get-all [get]
 func (h *TenantHandler) GetAllTenants(c *gin.Context) {
-	tokenDto, err := frameworkutils.GetTokenDTO(c)
-	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
-		return
-	}
-
-	if tokenDto.Role == string(frameworkconstants.UserRoleSuperAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to get this resource"))
-		return
-	}
-
 	tenants, err := h.tenantService.GetAllTenants()
 	if err != nil {
 		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
@@ -99,16 +100,17 @@ func (h *TenantHandler) GetAllTenants(c *gin.Context) {
 
 // UpdateTenant godoc
 // @Summary Update tenant
-// @Description Update tenant details (requires authentication, authorized roles only)
+// @Description Update tenant details. The slug names the tenant in subdomains of the configured base domain and the X-Tenant-ID header; it is kept when omitted and removed when empty (requires the tenants:update permission)
 // @Tags Tenant
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param updateTenantDTO body frameworkdto.UpdateTenantDTO true "Tenant update details"
 // @Success 202 {object} frameworkdto.SuccessResponseDTO "Tenant updated successfully"
-// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or slug"
 // @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
 // @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to update tenant"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Tenant slug is already in use"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /tenant/update [put]
 func (h *TenantHandler) UpdateTenant(c *gin.Context) {
@@ -118,11 +120,6 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 		return
 	}
 
-	if tokenDto.Role == string(frameworkconstants.UserRoleTenantAdmin) || tokenDto.Role == string(frameworkconstants.UserRoleSuperAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to get this resource"))
-		return
-	}
-
 	var updateTenantDTO frameworkdto.UpdateTenantDTO
 	if err := c.ShouldBindJSON(&updateTenantDTO); err != nil {
 		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
@@ -131,7 +128,7 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 
 	err = h.tenantService.UpdateTenant(tokenDto.TenantID, updateTenantDTO)
 	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		tenantErrorResponse(c, err)
 		return
 	}
 
@@ -140,7 +137,7 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 
 // DeleteTenant godoc
 // @Summary Delete tenant
-// @Description Delete a tenant (requires authentication, authorized roles only)
+// @Description Delete a tenant without child tenants; a child tenant's seats return to its parent (requires the tenants:delete permission and a recent login or re-authentication)
 // @Tags Tenant
 // @Accept json
 // @Produce json
@@ -148,6 +145,7 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 // @Success 202 {object} frameworkdto.SuccessResponseDTO "Tenant deleted successfully"
 // @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
 // @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to delete tenant"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Tenant has child tenants"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /tenant/delete [delete]
 func (h *TenantHandler) DeleteTenant(c *gin.Context) {
@@ -157,16 +155,382 @@ func (h *TenantHandler) DeleteTenant(c *gin.Context) {
 		return
 	}
 
-	if tokenDto.Role == string(frameworkconstants.UserRoleTenantAdmin) || tokenDto.Role == string(frameworkconstants.UserRoleSuperAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to get this resource"))
+	err = h.tenantService.DeleteTenant(tokenDto.TenantID)
+	if err != nil {
+		tenantErrorResponse(c, err)
 		return
 	}
 
-	err = h.tenantService.DeleteTenant(tokenDto.TenantID)
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Tenant deleted successfully")
+}
+
+// GetDomains godoc
+// @Summary Get tenant domains
+// @Description List the caller's tenant's custom domains with the TXT record that verifies each (requires the tenants:read permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.TenantDomainDTO} "Domains fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/domain/get-all [get]
+func (h *TenantHandler) GetDomains(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
 	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
 		return
 	}
 
-	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Tenant deleted successfully")
+	domains, err := h.tenantDomainService.GetDomains(tokenDto.TenantID)
+	if err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, domains, "Domains fetched successfully")
+}
+
+// AddDomain godoc
+// @Summary Add a tenant domain
+// @Description Register a custom domain for the caller's tenant. The domain serves the tenant once a TXT record named verification_record holding verification_value has been published and the domain verified (requires the tenants:update permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param addTenantDomainDTO body frameworkdto.AddTenantDomainDTO true "Domain"
+// @Success 201 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.TenantDomainDTO} "Domain added successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or domain"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Domain is already registered"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/domain/add [post]
+func (h *TenantHandler) AddDomain(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var addTenantDomainDTO frameworkdto.AddTenantDomainDTO
+	if err := c.ShouldBindJSON(&addTenantDomainDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	domain, err := h.tenantDomainService.AddDomain(tokenDto.TenantID, addTenantDomainDTO)
+	if err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusCreated, domain, "Domain added successfully")
+}
+
+// VerifyDomain godoc
+// @Summary Verify a tenant domain
+// @Description Look up the domain's verification TXT record and, when it holds the domain's verification value, start resolving requests to the domain to the caller's tenant (requires the tenants:update permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Domain ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.TenantDomainDTO} "Domain verified successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required, Invalid ID or verification record not found"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Domain not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/domain/verify [post]
+func (h *TenantHandler) VerifyDomain(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	domainID, ok := idQuery(c)
+	if !ok {
+		return
+	}
+
+	domain, err := h.tenantDomainService.VerifyDomain(tokenDto.TenantID, domainID)
+	if err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, domain, "Domain verified successfully")
+}
+
+// DeleteDomain godoc
+// @Summary Delete a tenant domain
+// @Description Remove one of the caller's tenant's custom domains; requests to it stop resolving to the tenant (requires the tenants:update permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Domain ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Domain deleted successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Domain not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/domain/delete [delete]
+func (h *TenantHandler) DeleteDomain(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	domainID, ok := idQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.tenantDomainService.DeleteDomain(tokenDto.TenantID, domainID); err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Domain deleted successfully")
+}
+
+// GetChildTenants godoc
+// @Summary Get child tenants
+// @Description List the tenants the caller's tenant resells to, with their licences and seats (requires the child_tenants:read permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=[]frameworkdto.ChildTenantDTO} "Child tenants fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/children/get-all [get]
+func (h *TenantHandler) GetChildTenants(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	children, err := h.tenantService.GetChildTenants(tokenDto.TenantID)
+	if err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, children, "Child tenants fetched successfully")
+}
+
+// GetSeatPool godoc
+// @Summary Get the seat pool
+// @Description Report how many of the caller's tenant's licence seats are used by its users, allocated to child tenants and still available (requires the child_tenants:read permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.SeatPoolDTO} "Seat pool fetched successfully"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden or tenant licence expired"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Tenant licence not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/children/seat-pool [get]
+func (h *TenantHandler) GetSeatPool(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	seatPool, err := h.tenantService.GetSeatPool(tokenDto.TenantID)
+	if err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, seatPool, "Seat pool fetched successfully")
+}
+
+// CreateChildTenant godoc
+// @Summary Create a child tenant
+// @Description Register a tenant managed by the caller's tenant, with its first admin user, allocating seats from the caller's licence. The admin takes one of the child's seats, so at least one is needed (requires the child_tenants:manage permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param createChildTenantDTO body frameworkdto.CreateChildTenantDTO true "Child tenant details"
+// @Success 201 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.ChildTenantDTO} "Child tenant created successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, seats or password policy violation"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden, the caller's licence has expired or has too few seats, or the licence type allows more seats than the caller's"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Licence type not found"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Tenant already exists"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/children/create [post]
+func (h *TenantHandler) CreateChildTenant(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var createChildTenantDTO frameworkdto.CreateChildTenantDTO
+	if err := c.ShouldBindJSON(&createChildTenantDTO); err != nil || !strings.Contains(createChildTenantDTO.Email, "@") {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	child, err := h.tenantService.CreateChildTenant(tokenDto.TenantID, createChildTenantDTO)
+	if err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusCreated, child, "Child tenant created successfully")
+}
+
+// UpdateChildTenantLicence godoc
+// @Summary Update a child tenant's licence
+// @Description Change a child tenant's licence type, expiry and seats. Extra seats come from the caller's licence and removed seats return to it. The expiry is capped at the caller's licence expiry (requires the child_tenants:manage permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param updateChildTenantLicenceDTO body frameworkdto.UpdateChildTenantLicenceDTO true "Licence details"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO{data=frameworkdto.ChildTenantDTO} "Child tenant licence updated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or seats"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden, the caller's licence has expired or has too few seats, or the licence type allows more seats than the caller's"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Tenant or licence type not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/children/licence [put]
+func (h *TenantHandler) UpdateChildTenantLicence(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	var updateChildTenantLicenceDTO frameworkdto.UpdateChildTenantLicenceDTO
+	if err := c.ShouldBindJSON(&updateChildTenantLicenceDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	child, err := h.tenantService.UpdateChildTenantLicence(tokenDto.TenantID, updateChildTenantLicenceDTO)
+	if err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, child, "Child tenant licence updated successfully")
+}
+
+// SuspendChildTenant godoc
+// @Summary Suspend a child tenant
+// @Description Suspend a tenant managed by the caller's tenant. Its users are signed out and cannot sign in or use API keys until it is reactivated (requires the child_tenants:manage permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Child tenant ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Child tenant suspended successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Tenant not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/children/suspend [post]
+func (h *TenantHandler) SuspendChildTenant(c *gin.Context) {
+	h.setChildTenantActive(c, false, "Child tenant suspended successfully")
+}
+
+// ReactivateChildTenant godoc
+// @Summary Reactivate a child tenant
+// @Description Lift the suspension of a tenant managed by the caller's tenant (requires the child_tenants:manage permission)
+// @Tags Tenant
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Child tenant ID"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Child tenant reactivated successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "ID is required or Invalid ID"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Forbidden"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "Tenant not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /tenant/children/reactivate [post]
+func (h *TenantHandler) ReactivateChildTenant(c *gin.Context) {
+	h.setChildTenantActive(c, true, "Child tenant reactivated successfully")
+}
+
+func (h *TenantHandler) setChildTenantActive(c *gin.Context, active bool, message string) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	childID, ok := idQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.tenantService.SetChildTenantActive(tokenDto.TenantID, childID, active); err != nil {
+		tenantErrorResponse(c, err)
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, message)
+}
+
+func idQuery(c *gin.Context) (uint, bool) {
+	id := c.Query("id")
+	if id == "" {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("ID is required"))
+		return 0, false
+	}
+
+	parsedID, err := strconv.Atoi(id)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid ID"))
+		return 0, false
+	}
+
+	return uint(parsedID), true
+}
+
+func tenantErrorResponse(c *gin.Context, err error) {
+	var policyErr *frameworkutils.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		frameworkutils.ErrorResponse(c, frameworkutils.PasswordPolicyViolation(policyErr))
+		return
+	}
+
+	switch err {
+	case frameworkconstants.ErrInvalidTenantSlug, frameworkconstants.ErrInvalidDomain, frameworkconstants.ErrDomainVerificationFailed,
+		frameworkconstants.ErrInvalidSeatAllocation:
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+	case frameworkconstants.ErrTenantLicenceExceeded, frameworkconstants.ErrTenantLicenceExpired, frameworkconstants.ErrLicenceTypeNotGrantable:
+		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden(err.Error()))
+	case frameworkconstants.ErrDomainNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Domain"))
+	case frameworkconstants.ErrTenantNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Tenant"))
+	case frameworkconstants.ErrTenantLicenceNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Tenant licence"))
+	case frameworkconstants.ErrLicenceTypeNotFound:
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("Licence type"))
+	case frameworkconstants.ErrTenantSlugTaken, frameworkconstants.ErrDomainAlreadyExists, frameworkconstants.ErrDomainAlreadyVerified,
+		frameworkconstants.ErrTenantAlreadyExists, frameworkconstants.ErrTenantHasChildren:
+		frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
+	default:
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+	}
 }
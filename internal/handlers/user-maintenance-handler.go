@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
@@ -13,12 +15,15 @@ import (
 )
 
 type UserMaintenanceHandler struct {
-	jwtSecret              string
+	authMiddleware         gin.HandlerFunc
+	rbacService            *services.RBACService
+	reauthenticationMaxAge time.Duration
 	userMaintenanceService *services.UserMaintenanceService
+	passwordService        *services.PasswordService
 }
 
-func NewUserMaintenanceHandler(jwtSecret string, userMaintenanceService *services.UserMaintenanceService) *UserMaintenanceHandler {
-	return &UserMaintenanceHandler{jwtSecret: jwtSecret, userMaintenanceService: userMaintenanceService}
+func NewUserMaintenanceHandler(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, reauthenticationMaxAge time.Duration, userMaintenanceService *services.UserMaintenanceService, passwordService *services.PasswordService) *UserMaintenanceHandler {
+	return &UserMaintenanceHandler{authMiddleware: authMiddleware, rbacService: rbacService, reauthenticationMaxAge: reauthenticationMaxAge, userMaintenanceService: userMaintenanceService, passwordService: passwordService}
 }
 
 func (h *UserMaintenanceHandler) RegisterRoutes(router *gin.Engine) {
@@ -28,18 +33,20 @@ func (h *UserMaintenanceHandler) RegisterRoutes(router *gin.Engine) {
 	api.POST("/reset-password", h.ResetPassword)
 	api.POST("/verify-email", h.VerifyEmail)
 
-	protected := api.Use(middleware.BearerAuthMiddleware(h.jwtSecret))
+	protected := api.Use(h.authMiddleware)
 	{
-		protected.DELETE("/user", h.DeleteUser)
+		protected.DELETE("/user", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionUsersDelete), frameworkutils.RequireRecentAuthentication(h.reauthenticationMaxAge), h.DeleteUser)
 		protected.PUT("/user", h.UpdateUser)
-		protected.GET("/users/get-all", h.GetAllUsers)
-		protected.GET("/users/get-roles", h.GetUserRoles)
+		protected.POST("/user/unlock", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionUsersUnlock), h.UnlockUser)
+		protected.POST("/change-password", h.ChangePassword)
+		protected.GET("/users/get-all", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionUsersRead), h.GetAllUsers)
+		protected.GET("/users/get-roles", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionUsersRead), h.GetUserRoles)
 	}
 }
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Delete a user from the tenant (requires authentication, tenant admin only)
+// @Description Delete a user from the tenant (requires the users:delete permission, every permission of the user's role and a recent login or re-authentication)
 // @Tags User Maintenance
 // @Accept json
 // @Produce json
@@ -49,6 +56,7 @@ func (h *UserMaintenanceHandler) RegisterRoutes(router *gin.Engine) {
 // @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid User ID format or User ID is required"
 // @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
 // @Failure 403 {object} frameworkdto.ErrorResponseDTO "You cannot delete yourself or not authorized"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /user-maintenance/user [delete]
 func (h *UserMaintenanceHandler) DeleteUser(c *gin.Context) {
@@ -80,8 +88,7 @@ func (h *UserMaintenanceHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if tokenDto.Role != string(frameworkconstants.UserRoleTenantUser) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to delete this user"))
+	if !h.canManageUser(c, tokenDto, uint(userID)) {
 		return
 	}
 
@@ -96,16 +103,18 @@ func (h *UserMaintenanceHandler) DeleteUser(c *gin.Context) {
 
 // UpdateUser godoc
 // @Summary Update a user
-// @Description Update user details (requires authentication, tenant admin or self)
+// @Description Update user details. Users can update their own name and email; updating other users, roles or status requires the users:update permission, and only users whose current and new roles grant no permission the caller lacks can be updated this way. Changing the email address requires a recent login or re-authentication; otherwise 401 with code REAUTHENTICATION_REQUIRED is returned.
 // @Tags User Maintenance
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param updateUserDTO body frameworkdto.UserUpdateRequestDTO true "User update details"
 // @Success 202 {object} frameworkdto.SuccessResponseDTO "User updated successfully"
-// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or User ID format"
-// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, User ID format or role"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized or re-authentication required"
 // @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to update this user"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "Another user of the tenant has the email address"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /user-maintenance/user [put]
 func (h *UserMaintenanceHandler) UpdateUser(c *gin.Context) {
@@ -127,14 +136,47 @@ func (h *UserMaintenanceHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	if updateUserDTO.UserID != uint(currentUserID) || tokenDto.Role != string(frameworkconstants.UserRoleTenantAdmin) {
+	manageUsers, err := h.rbacService.HasPermission(tokenDto, frameworkconstants.PermissionUsersUpdate)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return
+	}
+
+	if updateUserDTO.UserID != uint(currentUserID) && !manageUsers {
 		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to update this user"))
 		return
 	}
 
-	err = h.userMaintenanceService.UpdateUser(tokenDto.TenantID, updateUserDTO.UserID, updateUserDTO)
+	if manageUsers {
+		if !h.canManageUser(c, tokenDto, updateUserDTO.UserID) {
+			return
+		}
+
+		canAssign, err := h.rbacService.CanAssignRole(tokenDto, updateUserDTO.Role)
+		if err == frameworkconstants.ErrRoleNotFound {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+			return
+		} else if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+			return
+		}
+		if !canAssign {
+			frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to assign this role"))
+			return
+		}
+	}
+
+	recentlyAuthenticated := frameworkutils.IsRecentlyAuthenticated(tokenDto, h.reauthenticationMaxAge)
+	err = h.userMaintenanceService.UpdateUser(tokenDto.TenantID, updateUserDTO.UserID, updateUserDTO, manageUsers, recentlyAuthenticated)
 	if err != nil {
-		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		switch err {
+		case frameworkconstants.ErrReauthenticationRequired:
+			frameworkutils.ErrorResponse(c, frameworkutils.ReauthenticationRequired(h.reauthenticationMaxAge))
+		case frameworkconstants.ErrUserAlreadyExists:
+			frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
 		return
 	}
 
@@ -143,13 +185,13 @@ func (h *UserMaintenanceHandler) UpdateUser(c *gin.Context) {
 
 // ResetPasswordRequest godoc
 // @Summary Request password reset
-// @Description Request a password reset token to be sent to the user's email
+// @Description Request a password reset token to be sent to the user's email. Requests addressed to a tenant through its subdomain, a verified custom domain or the X-Tenant-ID header only look up that tenant's users; an email address shared by users of several tenants must be addressed to one.
 // @Tags User Maintenance
 // @Accept json
 // @Produce json
 // @Param resetPasswordRequestDTO body frameworkdto.ResetPasswordRequestDTO true "Email address"
 // @Success 202 {object} frameworkdto.SuccessResponseDTO "Reset password request sent successfully"
-// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, or an email address shared by several tenants without a tenant"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /user-maintenance/reset-password-request [post]
 func (h *UserMaintenanceHandler) ResetPasswordRequest(c *gin.Context) {
@@ -159,8 +201,11 @@ func (h *UserMaintenanceHandler) ResetPasswordRequest(c *gin.Context) {
 		return
 	}
 
-	err := h.userMaintenanceService.SetResetPasswordToken(resetPasswordRequestDTO.Email)
-	if err != nil {
+	err := h.userMaintenanceService.SetResetPasswordToken(resetPasswordRequestDTO.Email, resolvedTenantID(c))
+	if err == frameworkconstants.ErrTenantRequired {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+		return
+	} else if err != nil {
 		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
 		return
 	}
@@ -176,7 +221,7 @@ func (h *UserMaintenanceHandler) ResetPasswordRequest(c *gin.Context) {
 // @Produce json
 // @Param resetPasswordDTO body frameworkdto.ResetPasswordDTO true "Reset token and new password"
 // @Success 202 {object} frameworkdto.SuccessResponseDTO "Password reset successfully"
-// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body, invalid or expired reset token or password policy violation"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /user-maintenance/reset-password [post]
 func (h *UserMaintenanceHandler) ResetPassword(c *gin.Context) {
@@ -188,6 +233,15 @@ func (h *UserMaintenanceHandler) ResetPassword(c *gin.Context) {
 
 	err := h.userMaintenanceService.UpdateUserPassword(resetPasswordDTO.ResetToken, resetPasswordDTO.NewPassword)
 	if err != nil {
+		var policyErr *frameworkutils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			frameworkutils.ErrorResponse(c, frameworkutils.PasswordPolicyViolation(policyErr))
+			return
+		}
+		if err == frameworkconstants.ErrInvalidResetPasswordToken {
+			frameworkutils.ErrorResponse(c, frameworkutils.BadRequest(err.Error()))
+			return
+		}
 		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
 		return
 	}
@@ -195,6 +249,69 @@ func (h *UserMaintenanceHandler) ResetPassword(c *gin.Context) {
 	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Password reset successfully")
 }
 
+// ChangePassword godoc
+// @Summary Change password
+// @Description Replace the caller's password after confirming the current one. Every other session is signed out.
+// @Tags User Maintenance
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param changePasswordDTO body frameworkdto.ChangePasswordDTO true "Current and new password"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "Password changed successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or password policy violation"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized or current password is incorrect"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
+// @Failure 423 {object} frameworkdto.ErrorResponseDTO "Account locked after too many wrong passwords"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Failure 503 {object} frameworkdto.ErrorResponseDTO "The tenant's LDAP directory is unavailable"
+// @Router /user-maintenance/change-password [post]
+func (h *UserMaintenanceHandler) ChangePassword(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	userID, err := strconv.Atoi(tokenDto.Sub)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid User ID format"))
+		return
+	}
+
+	var changePasswordDTO frameworkdto.ChangePasswordDTO
+	if err := c.ShouldBindJSON(&changePasswordDTO); err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid request body"))
+		return
+	}
+
+	err = h.passwordService.ChangePassword(tokenDto.TenantID, uint(userID), tokenDto.Sid, changePasswordDTO)
+	if err != nil {
+		var policyErr *frameworkutils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			frameworkutils.ErrorResponse(c, frameworkutils.PasswordPolicyViolation(policyErr))
+			return
+		}
+		if errors.Is(err, frameworkconstants.ErrLDAPUnavailable) {
+			frameworkutils.ErrorResponse(c, frameworkutils.ServiceUnavailable(frameworkconstants.ErrLDAPUnavailable.Error()))
+			return
+		}
+
+		switch err {
+		case frameworkconstants.ErrInvalidPassword:
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Current password is incorrect"))
+		case frameworkconstants.ErrAccountLocked:
+			frameworkutils.ErrorResponse(c, frameworkutils.AccountLocked(err.Error()))
+		case frameworkconstants.ErrUserNotFound:
+			frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+		default:
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		}
+		return
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "Password changed successfully")
+}
+
 // VerifyEmail godoc
 // @Summary Verify email address
 // @Description Verify user email address using the verification token sent via email
@@ -224,7 +341,7 @@ func (h *UserMaintenanceHandler) VerifyEmail(c *gin.Context) {
 
 // GetAllUsers godoc
 // @Summary Get all users
-// @Description Get all users for the authenticated tenant (requires authentication, tenant admin only)
+// @Description Get all users for the authenticated tenant (requires the users:read permission)
 // @Tags User Maintenance
 // @Accept json
 // @Produce json
@@ -241,11 +358,6 @@ func (h *UserMaintenanceHandler) GetAllUsers(c *gin.Context) {
 		return
 	}
 
-	if tokenDto.Role != string(frameworkconstants.UserRoleTenantAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to get all users"))
-		return
-	}
-
 	users, err := h.userMaintenanceService.GetAllUsersByTenantID(tokenDto.TenantID)
 	if err != nil {
 		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
@@ -257,7 +369,7 @@ func (h *UserMaintenanceHandler) GetAllUsers(c *gin.Context) {
 
 // GetUserRoles godoc
 // @Summary Get all user roles
-// @Description Get list of all available user roles (requires authentication, tenant admin only)
+// @Description List the built-in and custom roles the caller is allowed to assign (requires the users:read permission)
 // @Tags User Maintenance
 // @Accept json
 // @Produce json
@@ -274,16 +386,94 @@ func (h *UserMaintenanceHandler) GetUserRoles(c *gin.Context) {
 		return
 	}
 
-	if tokenDto.Role != string(frameworkconstants.UserRoleTenantAdmin) {
-		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to get this resource"))
+	userRoles, err := h.userMaintenanceService.GetUserRoles(tokenDto.TenantID)
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
 		return
 	}
 
-	roles, err := h.userMaintenanceService.GetUserRoles()
+	roles := make([]frameworkdto.GetUserRoles, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		canAssign, err := h.rbacService.CanAssignRole(tokenDto, userRole.Role)
+		if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+			return
+		}
+		if canAssign {
+			roles = append(roles, userRole)
+		}
+	}
+
+	frameworkutils.SuccessResponse(c, http.StatusOK, roles, "User roles fetched successfully")
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user
+// @Description Lift a lockout caused by failed login attempts (requires the users:unlock permission)
+// @Tags User Maintenance
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userId query int true "User ID to unlock"
+// @Success 202 {object} frameworkdto.SuccessResponseDTO "User unlocked successfully"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid User ID format or User ID is required"
+// @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to unlock this user"
+// @Failure 404 {object} frameworkdto.ErrorResponseDTO "User not found"
+// @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
+// @Router /user-maintenance/user/unlock [post]
+func (h *UserMaintenanceHandler) UnlockUser(c *gin.Context) {
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
 	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+		return
+	}
+
+	userID, err := strconv.Atoi(c.Query("userId"))
+	if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("Invalid User ID format"))
+		return
+	}
+
+	if userID <= 0 {
+		frameworkutils.ErrorResponse(c, frameworkutils.BadRequest("User ID is required"))
+		return
+	}
+
+	err = h.userMaintenanceService.UnlockUser(tokenDto.TenantID, uint(userID))
+	if err != nil {
+		if err == frameworkconstants.ErrUserNotFound {
+			frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+			return
+		}
 		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
 		return
 	}
 
-	frameworkutils.SuccessResponse(c, http.StatusOK, roles, "User roles fetched successfully")
+	frameworkutils.SuccessResponse(c, http.StatusAccepted, nil, "User unlocked successfully")
+}
+
+// canManageUser reports whether the caller holds every permission of the user's current role,
+// so that managing users cannot be used against those more privileged than the caller. It
+// writes the error response when not.
+func (h *UserMaintenanceHandler) canManageUser(c *gin.Context, tokenDto frameworkdto.TokenDTO, userID uint) bool {
+	role, err := h.userMaintenanceService.GetUserRole(tokenDto.TenantID, userID)
+	if err == frameworkconstants.ErrUserNotFound {
+		frameworkutils.ErrorResponse(c, frameworkutils.NotFound("User"))
+		return false
+	} else if err != nil {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return false
+	}
+
+	canManage, err := h.rbacService.CanAssignRole(tokenDto, role)
+	if err != nil && err != frameworkconstants.ErrRoleNotFound {
+		frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+		return false
+	}
+	if !canManage {
+		frameworkutils.ErrorResponse(c, frameworkutils.Forbidden("You are not authorized to manage this user"))
+		return false
+	}
+	return true
 }
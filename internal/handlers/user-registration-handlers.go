@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
 	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
@@ -10,13 +12,15 @@ import (
 )
 
 type RegistrationHandlers struct {
-	jwtSecret           string
+	authMiddleware      gin.HandlerFunc
+	rbacService         *services.RBACService
 	registrationService *services.UserRegistrationService
 }
 
-func NewRegistrationHandlers(jwtSecret string, registrationService *services.UserRegistrationService) *RegistrationHandlers {
+func NewRegistrationHandlers(authMiddleware gin.HandlerFunc, rbacService *services.RBACService, registrationService *services.UserRegistrationService) *RegistrationHandlers {
 	return &RegistrationHandlers{
-		jwtSecret:           jwtSecret,
+		authMiddleware:      authMiddleware,
+		rbacService:         rbacService,
 		registrationService: registrationService,
 	}
 }
@@ -25,9 +29,9 @@ func (h *RegistrationHandlers) RegisterRoutes(router *gin.Engine) {
 	api := router.Group("/registration")
 	api.POST("/tenant", h.RegisterTenant)
 
-	protected := api.Use(middleware.BearerAuthMiddleware(h.jwtSecret))
+	protected := api.Use(h.authMiddleware)
 	{
-		protected.POST("/user", h.AddUser)
+		protected.POST("/user", middleware.RequirePermission(h.rbacService, frameworkconstants.PermissionUsersCreate), h.AddUser)
 	}
 }
 
@@ -39,7 +43,7 @@ func (h *RegistrationHandlers) RegisterRoutes(router *gin.Engine) {
 // @Produce json
 // @Param tenantDTO body frameworkdto.TenantRegistrationDTO true "Tenant registration details"
 // @Success 201 {object} frameworkdto.CreatedResponseDTO "Tenant registered successfully"
-// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or password policy violation"
 // @Failure 409 {object} frameworkdto.ErrorResponseDTO "Tenant already exists"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /registration/tenant [post]
@@ -52,6 +56,12 @@ func (h *RegistrationHandlers) RegisterTenant(c *gin.Context) {
 
 	err := h.registrationService.RegisterTenant(tenantDTO)
 	if err != nil {
+		var policyErr *frameworkutils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			frameworkutils.ErrorResponse(c, frameworkutils.PasswordPolicyViolation(policyErr))
+			return
+		}
+
 		if err == frameworkconstants.ErrTenantAlreadyExists {
 			frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
 			return
@@ -66,15 +76,17 @@ func (h *RegistrationHandlers) RegisterTenant(c *gin.Context) {
 
 // AddUser godoc
 // @Summary Add a new user to tenant
-// @Description Add a new user to the authenticated tenant (requires authentication)
+// @Description Add a new user to the authenticated tenant (requires the users:create permission)
 // @Tags Registration
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param userDTO body frameworkdto.UserRegistrationDTO true "User registration details"
 // @Success 201 {object} frameworkdto.CreatedResponseDTO "User added successfully"
-// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body"
+// @Failure 400 {object} frameworkdto.ErrorResponseDTO "Invalid request body or password policy violation"
 // @Failure 401 {object} frameworkdto.ErrorResponseDTO "Unauthorized"
+// @Failure 403 {object} frameworkdto.ErrorResponseDTO "Not authorized to add users"
+// @Failure 409 {object} frameworkdto.ErrorResponseDTO "A user with the email address already exists in the tenant"
 // @Failure 500 {object} frameworkdto.ErrorResponseDTO "Internal server error"
 // @Router /registration/user [post]
 func (h *RegistrationHandlers) AddUser(c *gin.Context) {
@@ -92,6 +104,15 @@ func (h *RegistrationHandlers) AddUser(c *gin.Context) {
 
 	err = h.registrationService.RegisterUser(tokenDTO.TenantID, userDTO)
 	if err != nil {
+		var policyErr *frameworkutils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			frameworkutils.ErrorResponse(c, frameworkutils.PasswordPolicyViolation(policyErr))
+			return
+		}
+		if err == frameworkconstants.ErrUserAlreadyExists {
+			frameworkutils.ErrorResponse(c, frameworkutils.Conflict(err.Error()))
+			return
+		}
 		frameworkutils.ErrorResponse(c, err)
 		return
 	}
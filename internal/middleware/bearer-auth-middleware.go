@@ -5,27 +5,115 @@ import (
 	"strings"
 
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
 	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
 	"github.com/gin-gonic/gin"
 )
 
-func BearerAuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// TokenRevocationChecker reports whether a correctly signed token has since been revoked
+type TokenRevocationChecker interface {
+	IsRevoked(tokenDto frameworkdto.TokenDTO) (bool, error)
+}
+
+// APIKeyAuthenticator resolves an API key to the service principal it represents
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(key string) (frameworkdto.TokenDTO, error)
+}
+
+func BearerAuthMiddleware(keySet *frameworkutils.JWTKeySet, revocationChecker TokenRevocationChecker) gin.HandlerFunc {
+	return AuthMiddleware(keySet, revocationChecker, nil)
+}
+
+// AuthMiddleware accepts a bearer JWT or, when an authenticator is supplied, an API key sent in
+// the X-API-Key header or as a bearer token carrying the API key prefix
+func AuthMiddleware(keySet *frameworkutils.JWTKeySet, revocationChecker TokenRevocationChecker, apiKeyAuthenticator APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		bearerToken := c.GetHeader("Authorization")
-		if bearerToken == "" {
+		apiKey := c.GetHeader(frameworkconstants.APIKeyHeader)
+
+		token := ""
+		if bearerToken := c.GetHeader("Authorization"); bearerToken != "" {
+			scheme, value, found := strings.Cut(bearerToken, " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || value == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header"})
+				return
+			}
+			token = strings.TrimSpace(value)
+		}
+
+		if apiKey == "" && strings.HasPrefix(token, frameworkconstants.APIKeyPrefix) {
+			apiKey, token = token, ""
+		}
+
+		if apiKey != "" && apiKeyAuthenticator != nil {
+			tokenDto, err := apiKeyAuthenticator.AuthenticateAPIKey(apiKey)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				return
+			}
+			if !belongsToResolvedTenant(c, tokenDto) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Credentials belong to another tenant"})
+				return
+			}
+
+			setTokenDTO(c, tokenDto)
+			c.Next()
+			return
+		}
+
+		if token == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing Authorization header"})
 			return
 		}
 
-		token := strings.Split(bearerToken, " ")[1]
-		tokenDto, err := frameworkutils.ParseJWT(token, []byte(jwtSecret))
+		tokenDto, err := keySet.ParseJWT(token)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
 
-		c.Set(frameworkconstants.TokenKey, tokenDto)
+		if revocationChecker != nil {
+			revoked, err := revocationChecker.IsRevoked(tokenDto)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Unable to validate token"})
+				return
+			}
+			if revoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				return
+			}
+		}
+
+		// Impersonation tokens are read-only unless the route opts in with AllowDuringImpersonation
+		if frameworkutils.IsImpersonating(tokenDto) && !isReadOnlyMethod(c.Request.Method) && !c.GetBool(frameworkconstants.ImpersonationAllowedKey) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "This action is not allowed while impersonating a user"})
+			return
+		}
+
+		if !belongsToResolvedTenant(c, tokenDto) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Credentials belong to another tenant"})
+			return
+		}
+
+		setTokenDTO(c, tokenDto)
 
 		c.Next()
 	}
 }
+
+// setTokenDTO stores the caller's token and scopes tenant-scoped queries made with the request
+// context to the caller's tenant
+func setTokenDTO(c *gin.Context, tokenDto frameworkdto.TokenDTO) {
+	c.Set(frameworkconstants.TokenKey, tokenDto)
+	c.Request = c.Request.WithContext(frameworkutils.WithTenantID(c.Request.Context(), tokenDto.TenantID))
+}
+
+// belongsToResolvedTenant reports whether the caller's tenant is the tenant the request was
+// addressed to, if any
+func belongsToResolvedTenant(c *gin.Context, tokenDto frameworkdto.TokenDTO) bool {
+	tenant, ok := frameworkutils.GetResolvedTenant(c)
+	return !ok || tenant.TenantID == tokenDto.TenantID
+}
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionChecker reports whether the caller's role grants a permission
+type PermissionChecker interface {
+	HasPermission(tokenDto frameworkdto.TokenDTO, permission string) (bool, error)
+}
+
+// RequirePermission returns middleware, to be placed after the auth middleware, that rejects
+// callers whose role does not grant the permission with HTTP 403
+func RequirePermission(permissionChecker PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenDto, err := frameworkutils.GetTokenDTO(c)
+		if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+			c.Abort()
+			return
+		}
+
+		granted, err := permissionChecker.HasPermission(tokenDto, permission)
+		if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+			c.Abort()
+			return
+		}
+
+		if !granted {
+			responseErr := frameworkutils.Forbidden("You are not authorized to perform this action")
+			responseErr.Details["permission"] = permission
+			frameworkutils.ErrorResponse(c, responseErr)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyEvaluator decides an action with the caller's tenant's access policies
+type PolicyEvaluator interface {
+	Evaluate(tokenDto frameworkdto.TokenDTO, request frameworkdto.PolicyRequestDTO, action string, resource any) (frameworkdto.PolicyDecisionDTO, error)
+}
+
+// RequirePolicy returns middleware, to be placed after the auth middleware, that rejects
+// requests the tenant's access policies deny with HTTP 403. The resource loader may be nil.
+func RequirePolicy(policyEvaluator PolicyEvaluator, action string, resourceLoader frameworkutils.PolicyResourceLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenDto, err := frameworkutils.GetTokenDTO(c)
+		if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.UnauthorizedError("Unauthorized"))
+			c.Abort()
+			return
+		}
+
+		var resource any
+		if resourceLoader != nil {
+			resource, err = resourceLoader(c)
+			if err != nil {
+				frameworkutils.ErrorResponse(c, err)
+				c.Abort()
+				return
+			}
+		}
+
+		decision, err := policyEvaluator.Evaluate(tokenDto, frameworkutils.GetPolicyRequest(c), action, resource)
+		if err != nil {
+			frameworkutils.ErrorResponse(c, frameworkutils.InternalServerError(err.Error()))
+			c.Abort()
+			return
+		}
+
+		if !decision.Allowed {
+			responseErr := frameworkutils.Forbidden("You are not authorized to perform this action")
+			responseErr.Details["action"] = action
+			responseErr.Details["reason"] = decision.Reason
+			frameworkutils.ErrorResponse(c, responseErr)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
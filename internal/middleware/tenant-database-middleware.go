@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	"github.com/gin-gonic/gin"
+)
+
+// ReleaseTenantDatabase returns middleware that hands the tenant database connection a request
+// reserved back to the pool once the request is finished, even if the handler panics
+func ReleaseTenantDatabase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if release, ok := c.Get(frameworkconstants.TenantDatabaseReleaseKey); ok {
+				release.(func())()
+			}
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/gin-gonic/gin"
+)
+
+// TenantResolver identifies the tenant a request is addressed to from its host or tenant header
+type TenantResolver interface {
+	Resolve(host, header string) (*frameworkdto.ResolvedTenantDTO, error)
+}
+
+// TenantResolverMiddleware stores the tenant a request is addressed to in the gin context and
+// scopes tenant-scoped queries to it, ahead of authentication. Requests naming an unknown or
+// inactive tenant in the X-Tenant-ID header or a subdomain are rejected.
+func TenantResolverMiddleware(resolver TenantResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, err := resolver.Resolve(c.Request.Host, c.GetHeader(frameworkconstants.TenantHeader))
+		if err == frameworkconstants.ErrTenantNotFound {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+			return
+		} else if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Unable to resolve tenant"})
+			return
+		}
+
+		if tenant != nil {
+			c.Set(frameworkconstants.ResolvedTenantKey, *tenant)
+			c.Request = c.Request.WithContext(frameworkutils.WithTenantID(c.Request.Context(), tenant.TenantID))
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type AccessPolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewAccessPolicyRepository(db *gorm.DB) *AccessPolicyRepository {
+	return &AccessPolicyRepository{db: db}
+}
+
+func (r *AccessPolicyRepository) Create(policy *entities.AccessPolicy) error {
+	return r.db.Create(policy).Error
+}
+
+func (r *AccessPolicyRepository) GetByID(id, tenantID uint) (*entities.AccessPolicy, error) {
+	var policy entities.AccessPolicy
+	if err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *AccessPolicyRepository) GetAllByTenantID(tenantID uint) ([]entities.AccessPolicy, error) {
+	var policies []entities.AccessPolicy
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *AccessPolicyRepository) Update(policy *entities.AccessPolicy) error {
+	return r.db.Save(policy).Error
+}
+
+func (r *AccessPolicyRepository) Delete(policy *entities.AccessPolicy) error {
+	return r.db.Delete(policy).Error
+}
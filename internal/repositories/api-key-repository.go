@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(apiKey *entities.APIKey) error {
+	return r.db.Create(apiKey).Error
+}
+
+func (r *APIKeyRepository) GetByKeyHash(keyHash string) (*entities.APIKey, error) {
+	var apiKey entities.APIKey
+	if err := r.db.Where("key_hash = ?", keyHash).First(&apiKey).Error; err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *APIKeyRepository) GetByID(id, tenantID uint) (*entities.APIKey, error) {
+	var apiKey entities.APIKey
+	if err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&apiKey).Error; err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *APIKeyRepository) GetAllByTenantID(tenantID uint) ([]entities.APIKey, error) {
+	var apiKeys []entities.APIKey
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("id").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func (r *APIKeyRepository) Update(apiKey *entities.APIKey) error {
+	return r.db.Save(apiKey).Error
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type GroupRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) *GroupRepository {
+	return &GroupRepository{db: db}
+}
+
+func (r *GroupRepository) Create(group *entities.Group) error {
+	return r.db.Omit("Members").Create(group).Error
+}
+
+// GetByID returns one of the tenant's groups with its roles and members
+func (r *GroupRepository) GetByID(id, tenantID uint) (*entities.Group, error) {
+	var group entities.Group
+	if err := r.db.Preload("Roles").Preload("Members").Where("id = ? AND tenant_id = ?", id, tenantID).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *GroupRepository) GetByName(name string, tenantID uint) (*entities.Group, error) {
+	var group entities.Group
+	if err := r.db.Where("name = ? AND tenant_id = ?", name, tenantID).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetAllByTenantID returns the tenant's groups with their roles
+func (r *GroupRepository) GetAllByTenantID(tenantID uint) ([]entities.Group, error) {
+	var groups []entities.Group
+	if err := r.db.Preload("Roles").Where("tenant_id = ?", tenantID).Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetAllWithRolePermissions returns every tenant's groups with their roles and the roles' permissions
+func (r *GroupRepository) GetAllWithRolePermissions() ([]entities.Group, error) {
+	var groups []entities.Group
+	if err := r.db.Preload("Roles.Permissions").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetGroupIDsByUserID returns the groups the user is a direct member of
+func (r *GroupRepository) GetGroupIDsByUserID(userID uint) ([]uint, error) {
+	var groupIDs []uint
+	if err := r.db.Table("group_members").Where("user_id = ?", userID).Pluck("group_id", &groupIDs).Error; err != nil {
+		return nil, err
+	}
+	return groupIDs, nil
+}
+
+// GetMembers returns the users who are direct members of any of the groups
+func (r *GroupRepository) GetMembers(groupIDs []uint) ([]entities.User, error) {
+	var users []entities.User
+	memberIDs := r.db.Table("group_members").Select("user_id").Where("group_id IN ?", groupIDs)
+	if err := r.db.Where("id IN (?)", memberIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Update saves the group and replaces its roles
+func (r *GroupRepository) Update(group *entities.Group) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Roles", "Members").Save(group).Error; err != nil {
+			return err
+		}
+		return tx.Model(group).Association("Roles").Replace(group.Roles)
+	})
+}
+
+// Delete removes the group, its role grants and its memberships permanently, so the name can be reused
+func (r *GroupRepository) Delete(group *entities.Group) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(group).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(group).Association("Members").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(group).Error
+	})
+}
+
+func (r *GroupRepository) AddMembers(group *entities.Group, users []entities.User) error {
+	return r.db.Model(group).Omit("Members.*").Association("Members").Append(users)
+}
+
+func (r *GroupRepository) RemoveMember(group *entities.Group, user *entities.User) error {
+	return r.db.Model(group).Association("Members").Delete(user)
+}
+
+func (r *GroupRepository) CountChildren(groupID uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&entities.Group{}).Where("parent_id = ?", groupID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountByRoleID counts the groups granting the role
+func (r *GroupRepository) CountByRoleID(roleID uint) (int64, error) {
+	var count int64
+	if err := r.db.Table("group_roles").Where("role_id = ?", roleID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
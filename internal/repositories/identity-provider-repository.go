@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type IdentityProviderRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityProviderRepository(db *gorm.DB) *IdentityProviderRepository {
+	return &IdentityProviderRepository{db: db}
+}
+
+func (r *IdentityProviderRepository) GetByTenantID(tenantID uint) (*entities.TenantIdentityProvider, error) {
+	var provider entities.TenantIdentityProvider
+	if err := r.db.First(&provider, "tenant_id = ?", tenantID).Error; err != nil {
+		return nil, err
+	}
+	return &provider, nil
+}
+
+func (r *IdentityProviderRepository) Save(provider *entities.TenantIdentityProvider) error {
+	return r.db.Save(provider).Error
+}
+
+func (r *IdentityProviderRepository) CreateLoginState(state *entities.OIDCLoginState) error {
+	return r.db.Create(state).Error
+}
+
+func (r *IdentityProviderRepository) GetLoginStateByHash(stateHash string) (*entities.OIDCLoginState, error) {
+	var state entities.OIDCLoginState
+	if err := r.db.Where("state_hash = ?", stateHash).First(&state).Error; err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// ConsumeLoginState marks the login state used unless it already was, reporting whether this
+// call marked it
+func (r *IdentityProviderRepository) ConsumeLoginState(id uint) (bool, error) {
+	result := r.db.Model(&entities.OIDCLoginState{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *IdentityProviderRepository) GetExternalIdentity(tenantID uint, issuer, subject string) (*entities.ExternalIdentity, error) {
+	var identity entities.ExternalIdentity
+	if err := r.db.Where("tenant_id = ? AND issuer = ? AND subject = ?", tenantID, issuer, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *IdentityProviderRepository) CreateExternalIdentity(identity *entities.ExternalIdentity) error {
+	return r.db.Create(identity).Error
+}
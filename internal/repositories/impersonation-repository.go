@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type ImpersonationRepository struct {
+	db *gorm.DB
+}
+
+func NewImpersonationRepository(db *gorm.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{db: db}
+}
+
+func (r *ImpersonationRepository) Create(impersonation *entities.Impersonation) error {
+	return r.db.Create(impersonation).Error
+}
+
+func (r *ImpersonationRepository) GetByID(id uint) (*entities.Impersonation, error) {
+	var impersonation entities.Impersonation
+	if err := r.db.First(&impersonation, id).Error; err != nil {
+		return nil, err
+	}
+	return &impersonation, nil
+}
+
+// GetAll returns impersonations newest first, limited to one target tenant when tenantID is not zero
+func (r *ImpersonationRepository) GetAll(tenantID uint) ([]entities.Impersonation, error) {
+	var impersonations []entities.Impersonation
+	query := r.db.Order("id DESC")
+	if tenantID != 0 {
+		query = query.Where("target_tenant_id = ?", tenantID)
+	}
+	if err := query.Find(&impersonations).Error; err != nil {
+		return nil, err
+	}
+	return impersonations, nil
+}
+
+func (r *ImpersonationRepository) Update(impersonation *entities.Impersonation) error {
+	return r.db.Save(impersonation).Error
+}
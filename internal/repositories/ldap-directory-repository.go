@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type LDAPDirectoryRepository struct {
+	db *gorm.DB
+}
+
+func NewLDAPDirectoryRepository(db *gorm.DB) *LDAPDirectoryRepository {
+	return &LDAPDirectoryRepository{db: db}
+}
+
+func (r *LDAPDirectoryRepository) GetByTenantID(tenantID uint) (*entities.TenantLDAPDirectory, error) {
+	var directory entities.TenantLDAPDirectory
+	if err := r.db.First(&directory, "tenant_id = ?", tenantID).Error; err != nil {
+		return nil, err
+	}
+	return &directory, nil
+}
+
+func (r *LDAPDirectoryRepository) Save(directory *entities.TenantLDAPDirectory) error {
+	return r.db.Save(directory).Error
+}
+
+func (r *LDAPDirectoryRepository) DeleteByTenantID(tenantID uint) error {
+	return r.db.Unscoped().Where("tenant_id = ?", tenantID).Delete(&entities.TenantLDAPDirectory{}).Error
+}
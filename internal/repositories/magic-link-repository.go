@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type MagicLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewMagicLinkRepository(db *gorm.DB) *MagicLinkRepository {
+	return &MagicLinkRepository{db: db}
+}
+
+func (r *MagicLinkRepository) Create(magicLink *entities.MagicLinkToken) error {
+	return r.db.Create(magicLink).Error
+}
+
+func (r *MagicLinkRepository) GetByTokenHash(tokenHash string) (*entities.MagicLinkToken, error) {
+	var magicLink entities.MagicLinkToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&magicLink).Error; err != nil {
+		return nil, err
+	}
+	return &magicLink, nil
+}
+
+// Consume marks the magic link used unless it already was, reporting whether this call
+// marked it
+func (r *MagicLinkRepository) Consume(id uint) (bool, error) {
+	result := r.db.Model(&entities.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type MFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+func (r *MFARepository) CreateChallenge(challenge *entities.MFAChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+func (r *MFARepository) GetChallengeByTokenHash(tokenHash string) (*entities.MFAChallenge, error) {
+	var challenge entities.MFAChallenge
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ConsumeChallenge marks the challenge used unless it already was, reporting whether this call
+// marked it
+func (r *MFARepository) ConsumeChallenge(id uint) (bool, error) {
+	result := r.db.Model(&entities.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// IncrementChallengeFailures counts a wrong code against the challenge in the database rather
+// than in memory, so concurrent guesses all count
+func (r *MFARepository) IncrementChallengeFailures(id uint) error {
+	return r.db.Model(&entities.MFAChallenge{}).
+		Where("id = ?", id).
+		Update("failed_attempts", gorm.Expr("failed_attempts + 1")).Error
+}
+
+// ReplaceRecoveryCodes deletes the user's existing recovery codes and stores the new set
+func (r *MFARepository) ReplaceRecoveryCodes(userID uint, codes []entities.MFARecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&entities.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *MFARepository) GetUnusedRecoveryCode(userID uint, codeHash string) (*entities.MFARecoveryCode, error) {
+	var code entities.MFARecoveryCode
+	if err := r.db.Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).First(&code).Error; err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// UseRecoveryCode marks the recovery code used unless it already was, reporting whether this
+// call marked it
+func (r *MFARepository) UseRecoveryCode(id uint) (bool, error) {
+	result := r.db.Model(&entities.MFARecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type PasswordPolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordPolicyRepository(db *gorm.DB) *PasswordPolicyRepository {
+	return &PasswordPolicyRepository{db: db}
+}
+
+func (r *PasswordPolicyRepository) GetByTenantID(tenantID uint) (*entities.TenantPasswordPolicy, error) {
+	var policy entities.TenantPasswordPolicy
+	if err := r.db.Where("tenant_id = ?", tenantID).First(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *PasswordPolicyRepository) Save(policy *entities.TenantPasswordPolicy) error {
+	return r.db.Save(policy).Error
+}
+
+func (r *PasswordPolicyRepository) DeleteByTenantID(tenantID uint) error {
+	return r.db.Unscoped().Where("tenant_id = ?", tenantID).Delete(&entities.TenantPasswordPolicy{}).Error
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type PasswordRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordRepository(db *gorm.DB) *PasswordRepository {
+	return &PasswordRepository{db: db}
+}
+
+func (r *PasswordRepository) CreateHistory(history *entities.PasswordHistory) error {
+	return r.db.Create(history).Error
+}
+
+// GetRecentHistory returns the user's most recently replaced password hashes, newest first
+func (r *PasswordRepository) GetRecentHistory(userID uint, limit int) ([]entities.PasswordHistory, error) {
+	var history []entities.PasswordHistory
+	if err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// PruneHistory deletes all but the user's keep most recent password hashes
+func (r *PasswordRepository) PruneHistory(userID uint, keep int) error {
+	// MySQL does not support LIMIT in an IN subquery, so the IDs to keep are read first
+	var keepIDs []uint
+	if keep > 0 {
+		if err := r.db.Model(&entities.PasswordHistory{}).Where("user_id = ?", userID).Order("id DESC").Limit(keep).Pluck("id", &keepIDs).Error; err != nil {
+			return err
+		}
+	}
+
+	query := r.db.Unscoped().Where("user_id = ?", userID)
+	if len(keepIDs) > 0 {
+		query = query.Where("id NOT IN ?", keepIDs)
+	}
+	return query.Delete(&entities.PasswordHistory{}).Error
+}
+
+func (r *PasswordRepository) CreateChangeChallenge(challenge *entities.PasswordChangeChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+func (r *PasswordRepository) GetChangeChallengeByTokenHash(tokenHash string) (*entities.PasswordChangeChallenge, error) {
+	var challenge entities.PasswordChangeChallenge
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ConsumeChangeChallenge marks the challenge used unless it already was, reporting whether
+// this call marked it
+func (r *PasswordRepository) ConsumeChangeChallenge(id uint) (bool, error) {
+	result := r.db.Model(&entities.PasswordChangeChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+func (r *RefreshTokenRepository) Create(refreshToken *entities.RefreshToken) error {
+	return r.db.Create(refreshToken).Error
+}
+
+func (r *RefreshTokenRepository) GetByTokenHash(tokenHash string) (*entities.RefreshToken, error) {
+	var refreshToken entities.RefreshToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+func (r *RefreshTokenRepository) Update(refreshToken *entities.RefreshToken) error {
+	return r.db.Save(refreshToken).Error
+}
+
+// MarkUsed marks the refresh token as used unless it already was, reporting whether this call
+// marked it. Concurrent rotations of the same token cannot both succeed.
+func (r *RefreshTokenRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&entities.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&entities.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUser(userID uint) error {
+	return r.db.Model(&entities.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepository) RevokeAllForUserExceptFamily(userID uint, familyID string) error {
+	return r.db.Model(&entities.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, familyID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type RevokedTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) *RevokedTokenRepository {
+	return &RevokedTokenRepository{db: db}
+}
+
+func (r *RevokedTokenRepository) Create(revokedToken *entities.RevokedToken) error {
+	return r.db.Create(revokedToken).Error
+}
+
+func (r *RevokedTokenRepository) ExistsByJti(jti string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&entities.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired removes entries for tokens that would be rejected on their exp claim anyway
+func (r *RevokedTokenRepository) DeleteExpired() error {
+	return r.db.Unscoped().Where("expires_at < ?", time.Now()).Delete(&entities.RevokedToken{}).Error
+}
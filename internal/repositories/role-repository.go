@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// GetAll returns every role of every tenant with its permissions
+func (r *RoleRepository) GetAll() ([]entities.Role, error) {
+	var roles []entities.Role
+	if err := r.db.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetAllByTenantID returns the built-in roles and the tenant's own roles with their permissions
+func (r *RoleRepository) GetAllByTenantID(tenantID uint) ([]entities.Role, error) {
+	var roles []entities.Role
+	if err := r.db.Preload("Permissions").Where("tenant_id IS NULL OR tenant_id = ?", tenantID).Order("is_system DESC, name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetByID returns one of the tenant's own roles; built-in roles are not found
+func (r *RoleRepository) GetByID(id, tenantID uint) (*entities.Role, error) {
+	var role entities.Role
+	if err := r.db.Preload("Permissions").Where("id = ? AND tenant_id = ?", id, tenantID).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetByName finds a built-in role or one of the tenant's own roles
+func (r *RoleRepository) GetByName(name string, tenantID uint) (*entities.Role, error) {
+	var role entities.Role
+	if err := r.db.Where("name = ? AND (tenant_id IS NULL OR tenant_id = ?)", name, tenantID).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) Create(role *entities.Role) error {
+	return r.db.Create(role).Error
+}
+
+// Update saves the role and replaces its permissions
+func (r *RoleRepository) Update(role *entities.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return err
+		}
+		return tx.Model(role).Association("Permissions").Replace(role.Permissions)
+	})
+}
+
+// Delete removes the role and its permission grants permanently, so the name can be reused
+func (r *RoleRepository) Delete(role *entities.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(role).Error
+	})
+}
+
+func (r *RoleRepository) GetAllPermissions() ([]entities.Permission, error) {
+	var permissions []entities.Permission
+	if err := r.db.Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *RoleRepository) GetPermissionsByName(names []string) ([]entities.Permission, error) {
+	var permissions []entities.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	if err := r.db.Where("name IN ?", names).Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// SeedPermission creates the permission if it does not exist yet and returns it
+func (r *RoleRepository) SeedPermission(name, description string) (entities.Permission, error) {
+	permission := entities.Permission{Name: name, Description: description}
+	if err := r.db.Where("name = ?", name).FirstOrCreate(&permission).Error; err != nil {
+		return entities.Permission{}, err
+	}
+	return permission, nil
+}
+
+// SeedRole creates the built-in role if it does not exist yet and grants it any of the
+// permissions it is missing. Permissions granted to it by other means are kept.
+func (r *RoleRepository) SeedRole(name, description string, permissions []entities.Permission) error {
+	role := entities.Role{Name: name, Description: description, IsSystem: true}
+	if err := r.db.Where("name = ? AND tenant_id IS NULL", name).FirstOrCreate(&role).Error; err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	return r.db.Model(&role).Association("Permissions").Append(permissions)
+}
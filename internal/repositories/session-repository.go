@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(session *entities.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *SessionRepository) GetBySessionID(sessionID string) (*entities.Session, error) {
+	var session entities.Session
+	if err := r.db.Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveByUserID returns the user's sessions that are neither revoked nor expired, most recently used first
+func (r *SessionRepository) GetActiveByUserID(userID, tenantID uint) ([]entities.Session, error) {
+	var sessions []entities.Session
+	if err := r.db.Where("user_id = ? AND tenant_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, tenantID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RecordSeen records that the session was used, reporting whether it is still live. Sessions are
+// only ever changed column by column so that saving one cannot undo a concurrent revocation.
+func (r *SessionRepository) RecordSeen(sessionID string, seenAt time.Time) (bool, error) {
+	return r.updateLive(sessionID, map[string]any{"last_seen_at": seenAt})
+}
+
+// Extend records that the session was used and moves its expiry, reporting whether it is still live
+func (r *SessionRepository) Extend(sessionID string, seenAt, expiresAt time.Time) (bool, error) {
+	return r.updateLive(sessionID, map[string]any{"last_seen_at": seenAt, "expires_at": expiresAt})
+}
+
+// RecordAuthentication records that the user proved who they are again within the session,
+// reporting whether it is still live
+func (r *SessionRepository) RecordAuthentication(sessionID string, authTime time.Time, authMethods string) (bool, error) {
+	return r.updateLive(sessionID, map[string]any{"last_seen_at": authTime, "auth_time": authTime, "auth_methods": authMethods})
+}
+
+func (r *SessionRepository) updateLive(sessionID string, values map[string]any) (bool, error) {
+	result := r.db.Model(&entities.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Updates(values)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *SessionRepository) Revoke(sessionID string) error {
+	return r.db.Model(&entities.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllForUser revokes every session of the user except exceptSessionID, which may be empty
+func (r *SessionRepository) RevokeAllForUser(userID uint, exceptSessionID string) error {
+	return r.db.Model(&entities.Session{}).
+		Where("user_id = ? AND session_id <> ? AND revoked_at IS NULL", userID, exceptSessionID).
+		Update("revoked_at", time.Now()).Error
+}
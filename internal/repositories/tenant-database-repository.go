@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	"gorm.io/gorm"
+)
+
+// TenantDatabaseRepository manages the PostgreSQL schemas, row-level security policies and
+// connections keeping tenants' data apart
+type TenantDatabaseRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantDatabaseRepository(db *gorm.DB) *TenantDatabaseRepository {
+	return &TenantDatabaseRepository{db: db}
+}
+
+// MigrateSchema creates the schema if it does not exist and migrates the models into it
+func (r *TenantDatabaseRepository) MigrateSchema(schema string, models []any) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE SCHEMA IF NOT EXISTS " + quoteIdentifier(schema)).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("SELECT set_config('search_path', ? || ', ' || current_setting('search_path'), true)", quoteIdentifier(schema)).Error; err != nil {
+			return err
+		}
+		return tx.AutoMigrate(models...)
+	})
+}
+
+// EnableRowLevelSecurity limits the model's table to the rows of the tenant set in the
+// app.tenant_id setting, through the column holding the given field. Connections without the
+// setting see no rows unless app.bypass_rls is on. Shared rows, whose column is NULL, stay
+// visible to every tenant.
+func (r *TenantDatabaseRepository) EnableRowLevelSecurity(model any, tenantField string, shared bool) error {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	field := stmt.Schema.LookUpField(tenantField)
+	if field == nil || field.DBName == "" {
+		return frameworkconstants.ErrRowLevelSecurityColumn
+	}
+
+	table, column := quoteIdentifier(stmt.Schema.Table), quoteIdentifier(field.DBName)
+	const (
+		currentTenant = "NULLIF(current_setting('" + frameworkconstants.TenantIDSetting + "', true), '')"
+		bypass        = "current_setting('" + frameworkconstants.BypassRowLevelSecuritySetting + "', true) = 'on'"
+	)
+	// A missing setting compares as NULL, so the policy fails closed
+	check := fmt.Sprintf("%s OR %s = %s::bigint", bypass, column, currentTenant)
+	using := check
+	if shared {
+		using = fmt.Sprintf("%s OR %s IS NULL", check, column)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range []string{
+			"ALTER TABLE " + table + " ENABLE ROW LEVEL SECURITY",
+			// Table owners bypass row-level security unless it is forced
+			"ALTER TABLE " + table + " FORCE ROW LEVEL SECURITY",
+			"DROP POLICY IF EXISTS tenant_isolation ON " + table,
+			fmt.Sprintf("CREATE POLICY tenant_isolation ON %s USING (%s) WITH CHECK (%s)", table, using, check),
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ConnectToSchema reserves a connection that searches the schema before the default search
+// path. The release function resets the search path and returns the connection to the pool.
+func (r *TenantDatabaseRepository) ConnectToSchema(ctx context.Context, schema string) (*gorm.DB, func(), error) {
+	return r.connect(ctx, "SELECT set_config('search_path', $1 || ', ' || current_setting('search_path'), false)", quoteIdentifier(schema), "RESET search_path")
+}
+
+// ConnectAsTenant reserves a connection whose row-level security policies only show the
+// tenant's rows, turning off the bypass the framework's connections carry. The release
+// function restores both settings and returns the connection to the pool.
+func (r *TenantDatabaseRepository) ConnectAsTenant(ctx context.Context, tenantID uint) (*gorm.DB, func(), error) {
+	return r.connect(ctx,
+		"SELECT set_config('"+frameworkconstants.TenantIDSetting+"', $1, false), set_config('"+frameworkconstants.BypassRowLevelSecuritySetting+"', 'off', false)",
+		strconv.FormatUint(uint64(tenantID), 10),
+		"RESET "+frameworkconstants.TenantIDSetting, "RESET "+frameworkconstants.BypassRowLevelSecuritySetting)
+}
+
+func (r *TenantDatabaseRepository) connect(ctx context.Context, setSQL, value string, resetStatements ...string) (*gorm.DB, func(), error) {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.ExecContext(ctx, setSQL, value); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	release := func() {
+		for _, resetSQL := range resetStatements {
+			if _, err := conn.ExecContext(context.Background(), resetSQL); err != nil {
+				// Never hand a connection still set up for a tenant back to the pool
+				conn.Raw(func(any) error { return driver.ErrBadConn })
+				break
+			}
+		}
+		conn.Close()
+	}
+
+	tx := r.db.Session(&gorm.Session{Context: ctx})
+	tx.Statement.ConnPool = conn
+	return tx, release, nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
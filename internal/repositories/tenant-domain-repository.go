@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+type TenantDomainRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantDomainRepository(db *gorm.DB) *TenantDomainRepository {
+	return &TenantDomainRepository{db: db}
+}
+
+func (r *TenantDomainRepository) Create(domain *entities.TenantDomain) error {
+	return r.db.Create(domain).Error
+}
+
+func (r *TenantDomainRepository) GetByID(id, tenantID uint) (*entities.TenantDomain, error) {
+	var domain entities.TenantDomain
+	if err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&domain).Error; err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// GetByDomainAndTenant returns the tenant's claim on the domain, verified or not
+func (r *TenantDomainRepository) GetByDomainAndTenant(domain string, tenantID uint) (*entities.TenantDomain, error) {
+	var tenantDomain entities.TenantDomain
+	if err := r.db.Where("domain = ? AND tenant_id = ?", domain, tenantID).First(&tenantDomain).Error; err != nil {
+		return nil, err
+	}
+	return &tenantDomain, nil
+}
+
+// GetVerifiedByDomain returns the verified claim on the domain, whichever tenant holds it
+func (r *TenantDomainRepository) GetVerifiedByDomain(domain string) (*entities.TenantDomain, error) {
+	var tenantDomain entities.TenantDomain
+	if err := r.db.Where("verified_domain = ?", domain).First(&tenantDomain).Error; err != nil {
+		return nil, err
+	}
+	return &tenantDomain, nil
+}
+
+func (r *TenantDomainRepository) GetAllByTenantID(tenantID uint) ([]entities.TenantDomain, error) {
+	var domains []entities.TenantDomain
+	if err := r.db.Where("tenant_id = ?", tenantID).Order("domain").Find(&domains).Error; err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+func (r *TenantDomainRepository) Update(domain *entities.TenantDomain) error {
+	return r.db.Save(domain).Error
+}
+
+// Delete removes the domain permanently so another tenant can register it
+func (r *TenantDomainRepository) Delete(domain *entities.TenantDomain) error {
+	return r.db.Unscoped().Delete(domain).Error
+}
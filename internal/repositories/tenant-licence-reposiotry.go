@@ -1,10 +1,17 @@
 package repositories
 
 import (
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"gorm.io/gorm"
 )
 
+// seatsFit is the condition that a licence has room for more seats. Its capacity is the seat
+// limit allocated by a parent tenant or, without one, the licence type's MaxSeats.
+const seatsFit = "used_seats + allocated_seats + ? <= COALESCE(seat_limit, ?)"
+
 type TenantLicenceRepository struct {
 	db *gorm.DB
 }
@@ -56,3 +63,99 @@ func (r *TenantLicenceRepository) GetByTenantID(tenantID uint) (*entities.Tenant
 	}
 	return &tenantLicence, nil
 }
+
+// ReserveUserSeat takes a seat of the tenant's licence for a user unless the licence is full,
+// reporting whether it did. maxSeats is the licence type's MaxSeats.
+func (r *TenantLicenceRepository) ReserveUserSeat(tenantID uint, maxSeats int) (bool, error) {
+	result := r.db.Model(&entities.TenantLicence{}).
+		Where("tenant_id = ? AND "+seatsFit, tenantID, 1, maxSeats).
+		Update("used_seats", gorm.Expr("used_seats + 1"))
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseUserSeat returns a user's seat to the tenant's licence
+func (r *TenantLicenceRepository) ReleaseUserSeat(tenantID uint) error {
+	return r.db.Model(&entities.TenantLicence{}).
+		Where("tenant_id = ? AND used_seats > 0", tenantID).
+		Update("used_seats", gorm.Expr("used_seats - 1")).Error
+}
+
+// AllocateSeats sets seats of the tenant's licence aside for a child tenant unless the licence
+// has too few left, reporting whether it did. maxSeats is the licence type's MaxSeats.
+func (r *TenantLicenceRepository) AllocateSeats(tenantID uint, seats, maxSeats int) (bool, error) {
+	result := r.db.Model(&entities.TenantLicence{}).
+		Where("tenant_id = ? AND "+seatsFit, tenantID, seats, maxSeats).
+		Update("allocated_seats", gorm.Expr("allocated_seats + ?", seats))
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseSeats returns seats allocated to a child tenant to the tenant's licence
+func (r *TenantLicenceRepository) ReleaseSeats(tenantID uint, seats int) error {
+	return r.db.Model(&entities.TenantLicence{}).
+		Where("tenant_id = ? AND allocated_seats >= ?", tenantID, seats).
+		Update("allocated_seats", gorm.Expr("allocated_seats - ?", seats)).Error
+}
+
+// ReallocateSeats gives a child licence a new seat limit, licence type and expiry and moves the
+// difference in seats between it and its parent's licence in one transaction. It changes
+// nothing and returns ErrInvalidSeatAllocation when the child's seat limit has changed since
+// currentSeats was read or its users and children no longer fit, and ErrTenantLicenceExceeded
+// when the parent has too few seats left. parentMaxSeats is the parent's licence type's MaxSeats.
+func (r *TenantLicenceRepository) ReallocateSeats(parentTenantID uint, parentMaxSeats int, childTenantID uint, currentSeats *int, seats int, licenceTypeID uint, expiryDate *time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		child := tx.Model(&entities.TenantLicence{}).Where("tenant_id = ? AND used_seats + allocated_seats <= ?", childTenantID, seats)
+		if currentSeats == nil {
+			child = child.Where("seat_limit IS NULL")
+		} else {
+			child = child.Where("seat_limit = ?", *currentSeats)
+		}
+		result := child.Updates(map[string]any{
+			"seat_limit":      seats,
+			"licence_type_id": licenceTypeID,
+			"expiry_date":     expiryDate,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return frameworkconstants.ErrInvalidSeatAllocation
+		}
+
+		change := seats
+		if currentSeats != nil {
+			change -= *currentSeats
+		}
+		parent := tx.Model(&entities.TenantLicence{}).Where("tenant_id = ?", parentTenantID)
+		if change > 0 {
+			parent = parent.Where(seatsFit, change, parentMaxSeats)
+		}
+		result = parent.Update("allocated_seats", gorm.Expr("allocated_seats + ?", change))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return frameworkconstants.ErrTenantLicenceExceeded
+		}
+		return nil
+	})
+}
+
+// ReleaseChildSeats takes a child licence's seat limit away and returns its seats to the
+// parent's licence in one transaction. It returns ErrInvalidSeatAllocation, changing nothing,
+// when the child's seat limit is no longer seats.
+func (r *TenantLicenceRepository) ReleaseChildSeats(parentTenantID, childTenantID uint, seats int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.TenantLicence{}).
+			Where("tenant_id = ? AND seat_limit = ?", childTenantID, seats).
+			Update("seat_limit", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return frameworkconstants.ErrInvalidSeatAllocation
+		}
+		return tx.Model(&entities.TenantLicence{}).
+			Where("tenant_id = ? AND allocated_seats >= ?", parentTenantID, seats).
+			Update("allocated_seats", gorm.Expr("allocated_seats - ?", seats)).Error
+	})
+}
@@ -56,3 +56,37 @@ func (r *TenantRepository) GetByEmailDomain(emailDomain string) (*entities.Tenan
 	}
 	return &tenant, nil
 }
+
+func (r *TenantRepository) GetBySlug(slug string) (*entities.Tenant, error) {
+	var tenant entities.Tenant
+	if err := r.db.First(&tenant, "slug = ?", slug).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// GetChildren returns the tenants the parent manages with their licences
+func (r *TenantRepository) GetChildren(parentID uint) ([]entities.Tenant, error) {
+	var tenants []entities.Tenant
+	if err := r.db.Preload("TenantLicence.LicenceType").Where("parent_id = ?", parentID).Order("name").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// GetChild returns one of the parent's tenants with its licence
+func (r *TenantRepository) GetChild(tenantID, parentID uint) (*entities.Tenant, error) {
+	var tenant entities.Tenant
+	if err := r.db.Preload("TenantLicence.LicenceType").Where("id = ? AND parent_id = ?", tenantID, parentID).First(&tenant).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (r *TenantRepository) CountChildren(parentID uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&entities.Tenant{}).Where("parent_id = ?", parentID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -1,6 +1,9 @@
 package repositories
 
 import (
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"gorm.io/gorm"
 )
@@ -19,7 +22,7 @@ func (r *UserRepository) Create(user *entities.User) error {
 
 func (r *UserRepository) GetByID(userId, tenantId uint) (*entities.User, error) {
 	var user entities.User
-	if err := r.db.First(&user, "user_id = ? AND tenant_id = ?", userId, tenantId).Error; err != nil {
+	if err := r.db.First(&user, "id = ? AND tenant_id = ?", userId, tenantId).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -37,6 +40,45 @@ func (r *UserRepository) Update(user *entities.User) error {
 	return r.db.Save(user).Error
 }
 
+// UseTOTPStep records the TOTP time step as the user's last used one if it is later than the
+// step already recorded, reporting whether it was. A code cannot be replayed by racing it.
+func (r *UserRepository) UseTOTPStep(userId uint, step int64) (bool, error) {
+	result := r.db.Model(&entities.User{}).
+		Where("id = ? AND mfa_last_used_step < ?", userId, step).
+		Update("mfa_last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// RecordFailedLogin adds one to the user's failed login count in the database and returns the
+// failed login and lockout counts as they are afterwards, so concurrent failures all count
+func (r *UserRepository) RecordFailedLogin(userId uint) (failedAttempts, lockoutCount int, err error) {
+	if err := r.db.Model(&entities.User{}).
+		Where("id = ?", userId).
+		Update("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var user entities.User
+	if err := r.db.Select("failed_login_attempts", "lockout_count").First(&user, "id = ?", userId).Error; err != nil {
+		return 0, 0, err
+	}
+	return user.FailedLoginAttempts, user.LockoutCount, nil
+}
+
+// LockOut locks the user until lockedUntil and starts their failed login count again, provided
+// the counts are still those read by RecordFailedLogin, reporting whether it did. Of several
+// failures reaching the threshold together only one locks the account.
+func (r *UserRepository) LockOut(userId uint, failedAttempts, lockoutCount int, lockedUntil time.Time) (bool, error) {
+	result := r.db.Model(&entities.User{}).
+		Where("id = ? AND failed_login_attempts = ? AND lockout_count = ?", userId, failedAttempts, lockoutCount).
+		Updates(map[string]any{
+			"failed_login_attempts": 0,
+			"lockout_count":         lockoutCount + 1,
+			"locked_until":          lockedUntil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
 func (r *UserRepository) Delete(user *entities.User) error {
 	return r.db.Delete(user).Error
 }
@@ -65,10 +107,37 @@ func (r *UserRepository) GetByEmailDomain(emailDomain string) (*entities.User, e
 	return &user, nil
 }
 
+// GetByEmail finds the user with the email address across all tenants. Emails are only unique
+// within a tenant, so when users of several tenants share it ErrTenantRequired is returned
+// rather than one of them.
 func (r *UserRepository) GetByEmail(email string) (*entities.User, error) {
+	var users []entities.User
+	if err := r.db.Where("email = ?", email).Limit(2).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	switch len(users) {
+	case 0:
+		return nil, gorm.ErrRecordNotFound
+	case 1:
+		return &users[0], nil
+	default:
+		return nil, frameworkconstants.ErrTenantRequired
+	}
+}
+
+func (r *UserRepository) GetByEmailAndTenant(email string, tenantId uint) (*entities.User, error) {
 	var user entities.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.Where("email = ? AND tenant_id = ?", email, tenantId).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
+
+// CountByRole counts the tenant's users holding the role
+func (r *UserRepository) CountByRole(tenantId uint, role string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&entities.User{}).Where("tenant_id = ? AND role = ?", tenantId, role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const apiKeyDisplayPrefixLength = 12
+
+type APIKeyService struct {
+	apiKeyRepo *repositories.APIKeyRepository
+	tenantRepo *repositories.TenantRepository
+}
+
+func NewAPIKeyService(apiKeyRepo *repositories.APIKeyRepository, tenantRepo *repositories.TenantRepository) *APIKeyService {
+	return &APIKeyService{apiKeyRepo: apiKeyRepo, tenantRepo: tenantRepo}
+}
+
+// CreateAPIKey mints a new key for the tenant. The plaintext key is only ever returned here.
+func (s *APIKeyService) CreateAPIKey(tenantID, userID uint, createDTO frameworkdto.CreateAPIKeyDTO) (frameworkdto.CreatedAPIKeyDTO, error) {
+	scopes, err := normalizeScopes(createDTO.Scopes)
+	if err != nil {
+		return frameworkdto.CreatedAPIKeyDTO{}, err
+	}
+
+	if createDTO.ExpiresAt != nil && createDTO.ExpiresAt.Before(time.Now()) {
+		return frameworkdto.CreatedAPIKeyDTO{}, frameworkconstants.ErrInvalidAPIKeyExpiry
+	}
+
+	secret, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.CreatedAPIKeyDTO{}, err
+	}
+	key := frameworkconstants.APIKeyPrefix + secret
+
+	apiKey := &entities.APIKey{
+		TenantID:        tenantID,
+		CreatedByUserID: userID,
+		Name:            strings.TrimSpace(createDTO.Name),
+		KeyPrefix:       key[:apiKeyDisplayPrefixLength],
+		KeyHash:         frameworkutils.HashToken(key),
+		Scopes:          strings.Join(scopes, " "),
+		ExpiresAt:       createDTO.ExpiresAt,
+	}
+	if err := s.apiKeyRepo.Create(apiKey); err != nil {
+		return frameworkdto.CreatedAPIKeyDTO{}, err
+	}
+
+	return frameworkdto.CreatedAPIKeyDTO{APIKeyDTO: toAPIKeyDTO(apiKey), Key: key}, nil
+}
+
+func (s *APIKeyService) GetAllAPIKeys(tenantID uint) ([]frameworkdto.APIKeyDTO, error) {
+	apiKeys, err := s.apiKeyRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyDTOs := make([]frameworkdto.APIKeyDTO, 0, len(apiKeys))
+	for i := range apiKeys {
+		apiKeyDTOs = append(apiKeyDTOs, toAPIKeyDTO(&apiKeys[i]))
+	}
+
+	return apiKeyDTOs, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(tenantID, apiKeyID uint) error {
+	apiKey, err := s.apiKeyRepo.GetByID(apiKeyID, tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrAPIKeyNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	return s.apiKeyRepo.Update(apiKey)
+}
+
+// AuthenticateAPIKey resolves a presented key to a service principal scoped to the key's tenant
+func (s *APIKeyService) AuthenticateAPIKey(key string) (frameworkdto.TokenDTO, error) {
+	if !strings.HasPrefix(key, frameworkconstants.APIKeyPrefix) {
+		return frameworkdto.TokenDTO{}, frameworkconstants.ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyRepo.GetByKeyHash(frameworkutils.HashToken(key))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.TokenDTO{}, frameworkconstants.ErrInvalidAPIKey
+	} else if err != nil {
+		return frameworkdto.TokenDTO{}, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now)) {
+		return frameworkdto.TokenDTO{}, frameworkconstants.ErrInvalidAPIKey
+	}
+
+	tenant, err := s.tenantRepo.GetByID(apiKey.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.TokenDTO{}, frameworkconstants.ErrInvalidAPIKey
+	} else if err != nil {
+		return frameworkdto.TokenDTO{}, err
+	}
+	if !tenant.IsActive {
+		return frameworkdto.TokenDTO{}, frameworkconstants.ErrTenantSuspended
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > frameworkconstants.APIKeyLastUsedInterval {
+		apiKey.LastUsedAt = &now
+		if err := s.apiKeyRepo.Update(apiKey); err != nil {
+			return frameworkdto.TokenDTO{}, err
+		}
+	}
+
+	tokenDto := frameworkdto.TokenDTO{
+		Sub:           fmt.Sprintf("api_key:%d", apiKey.ID),
+		TenantID:      apiKey.TenantID,
+		FirstName:     apiKey.Name,
+		Iat:           apiKey.CreatedAt.Unix(),
+		PrincipalType: frameworkconstants.PrincipalTypeService,
+		Scopes:        strings.Fields(apiKey.Scopes),
+	}
+	if apiKey.ExpiresAt != nil {
+		tokenDto.Exp = apiKey.ExpiresAt.Unix()
+	}
+
+	return tokenDto, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || strings.ContainsAny(scope, " \t\r\n") {
+			return nil, frameworkconstants.ErrInvalidAPIKeyScopes
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, frameworkconstants.ErrInvalidAPIKeyScopes
+	}
+
+	return normalized, nil
+}
+
+func toAPIKeyDTO(apiKey *entities.APIKey) frameworkdto.APIKeyDTO {
+	return frameworkdto.APIKeyDTO{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		KeyPrefix:  apiKey.KeyPrefix,
+		Scopes:     strings.Fields(apiKey.Scopes),
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
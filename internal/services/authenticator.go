@@ -0,0 +1,34 @@
+package services
+
+import (
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+)
+
+// Authenticator checks the password of a user found by the email address they log in with.
+// It returns frameworkconstants.ErrInvalidPassword when the password is wrong and may update
+// the user, who is saved by the caller.
+type Authenticator interface {
+	Authenticate(user *entities.User, password string) error
+}
+
+// passwordAuthenticator checks passwords against the hash stored on the user
+type passwordAuthenticator struct {
+	passwordHasher frameworkutils.PasswordHasher
+}
+
+func (a *passwordAuthenticator) Authenticate(user *entities.User, password string) error {
+	if err := a.passwordHasher.Verify(password, user.PasswordHash); err != nil {
+		return err
+	}
+
+	// Upgrade hashes made with an older algorithm or work factor while the password is at hand.
+	// A failed re-hash keeps the old hash, which still verifies.
+	if a.passwordHasher.NeedsRehash(user.PasswordHash) {
+		if passwordHash, err := a.passwordHasher.Hash(password); err == nil {
+			user.PasswordHash = passwordHash
+		}
+	}
+
+	return nil
+}
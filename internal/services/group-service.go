@@ -0,0 +1,446 @@
+package services
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const maxGroupNameLength = 64
+
+// GroupService manages groups of users within a tenant. Members hold the roles of their groups
+// and of the groups containing them, so callers can only manage a group when they could assign
+// every one of those roles themselves. Access tokens name the member's groups, so members who
+// lose a group or one of its roles are signed out.
+type GroupService struct {
+	groupRepo              *repositories.GroupRepository
+	roleRepo               *repositories.RoleRepository
+	userRepo               *repositories.UserRepository
+	rbacService            *RBACService
+	tokenRevocationService *TokenRevocationService
+}
+
+func NewGroupService(
+	groupRepo *repositories.GroupRepository,
+	roleRepo *repositories.RoleRepository,
+	userRepo *repositories.UserRepository,
+	rbacService *RBACService,
+	tokenRevocationService *TokenRevocationService) *GroupService {
+	return &GroupService{
+		groupRepo:              groupRepo,
+		roleRepo:               roleRepo,
+		userRepo:               userRepo,
+		rbacService:            rbacService,
+		tokenRevocationService: tokenRevocationService,
+	}
+}
+
+func (s *GroupService) GetAllGroups(tenantID uint) ([]frameworkdto.GroupDTO, error) {
+	groups, err := s.groupRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupDTOs := make([]frameworkdto.GroupDTO, 0, len(groups))
+	for i := range groups {
+		groupDTOs = append(groupDTOs, toGroupDTO(&groups[i]))
+	}
+	return groupDTOs, nil
+}
+
+func (s *GroupService) GetGroup(tenantID, groupID uint) (frameworkdto.GroupDetailDTO, error) {
+	group, err := s.getGroup(tenantID, groupID)
+	if err != nil {
+		return frameworkdto.GroupDetailDTO{}, err
+	}
+
+	members := make([]frameworkdto.GroupMemberDTO, 0, len(group.Members))
+	for _, member := range group.Members {
+		members = append(members, frameworkdto.GroupMemberDTO{
+			UserID:    member.ID,
+			FirstName: member.FirstName,
+			LastName:  member.LastName,
+			Email:     member.Email,
+		})
+	}
+
+	return frameworkdto.GroupDetailDTO{GroupDTO: toGroupDTO(group), Members: members}, nil
+}
+
+// GetUserGroupIDs returns the groups the user belongs to directly or through a subgroup
+func (s *GroupService) GetUserGroupIDs(tenantID, userID uint) ([]uint, error) {
+	return userGroupIDs(s.groupRepo, tenantID, userID)
+}
+
+func (s *GroupService) CreateGroup(tokenDto frameworkdto.TokenDTO, createDTO frameworkdto.CreateGroupDTO) (frameworkdto.GroupDTO, error) {
+	name := strings.TrimSpace(createDTO.Name)
+	if name == "" || len(name) > maxGroupNameLength {
+		return frameworkdto.GroupDTO{}, frameworkconstants.ErrInvalidGroup
+	}
+	if err := s.checkNameAvailable(name, tokenDto.TenantID); err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+
+	groupsByID, err := s.getTenantGroups(tokenDto.TenantID)
+	if err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+	if createDTO.ParentID != nil && groupsByID[*createDTO.ParentID] == nil {
+		return frameworkdto.GroupDTO{}, frameworkconstants.ErrInvalidGroup
+	}
+
+	roles, err := s.resolveRoles(tokenDto.TenantID, createDTO.Roles)
+	if err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+	if err := s.checkCanAssign(tokenDto, append(roles, ancestorRoles(groupsByID, createDTO.ParentID)...)); err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+
+	group := &entities.Group{
+		TenantID:    tokenDto.TenantID,
+		Name:        name,
+		Description: strings.TrimSpace(createDTO.Description),
+		ParentID:    createDTO.ParentID,
+		Roles:       roles,
+	}
+	if err := s.groupRepo.Create(group); err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+	s.rbacService.Invalidate()
+
+	return toGroupDTO(group), nil
+}
+
+// UpdateGroup renames, moves or re-describes a group and replaces its roles. A group cannot be
+// moved inside itself or one of its subgroups. Moving a group or taking a role from it signs out
+// the members of the group and its subgroups.
+func (s *GroupService) UpdateGroup(tokenDto frameworkdto.TokenDTO, updateDTO frameworkdto.UpdateGroupDTO) (frameworkdto.GroupDTO, error) {
+	group, groupsByID, err := s.getManageableGroup(tokenDto, updateDTO.ID)
+	if err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+
+	name := strings.TrimSpace(updateDTO.Name)
+	if name == "" || len(name) > maxGroupNameLength {
+		return frameworkdto.GroupDTO{}, frameworkconstants.ErrInvalidGroup
+	}
+	if name != group.Name {
+		if err := s.checkNameAvailable(name, tokenDto.TenantID); err != nil {
+			return frameworkdto.GroupDTO{}, err
+		}
+	}
+
+	if updateDTO.ParentID != nil {
+		if groupsByID[*updateDTO.ParentID] == nil {
+			return frameworkdto.GroupDTO{}, frameworkconstants.ErrInvalidGroup
+		}
+		for _, ancestorID := range groupAncestry(groupsByID, *updateDTO.ParentID) {
+			if ancestorID == group.ID {
+				return frameworkdto.GroupDTO{}, frameworkconstants.ErrInvalidGroup
+			}
+		}
+	}
+
+	roles, err := s.resolveRoles(tokenDto.TenantID, updateDTO.Roles)
+	if err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+	if err := s.checkCanAssign(tokenDto, append(roles, ancestorRoles(groupsByID, updateDTO.ParentID)...)); err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+
+	narrowed := !sameParent(group.ParentID, updateDTO.ParentID) || removesRole(group.Roles, roles)
+
+	group.Name = name
+	group.Description = strings.TrimSpace(updateDTO.Description)
+	group.ParentID = updateDTO.ParentID
+	group.Roles = roles
+	if err := s.groupRepo.Update(group); err != nil {
+		return frameworkdto.GroupDTO{}, err
+	}
+	s.rbacService.Invalidate()
+
+	if narrowed {
+		if err := s.revokeMembers(groupsByID, group.ID); err != nil {
+			return frameworkdto.GroupDTO{}, err
+		}
+	}
+
+	return toGroupDTO(group), nil
+}
+
+// DeleteGroup removes a group without subgroups, ending its members' membership and signing them out
+func (s *GroupService) DeleteGroup(tokenDto frameworkdto.TokenDTO, groupID uint) error {
+	group, _, err := s.getManageableGroup(tokenDto, groupID)
+	if err != nil {
+		return err
+	}
+
+	children, err := s.groupRepo.CountChildren(group.ID)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return frameworkconstants.ErrGroupHasSubgroups
+	}
+
+	members := group.Members
+	if err := s.groupRepo.Delete(group); err != nil {
+		return err
+	}
+	s.rbacService.Invalidate()
+
+	for i := range members {
+		if err := s.tokenRevocationService.RevokeAllForUser(&members[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GroupService) AddMembers(tokenDto frameworkdto.TokenDTO, addDTO frameworkdto.AddGroupMembersDTO) error {
+	group, _, err := s.getManageableGroup(tokenDto, addDTO.GroupID)
+	if err != nil {
+		return err
+	}
+
+	users := make([]entities.User, 0, len(addDTO.UserIDs))
+	for _, userID := range addDTO.UserIDs {
+		user, err := s.userRepo.GetByID(userID, tokenDto.TenantID)
+		if err == gorm.ErrRecordNotFound {
+			return frameworkconstants.ErrUserNotFound
+		} else if err != nil {
+			return err
+		}
+		users = append(users, *user)
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	return s.groupRepo.AddMembers(group, users)
+}
+
+// RemoveMember takes the user out of the group and signs them out, since their tokens name it
+func (s *GroupService) RemoveMember(tokenDto frameworkdto.TokenDTO, groupID, userID uint) error {
+	group, _, err := s.getManageableGroup(tokenDto, groupID)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(userID, tokenDto.TenantID)
+	if err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := s.groupRepo.RemoveMember(group, user); err != nil {
+		return err
+	}
+	return s.tokenRevocationService.RevokeAllForUser(user)
+}
+
+// revokeMembers signs out the members of the group and of every group inside it
+func (s *GroupService) revokeMembers(groupsByID map[uint]*entities.Group, groupID uint) error {
+	var groupIDs []uint
+	for id := range groupsByID {
+		if slices.Contains(groupAncestry(groupsByID, id), groupID) {
+			groupIDs = append(groupIDs, id)
+		}
+	}
+
+	members, err := s.groupRepo.GetMembers(groupIDs)
+	if err != nil {
+		return err
+	}
+	for i := range members {
+		if err := s.tokenRevocationService.RevokeAllForUser(&members[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GroupService) getGroup(tenantID, groupID uint) (*entities.Group, error) {
+	group, err := s.groupRepo.GetByID(groupID, tenantID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, frameworkconstants.ErrGroupNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// getManageableGroup loads a group and checks the caller could assign every role its members hold
+func (s *GroupService) getManageableGroup(tokenDto frameworkdto.TokenDTO, groupID uint) (*entities.Group, map[uint]*entities.Group, error) {
+	group, err := s.getGroup(tokenDto.TenantID, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groupsByID, err := s.getTenantGroups(tokenDto.TenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkCanAssign(tokenDto, ancestorRoles(groupsByID, &group.ID)); err != nil {
+		return nil, nil, err
+	}
+
+	return group, groupsByID, nil
+}
+
+func (s *GroupService) getTenantGroups(tenantID uint) (map[uint]*entities.Group, error) {
+	groups, err := s.groupRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupsByID := make(map[uint]*entities.Group, len(groups))
+	for i := range groups {
+		groupsByID[groups[i].ID] = &groups[i]
+	}
+	return groupsByID, nil
+}
+
+func (s *GroupService) checkNameAvailable(name string, tenantID uint) error {
+	_, err := s.groupRepo.GetByName(name, tenantID)
+	if err == nil {
+		return frameworkconstants.ErrGroupAlreadyExists
+	} else if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return nil
+}
+
+func (s *GroupService) resolveRoles(tenantID uint, names []string) ([]entities.Role, error) {
+	roles := make([]entities.Role, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		role, err := s.roleRepo.GetByName(name, tenantID)
+		if err == gorm.ErrRecordNotFound {
+			return nil, frameworkconstants.ErrRoleNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+func (s *GroupService) checkCanAssign(tokenDto frameworkdto.TokenDTO, roles []entities.Role) error {
+	for _, role := range roles {
+		canAssign, err := s.rbacService.CanAssignRole(tokenDto, role.Name)
+		if err != nil {
+			return err
+		}
+		if !canAssign {
+			return frameworkconstants.ErrPermissionNotHeld
+		}
+	}
+	return nil
+}
+
+func sameParent(a, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// removesRole reports whether a role in before is missing from after
+func removesRole(before, after []entities.Role) bool {
+	for _, role := range before {
+		if !slices.ContainsFunc(after, func(r entities.Role) bool { return r.ID == role.ID }) {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestorRoles returns the roles of the group and every group containing it
+func ancestorRoles(groupsByID map[uint]*entities.Group, groupID *uint) []entities.Role {
+	if groupID == nil {
+		return nil
+	}
+
+	var roles []entities.Role
+	for _, ancestorID := range groupAncestry(groupsByID, *groupID) {
+		roles = append(roles, groupsByID[ancestorID].Roles...)
+	}
+	return roles
+}
+
+// groupAncestry returns the group followed by the groups containing it, innermost first
+func groupAncestry(groupsByID map[uint]*entities.Group, groupID uint) []uint {
+	var ancestry []uint
+	visited := make(map[uint]bool)
+	for group := groupsByID[groupID]; group != nil && !visited[group.ID]; {
+		visited[group.ID] = true
+		ancestry = append(ancestry, group.ID)
+		if group.ParentID == nil {
+			break
+		}
+		group = groupsByID[*group.ParentID]
+	}
+	return ancestry
+}
+
+// userGroupIDs returns the tenant's groups the user belongs to directly or through a subgroup,
+// in ascending order
+func userGroupIDs(groupRepo *repositories.GroupRepository, tenantID, userID uint) ([]uint, error) {
+	directGroupIDs, err := groupRepo.GetGroupIDsByUserID(userID)
+	if err != nil || len(directGroupIDs) == 0 {
+		return nil, err
+	}
+
+	groups, err := groupRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	groupsByID := make(map[uint]*entities.Group, len(groups))
+	for i := range groups {
+		groupsByID[groups[i].ID] = &groups[i]
+	}
+
+	seen := make(map[uint]bool)
+	var groupIDs []uint
+	for _, directGroupID := range directGroupIDs {
+		for _, groupID := range groupAncestry(groupsByID, directGroupID) {
+			if !seen[groupID] {
+				seen[groupID] = true
+				groupIDs = append(groupIDs, groupID)
+			}
+		}
+	}
+	sort.Slice(groupIDs, func(i, j int) bool { return groupIDs[i] < groupIDs[j] })
+	return groupIDs, nil
+}
+
+func toGroupDTO(group *entities.Group) frameworkdto.GroupDTO {
+	roles := make([]string, 0, len(group.Roles))
+	for _, role := range group.Roles {
+		roles = append(roles, role.Name)
+	}
+	sort.Strings(roles)
+
+	return frameworkdto.GroupDTO{
+		ID:          group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		ParentID:    group.ParentID,
+		Roles:       roles,
+		CreatedAt:   group.CreatedAt,
+		UpdatedAt:   group.UpdatedAt,
+	}
+}
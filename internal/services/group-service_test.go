@@ -0,0 +1,125 @@
+package services
+
+import (
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+)
+
+func TestGroupChangesSignOutAffectedMembers(t *testing.T) {
+	tenantUser := string(frameworkconstants.UserRoleTenantUser)
+	tenantAdmin := string(frameworkconstants.UserRoleTenantAdmin)
+
+	tests := []struct {
+		name string
+		// change changes the groups, in which the member belongs to team inside staff
+		change      func(s *testServices, admin frameworkdto.TokenDTO, staff, team frameworkdto.GroupDTO, memberID uint) error
+		wantRevoked bool
+	}{
+		{
+			name: "member removed",
+			change: func(s *testServices, admin frameworkdto.TokenDTO, staff, team frameworkdto.GroupDTO, memberID uint) error {
+				return s.groupService.RemoveMember(admin, team.ID, memberID)
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "group moved",
+			change: func(s *testServices, admin frameworkdto.TokenDTO, staff, team frameworkdto.GroupDTO, memberID uint) error {
+				_, err := s.groupService.UpdateGroup(admin, frameworkdto.UpdateGroupDTO{ID: team.ID, CreateGroupDTO: frameworkdto.CreateGroupDTO{Name: team.Name}})
+				return err
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "role taken from the containing group",
+			change: func(s *testServices, admin frameworkdto.TokenDTO, staff, team frameworkdto.GroupDTO, memberID uint) error {
+				_, err := s.groupService.UpdateGroup(admin, frameworkdto.UpdateGroupDTO{ID: staff.ID, CreateGroupDTO: frameworkdto.CreateGroupDTO{Name: staff.Name}})
+				return err
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "group deleted",
+			change: func(s *testServices, admin frameworkdto.TokenDTO, staff, team frameworkdto.GroupDTO, memberID uint) error {
+				return s.groupService.DeleteGroup(admin, team.ID)
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "role added",
+			change: func(s *testServices, admin frameworkdto.TokenDTO, staff, team frameworkdto.GroupDTO, memberID uint) error {
+				_, err := s.groupService.UpdateGroup(admin, frameworkdto.UpdateGroupDTO{ID: staff.ID, CreateGroupDTO: frameworkdto.CreateGroupDTO{
+					Name:  staff.Name,
+					Roles: []string{tenantUser, tenantAdmin},
+				}})
+				return err
+			},
+		},
+		{
+			name: "group renamed",
+			change: func(s *testServices, admin frameworkdto.TokenDTO, staff, team frameworkdto.GroupDTO, memberID uint) error {
+				_, err := s.groupService.UpdateGroup(admin, frameworkdto.UpdateGroupDTO{ID: team.ID, CreateGroupDTO: frameworkdto.CreateGroupDTO{
+					Name:     "Engineering",
+					ParentID: team.ParentID,
+				}})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			admin := s.registerTenant(t, "acme.com", 5)
+			adminToken := tokenDTO(admin)
+
+			if err := s.registrationService.RegisterUser(admin.TenantID, frameworkdto.UserRegistrationDTO{
+				FirstName: "Mia",
+				LastName:  "Member",
+				Email:     "member@acme.com",
+				Password:  testPassword,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			member, err := s.userRepo.GetByEmailAndTenant("member@acme.com", admin.TenantID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			staff, err := s.groupService.CreateGroup(adminToken, frameworkdto.CreateGroupDTO{Name: "Staff", Roles: []string{tenantUser}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			team, err := s.groupService.CreateGroup(adminToken, frameworkdto.CreateGroupDTO{Name: "Team", ParentID: &staff.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.groupService.AddMembers(adminToken, frameworkdto.AddGroupMembersDTO{GroupID: team.ID, UserIDs: []uint{member.ID}}); err != nil {
+				t.Fatal(err)
+			}
+
+			tokens, err := s.tokenService.IssueTokens(member, []string{frameworkconstants.AuthMethodPassword}, "127.0.0.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			memberToken, err := s.keySet.ParseJWT(string(tokens.Token))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.change(s, adminToken, staff, team, member.ID); err != nil {
+				t.Fatal(err)
+			}
+
+			revoked, err := s.tokenRevocationService.IsRevoked(memberToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.wantRevoked {
+				t.Errorf("member's token revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
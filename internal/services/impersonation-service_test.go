@@ -0,0 +1,99 @@
+package services
+
+import (
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+)
+
+func TestImpersonationTokenLivesWithAdminSession(t *testing.T) {
+	tests := []struct {
+		name string
+		// end ends the super admin's session, or leaves it running
+		end         func(t *testing.T, s *testServices, admin *entities.User, adminToken frameworkdto.TokenDTO)
+		wantRevoked bool
+	}{
+		{
+			name: "admin still signed in",
+			end:  func(t *testing.T, s *testServices, admin *entities.User, adminToken frameworkdto.TokenDTO) {},
+		},
+		{
+			name: "admin signed out",
+			end: func(t *testing.T, s *testServices, admin *entities.User, adminToken frameworkdto.TokenDTO) {
+				if err := s.tokenRevocationService.RevokeSession(adminToken.Sid); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "admin's tokens all revoked",
+			end: func(t *testing.T, s *testServices, admin *entities.User, adminToken frameworkdto.TokenDTO) {
+				if err := s.tokenRevocationService.RevokeAllForUser(admin); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			admin := s.registerTenant(t, "acme.com", 5)
+			target := s.registerTenant(t, "globex.com", 5)
+			impersonationService := NewImpersonationService(s.cfg, s.keySet, s.userRepo, repositories.NewImpersonationRepository(s.db), s.tokenRevocationService, s.tokenService)
+
+			tokens, err := s.tokenService.IssueTokens(admin, []string{frameworkconstants.AuthMethodPassword}, "127.0.0.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			adminToken, err := s.keySet.ParseJWT(string(tokens.Token))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			impersonation, err := impersonationService.StartImpersonation(adminToken, admin.ID, frameworkdto.StartImpersonationDTO{
+				TenantID: target.TenantID,
+				UserID:   target.ID,
+				Reason:   "support ticket",
+			}, "127.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			impersonationToken, err := s.keySet.ParseJWT(string(impersonation.Token))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.end(t, s, admin, adminToken)
+
+			revoked, err := s.tokenRevocationService.IsRevoked(impersonationToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.wantRevoked {
+				t.Errorf("IsRevoked() = %v, want %v", revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestImpersonationNeedsAdminSession(t *testing.T) {
+	s := newTestServices(t)
+	admin := s.registerTenant(t, "acme.com", 5)
+	target := s.registerTenant(t, "globex.com", 5)
+	impersonationService := NewImpersonationService(s.cfg, s.keySet, s.userRepo, repositories.NewImpersonationRepository(s.db), s.tokenRevocationService, s.tokenService)
+
+	// Without a session nothing would revoke the impersonation token before it expires
+	_, err := impersonationService.StartImpersonation(tokenDTO(admin), admin.ID, frameworkdto.StartImpersonationDTO{
+		TenantID: target.TenantID,
+		UserID:   target.ID,
+		Reason:   "support ticket",
+	}, "127.0.0.1")
+	if err != frameworkconstants.ErrCannotImpersonate {
+		t.Errorf("StartImpersonation() error = %v, want %v", err, frameworkconstants.ErrCannotImpersonate)
+	}
+}
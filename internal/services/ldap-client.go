@@ -0,0 +1,83 @@
+package services
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const ldapTimeout = 10 * time.Second
+
+// dialLDAP connects to an ldap:// or ldaps:// URL, upgrading ldap:// connections with StartTLS
+// when startTLS is set. dialer, when set, opens the underlying connection, so tests can point
+// the framework at an in-process directory.
+func dialLDAP(rawURL string, startTLS bool, dialer frameworkdto.LDAPDialer) (*ldap.Conn, error) {
+	ldapURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := ldapURL.Hostname()
+	port := ldapURL.Port()
+	switch ldapURL.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ldap url scheme %q", ldapURL.Scheme)
+	}
+
+	if dialer == nil {
+		dialer = func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, ldapTimeout)
+		}
+	}
+	conn, err := dialer("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	// The deadline bounds the whole login, however slowly the directory answers
+	if err := conn.SetDeadline(time.Now().Add(ldapTimeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+	isTLS := ldapURL.Scheme == "ldaps"
+	if isTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	l := ldap.NewConn(conn, isTLS)
+	l.SetTimeout(ldapTimeout)
+	l.Start()
+
+	if startTLS && !isTLS {
+		if err := l.StartTLS(tlsConfig); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// ldapFilter puts parentheses around a filter written without them, such as mail={email}
+func ldapFilter(filter string) string {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		return "(" + filter + ")"
+	}
+	return filter
+}
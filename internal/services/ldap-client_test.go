@@ -0,0 +1,351 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const stubServiceDN = "cn=service,dc=acme,dc=com"
+
+type stubDirectoryEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// stubDirectory is an in-process LDAP server answering binds and equality searches over a
+// net.Pipe, reached through the framework's LDAPDialer hook
+type stubDirectory struct {
+	t       *testing.T
+	entries []stubDirectoryEntry
+	// filters records the filter of each search the directory answered
+	filters []*ber.Packet
+}
+
+func (d *stubDirectory) dial(network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	go d.serve(server)
+	return client, nil
+}
+
+func (d *stubDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		message, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(message.Children) < 2 {
+			d.t.Errorf("stub directory received a malformed message")
+			return
+		}
+		messageID, _ := message.Children[0].Value.(int64)
+		op := message.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			d.write(conn, messageID, d.bind(op))
+		case ldap.ApplicationSearchRequest:
+			for _, response := range d.search(op) {
+				d.write(conn, messageID, response)
+			}
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			d.t.Errorf("stub directory received unexpected operation %d", op.Tag)
+			return
+		}
+	}
+}
+
+func (d *stubDirectory) write(conn net.Conn, messageID int64, op *ber.Packet) {
+	message := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, ""))
+	message.AppendChild(op)
+	conn.Write(message.Bytes())
+}
+
+func (d *stubDirectory) bind(op *ber.Packet) *ber.Packet {
+	dn, password := op.Children[1].Data.String(), op.Children[2].Data.String()
+
+	code := ldap.LDAPResultInvalidCredentials
+	for _, entry := range d.entries {
+		if entry.dn == dn && entry.password == password {
+			code = ldap.LDAPResultSuccess
+		}
+	}
+	return stubLDAPResult(ldap.ApplicationBindResponse, code)
+}
+
+// search answers equality filters only, which is all the default user filter compiles to
+func (d *stubDirectory) search(op *ber.Packet) []*ber.Packet {
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	d.filters = append(d.filters, filter)
+
+	reference := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultReference, nil, "")
+	reference.AppendChild(stubOctetString("ldap://other.acme.com/dc=acme,dc=com"))
+	responses := []*ber.Packet{reference}
+	if filter.Tag != ldap.FilterEqualityMatch {
+		return append(responses, stubLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+	}
+	attribute, value := filter.Children[0].Data.String(), filter.Children[1].Data.String()
+
+	var matched int64
+	for _, entry := range d.entries {
+		if !containsFold(entry.attributes[attribute], value) {
+			continue
+		}
+		if matched == sizeLimit {
+			return append(responses, stubLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded))
+		}
+		matched++
+
+		attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		for name, values := range entry.attributes {
+			encodedValues := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+			for _, value := range values {
+				encodedValues.AppendChild(stubOctetString(value))
+			}
+			encodedAttribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+			encodedAttribute.AppendChild(stubOctetString(name))
+			encodedAttribute.AppendChild(encodedValues)
+			attributes.AppendChild(encodedAttribute)
+		}
+		response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+		response.AppendChild(stubOctetString(entry.dn))
+		response.AppendChild(attributes)
+		responses = append(responses, response)
+	}
+	return append(responses, stubLDAPResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+func stubLDAPResult(tag ber.Tag, code int) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	result.AppendChild(stubOctetString(""))
+	result.AppendChild(stubOctetString(""))
+	return result
+}
+
+func stubOctetString(value string) *ber.Packet {
+	return ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "")
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func newStubDirectory(t *testing.T) *stubDirectory {
+	return &stubDirectory{t: t, entries: []stubDirectoryEntry{
+		{dn: stubServiceDN, password: "service-secret"},
+		{
+			dn:       "uid=ada,ou=people,dc=acme,dc=com",
+			password: "directory-password",
+			attributes: map[string][]string{
+				"mail":      {"ada@acme.com"},
+				"givenName": {"Ada"},
+				"sn":        {"Lovelace"},
+				"memberOf":  {"cn=admins,ou=groups,dc=acme,dc=com", "cn=staff,ou=groups,dc=acme,dc=com"},
+			},
+		},
+		{dn: "uid=bob,ou=people,dc=acme,dc=com", password: "bob-password", attributes: map[string][]string{"mail": {"shared@acme.com"}}},
+		{dn: "uid=bea,ou=people,dc=acme,dc=com", password: "bea-password", attributes: map[string][]string{"mail": {"shared@acme.com"}}},
+		{dn: "uid=cal,ou=people,dc=acme,dc=com", password: "cal-password", attributes: map[string][]string{"mail": {"crowded@acme.com"}}},
+		{dn: "uid=cat,ou=people,dc=acme,dc=com", password: "cat-password", attributes: map[string][]string{"mail": {"crowded@acme.com"}}},
+		{dn: "uid=cid,ou=people,dc=acme,dc=com", password: "cid-password", attributes: map[string][]string{"mail": {"crowded@acme.com"}}},
+	}}
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	tests := []struct {
+		name         string
+		email        string
+		password     string
+		bindPassword string
+		wantErr      error
+	}{
+		{name: "bind succeeds", email: "ada@acme.com", password: "directory-password"},
+		{name: "wrong password", email: "ada@acme.com", password: "guess", wantErr: frameworkconstants.ErrInvalidPassword},
+		{name: "empty password", email: "ada@acme.com", password: "", wantErr: frameworkconstants.ErrInvalidPassword},
+		{name: "unknown user", email: "nobody@acme.com", password: "directory-password", wantErr: frameworkconstants.ErrInvalidPassword},
+		{name: "ambiguous filter", email: "shared@acme.com", password: "bob-password", wantErr: frameworkconstants.ErrInvalidPassword},
+		{name: "ambiguous filter over the size limit", email: "crowded@acme.com", password: "cal-password", wantErr: frameworkconstants.ErrInvalidPassword},
+		{name: "service account rejected", email: "ada@acme.com", password: "directory-password", bindPassword: "wrong", wantErr: frameworkconstants.ErrLDAPUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := newStubDirectory(t)
+			bindPassword := "service-secret"
+			if tt.bindPassword != "" {
+				bindPassword = tt.bindPassword
+			}
+			authenticator := &ldapAuthenticator{
+				directory: &entities.TenantLDAPDirectory{
+					URL:          "ldap://ldap.acme.com",
+					BindDN:       stubServiceDN,
+					BindPassword: bindPassword,
+					BaseDN:       "dc=acme,dc=com",
+					GroupRoleMappings: []entities.LDAPGroupRoleMapping{
+						{Group: "cn=admins,ou=groups,dc=acme,dc=com", Role: string(frameworkconstants.UserRoleTenantAdmin)},
+					},
+				},
+				dialer: directory.dial,
+			}
+
+			user := &entities.User{Email: tt.email, Role: string(frameworkconstants.UserRoleTenantUser)}
+			err := authenticator.Authenticate(user, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if user.FirstName != "Ada" || user.LastName != "Lovelace" {
+				t.Errorf("names = %q %q, want Ada Lovelace", user.FirstName, user.LastName)
+			}
+			if user.Role != string(frameworkconstants.UserRoleTenantAdmin) {
+				t.Errorf("role = %q, want the role mapped from memberOf", user.Role)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateEscapesEmail(t *testing.T) {
+	directory := newStubDirectory(t)
+	authenticator := &ldapAuthenticator{
+		directory: &entities.TenantLDAPDirectory{URL: "ldap://ldap.acme.com", BaseDN: "dc=acme,dc=com"},
+		dialer:    directory.dial,
+	}
+
+	// Unescaped, this email would turn the default filter into a presence match on every entry
+	err := authenticator.Authenticate(&entities.User{Email: "*)(uid=*"}, "directory-password")
+	if err != frameworkconstants.ErrInvalidPassword {
+		t.Fatalf("Authenticate() error = %v, want %v", err, frameworkconstants.ErrInvalidPassword)
+	}
+	if len(directory.filters) != 1 || directory.filters[0].Tag != ldap.FilterEqualityMatch {
+		t.Fatalf("search filters = %v, want one equality match", directory.filters)
+	}
+	if value := directory.filters[0].Children[1].Data.String(); value != "*)(uid=*" {
+		t.Errorf("filter value = %q, want the email address", value)
+	}
+}
+
+func TestUpdateLDAPDirectory(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		startTLS      bool
+		userFilter    string
+		allowInsecure bool
+		wantErr       error
+	}{
+		{name: "ldaps", url: "ldaps://ldap.acme.com"},
+		{name: "ldap with StartTLS", url: "ldap://ldap.acme.com", startTLS: true},
+		{name: "ldap without StartTLS", url: "ldap://ldap.acme.com", wantErr: frameworkconstants.ErrInsecureLDAPDirectory},
+		{name: "ldap without StartTLS allowed", url: "ldap://ldap.acme.com", allowInsecure: true},
+		{name: "ldaps with StartTLS", url: "ldaps://ldap.acme.com", startTLS: true, wantErr: frameworkconstants.ErrInvalidLDAPDirectory},
+		{name: "other scheme", url: "https://ldap.acme.com", wantErr: frameworkconstants.ErrInvalidLDAPDirectory},
+		{name: "compound filter", url: "ldaps://ldap.acme.com", userFilter: "(&(objectClass=person)(|(mail={email})(uid={email})))"},
+		{name: "filter without parentheses", url: "ldaps://ldap.acme.com", userFilter: "mail={email}"},
+		{name: "filter without the email", url: "ldaps://ldap.acme.com", userFilter: "(uid=ada)", wantErr: frameworkconstants.ErrInvalidLDAPDirectory},
+		{name: "unclosed filter", url: "ldaps://ldap.acme.com", userFilter: "(mail={email}", wantErr: frameworkconstants.ErrInvalidLDAPDirectory},
+		{name: "filter with trailing text", url: "ldaps://ldap.acme.com", userFilter: "(mail={email}))", wantErr: frameworkconstants.ErrInvalidLDAPDirectory},
+		{name: "filter with a bad escape", url: "ldaps://ldap.acme.com", userFilter: `(mail=\zz{email})`, wantErr: frameworkconstants.ErrInvalidLDAPDirectory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			admin := s.registerTenant(t, "acme.com", 5)
+			cfg := *s.cfg
+			cfg.AllowInsecureLDAP = tt.allowInsecure
+			ldapService := NewLDAPService(&cfg, repositories.NewLDAPDirectoryRepository(s.db))
+
+			err := ldapService.UpdateDirectory(admin.TenantID, frameworkdto.UpdateLDAPDirectoryDTO{LDAPDirectoryDTO: frameworkdto.LDAPDirectoryDTO{
+				URL:        tt.url,
+				StartTLS:   tt.startTLS,
+				BaseDN:     "dc=acme,dc=com",
+				UserFilter: tt.userFilter,
+				IsEnabled:  true,
+			}})
+			if err != tt.wantErr {
+				t.Fatalf("UpdateDirectory() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			authenticator, err := ldapService.Authenticator(admin.TenantID)
+			if err != nil || authenticator == nil {
+				t.Fatalf("Authenticator() = %v, %v, want the directory", authenticator, err)
+			}
+
+			// A directory saved while insecure directories were allowed is not used once they are not
+			cfg.AllowInsecureLDAP = false
+			_, err = ldapService.Authenticator(admin.TenantID)
+			if wantUnavailable := tt.allowInsecure; errors.Is(err, frameworkconstants.ErrLDAPUnavailable) != wantUnavailable {
+				t.Errorf("Authenticator() without AllowInsecureLDAP error = %v, want unavailable %v", err, wantUnavailable)
+			}
+		})
+	}
+}
+
+func TestChangePasswordChecksDirectoryPassword(t *testing.T) {
+	s := newTestServices(t)
+	admin := s.registerTenant(t, "acme.com", 5)
+
+	directory := newStubDirectory(t)
+	directory.entries = append(directory.entries, stubDirectoryEntry{
+		dn:         "uid=admin,ou=people,dc=acme,dc=com",
+		password:   "directory-password",
+		attributes: map[string][]string{"mail": {admin.Email}},
+	})
+	s.cfg.LDAPDialer = directory.dial
+	s.cfg.AllowInsecureLDAP = true
+	ldapService := NewLDAPService(s.cfg, repositories.NewLDAPDirectoryRepository(s.db))
+	if err := ldapService.UpdateDirectory(admin.TenantID, frameworkdto.UpdateLDAPDirectoryDTO{LDAPDirectoryDTO: frameworkdto.LDAPDirectoryDTO{
+		URL:       "ldap://ldap.acme.com",
+		BaseDN:    "dc=acme,dc=com",
+		IsEnabled: true,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The framework password is not the one the tenant's users log in with
+	err := s.passwordService.ChangePassword(admin.TenantID, admin.ID, "", frameworkdto.ChangePasswordDTO{
+		CurrentPassword: testPassword,
+		NewPassword:     "Another-Tricky-Horse",
+	})
+	if err != frameworkconstants.ErrInvalidPassword {
+		t.Fatalf("ChangePassword() with the framework password error = %v, want %v", err, frameworkconstants.ErrInvalidPassword)
+	}
+	if user, _ := s.userRepo.GetByID(admin.ID, admin.TenantID); user.FailedLoginAttempts != 1 {
+		t.Errorf("FailedLoginAttempts = %d, want the wrong password counted", user.FailedLoginAttempts)
+	}
+
+	err = s.passwordService.ChangePassword(admin.TenantID, admin.ID, "", frameworkdto.ChangePasswordDTO{
+		CurrentPassword: "directory-password",
+		NewPassword:     "Another-Tricky-Horse",
+	})
+	if err != nil {
+		t.Fatalf("ChangePassword() with the directory password error = %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLDAPUserFilter         = "(mail={email})"
+	defaultLDAPFirstNameAttribute = "givenName"
+	defaultLDAPLastNameAttribute  = "sn"
+	defaultLDAPGroupAttribute     = "memberOf"
+	ldapEmailPlaceholder          = "{email}"
+)
+
+type LDAPService struct {
+	cfg               *frameworkdto.FrameworkConfig
+	ldapDirectoryRepo *repositories.LDAPDirectoryRepository
+}
+
+func NewLDAPService(cfg *frameworkdto.FrameworkConfig, ldapDirectoryRepo *repositories.LDAPDirectoryRepository) *LDAPService {
+	return &LDAPService{cfg: cfg, ldapDirectoryRepo: ldapDirectoryRepo}
+}
+
+func (s *LDAPService) GetDirectory(tenantID uint) (frameworkdto.LDAPDirectoryDTO, error) {
+	directory, err := s.ldapDirectoryRepo.GetByTenantID(tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LDAPDirectoryDTO{}, frameworkconstants.ErrLDAPDirectoryNotFound
+	} else if err != nil {
+		return frameworkdto.LDAPDirectoryDTO{}, err
+	}
+
+	mappings := make([]frameworkdto.LDAPGroupRoleMappingDTO, len(directory.GroupRoleMappings))
+	for i, mapping := range directory.GroupRoleMappings {
+		mappings[i] = frameworkdto.LDAPGroupRoleMappingDTO{Group: mapping.Group, Role: mapping.Role}
+	}
+
+	return frameworkdto.LDAPDirectoryDTO{
+		URL:                directory.URL,
+		StartTLS:           directory.StartTLS,
+		BindDN:             directory.BindDN,
+		BaseDN:             directory.BaseDN,
+		UserFilter:         directory.UserFilter,
+		FirstNameAttribute: directory.FirstNameAttribute,
+		LastNameAttribute:  directory.LastNameAttribute,
+		GroupAttribute:     directory.GroupAttribute,
+		GroupRoleMappings:  mappings,
+		IsEnabled:          directory.IsEnabled,
+	}, nil
+}
+
+func (s *LDAPService) UpdateDirectory(tenantID uint, directoryDTO frameworkdto.UpdateLDAPDirectoryDTO) error {
+	if err := validateLDAPDirectory(directoryDTO.LDAPDirectoryDTO); err != nil {
+		return err
+	}
+	if !s.cfg.AllowInsecureLDAP && isInsecureLDAP(directoryDTO.URL, directoryDTO.StartTLS) {
+		return frameworkconstants.ErrInsecureLDAPDirectory
+	}
+
+	directory, err := s.ldapDirectoryRepo.GetByTenantID(tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		directory = &entities.TenantLDAPDirectory{TenantID: tenantID}
+	} else if err != nil {
+		return err
+	}
+
+	mappings := make([]entities.LDAPGroupRoleMapping, len(directoryDTO.GroupRoleMappings))
+	for i, mapping := range directoryDTO.GroupRoleMappings {
+		mappings[i] = entities.LDAPGroupRoleMapping{Group: mapping.Group, Role: mapping.Role}
+	}
+
+	directory.URL = directoryDTO.URL
+	directory.StartTLS = directoryDTO.StartTLS
+	directory.BindDN = directoryDTO.BindDN
+	directory.BaseDN = directoryDTO.BaseDN
+	directory.UserFilter = directoryDTO.UserFilter
+	directory.FirstNameAttribute = directoryDTO.FirstNameAttribute
+	directory.LastNameAttribute = directoryDTO.LastNameAttribute
+	directory.GroupAttribute = directoryDTO.GroupAttribute
+	directory.GroupRoleMappings = mappings
+	directory.IsEnabled = directoryDTO.IsEnabled
+	if directoryDTO.BindPassword != "" {
+		directory.BindPassword = directoryDTO.BindPassword
+	}
+	if directory.BindDN == "" {
+		directory.BindPassword = ""
+	}
+
+	return s.ldapDirectoryRepo.Save(directory)
+}
+
+// DeleteDirectory removes the tenant's directory; its users log in with their framework
+// password again
+func (s *LDAPService) DeleteDirectory(tenantID uint) error {
+	return s.ldapDirectoryRepo.DeleteByTenantID(tenantID)
+}
+
+// Authenticator returns the authenticator for the tenant's enabled directory, or nil when the
+// tenant's users log in with their framework password. A directory saved while
+// AllowInsecureLDAP was set is unavailable once it is cleared.
+func (s *LDAPService) Authenticator(tenantID uint) (Authenticator, error) {
+	directory, err := s.ldapDirectoryRepo.GetByTenantID(tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if !directory.IsEnabled {
+		return nil, nil
+	}
+	if !s.cfg.AllowInsecureLDAP && isInsecureLDAP(directory.URL, directory.StartTLS) {
+		return nil, fmt.Errorf("%w: %v", frameworkconstants.ErrLDAPUnavailable, frameworkconstants.ErrInsecureLDAPDirectory)
+	}
+
+	return &ldapAuthenticator{directory: directory, dialer: s.cfg.LDAPDialer}, nil
+}
+
+func validateLDAPDirectory(directoryDTO frameworkdto.LDAPDirectoryDTO) error {
+	ldapURL, err := url.Parse(directoryDTO.URL)
+	if err != nil || (ldapURL.Scheme != "ldap" && ldapURL.Scheme != "ldaps") || ldapURL.Hostname() == "" {
+		return frameworkconstants.ErrInvalidLDAPDirectory
+	}
+	if ldapURL.Scheme == "ldaps" && directoryDTO.StartTLS {
+		return frameworkconstants.ErrInvalidLDAPDirectory
+	}
+	if strings.TrimSpace(directoryDTO.BaseDN) == "" {
+		return frameworkconstants.ErrInvalidLDAPDirectory
+	}
+
+	if directoryDTO.UserFilter != "" {
+		if !strings.Contains(directoryDTO.UserFilter, ldapEmailPlaceholder) {
+			return frameworkconstants.ErrInvalidLDAPDirectory
+		}
+		if _, err := ldap.CompileFilter(ldapFilter(strings.ReplaceAll(directoryDTO.UserFilter, ldapEmailPlaceholder, "user@example.com"))); err != nil {
+			return frameworkconstants.ErrInvalidLDAPDirectory
+		}
+	}
+
+	for _, mapping := range directoryDTO.GroupRoleMappings {
+		if strings.TrimSpace(mapping.Group) == "" {
+			return frameworkconstants.ErrInvalidLDAPDirectory
+		}
+		if mapping.Role != string(frameworkconstants.UserRoleTenantAdmin) && mapping.Role != string(frameworkconstants.UserRoleTenantUser) {
+			return frameworkconstants.ErrInvalidLDAPDirectory
+		}
+	}
+
+	return nil
+}
+
+// isInsecureLDAP reports whether passwords sent to the directory would cross the network unencrypted
+func isInsecureLDAP(rawURL string, startTLS bool) bool {
+	ldapURL, err := url.Parse(rawURL)
+	return err != nil || (ldapURL.Scheme != "ldaps" && !startTLS)
+}
+
+// ldapAuthenticator finds the user's directory entry with the service account, binds as that
+// entry with the password, and copies the user's names and mapped role into the framework
+type ldapAuthenticator struct {
+	directory *entities.TenantLDAPDirectory
+	dialer    frameworkdto.LDAPDialer
+}
+
+func (a *ldapAuthenticator) Authenticate(user *entities.User, password string) error {
+	// Most directories treat a bind with an empty password as an anonymous bind that succeeds
+	if password == "" {
+		return frameworkconstants.ErrInvalidPassword
+	}
+
+	conn, err := dialLDAP(a.directory.URL, a.directory.StartTLS, a.dialer)
+	if err != nil {
+		return fmt.Errorf("%w: %v", frameworkconstants.ErrLDAPUnavailable, err)
+	}
+	defer conn.Close()
+
+	if a.directory.BindDN != "" {
+		if err := conn.Bind(a.directory.BindDN, a.directory.BindPassword); err != nil {
+			return fmt.Errorf("%w: service account bind: %v", frameworkconstants.ErrLDAPUnavailable, err)
+		}
+	}
+
+	filter := a.directory.UserFilter
+	if filter == "" {
+		filter = defaultLDAPUserFilter
+	}
+	filter = ldapFilter(strings.ReplaceAll(filter, ldapEmailPlaceholder, ldap.EscapeFilter(user.Email)))
+
+	firstNameAttribute := valueOrDefault(a.directory.FirstNameAttribute, defaultLDAPFirstNameAttribute)
+	lastNameAttribute := valueOrDefault(a.directory.LastNameAttribute, defaultLDAPLastNameAttribute)
+	groupAttribute := valueOrDefault(a.directory.GroupAttribute, defaultLDAPGroupAttribute)
+
+	// A size limit of 2 is enough to tell a unique match from an ambiguous filter
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.directory.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		filter, []string{firstNameAttribute, lastNameAttribute, groupAttribute}, nil))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return frameworkconstants.ErrInvalidPassword
+	} else if err != nil {
+		return fmt.Errorf("%w: %v", frameworkconstants.ErrLDAPUnavailable, err)
+	}
+	if len(result.Entries) != 1 {
+		return frameworkconstants.ErrInvalidPassword
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return frameworkconstants.ErrInvalidPassword
+		}
+		return fmt.Errorf("%w: %v", frameworkconstants.ErrLDAPUnavailable, err)
+	}
+
+	if firstName := entry.GetEqualFoldAttributeValue(firstNameAttribute); firstName != "" {
+		user.FirstName = firstName
+	}
+	if lastName := entry.GetEqualFoldAttributeValue(lastNameAttribute); lastName != "" {
+		user.LastName = lastName
+	}
+	if role, ok := a.mappedRole(entry.GetEqualFoldAttributeValues(groupAttribute)); ok && !isSuperRole(user.Role) {
+		user.Role = role
+	}
+
+	return nil
+}
+
+func (a *ldapAuthenticator) mappedRole(groups []string) (string, bool) {
+	for _, mapping := range a.directory.GroupRoleMappings {
+		for _, group := range groups {
+			if strings.EqualFold(strings.TrimSpace(group), strings.TrimSpace(mapping.Group)) {
+				return mapping.Role, true
+			}
+		}
+	}
+	return "", false
+}
+
+func isSuperRole(role string) bool {
+	return role == string(frameworkconstants.UserRoleSuperAdmin) || role == string(frameworkconstants.UserRoleSuperUser)
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
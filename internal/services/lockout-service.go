@@ -0,0 +1,76 @@
+package services
+
+import (
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+)
+
+// LockoutService locks accounts after too many failed logins, whichever factor failed
+type LockoutService struct {
+	cfg      *frameworkdto.FrameworkConfig
+	userRepo *repositories.UserRepository
+}
+
+func NewLockoutService(cfg *frameworkdto.FrameworkConfig, userRepo *repositories.UserRepository) *LockoutService {
+	return &LockoutService{
+		cfg:      cfg,
+		userRepo: userRepo,
+	}
+}
+
+// RecordFailedLogin counts a wrong password or code and locks the account once the threshold
+// is reached, returning ErrAccountLocked if it is locked and ErrInvalidPassword otherwise.
+// Consecutive lockouts double the lockout window.
+func (s *LockoutService) RecordFailedLogin(user *entities.User, now time.Time) error {
+	maxAttempts := s.cfg.MaxFailedLoginAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = frameworkconstants.MaxFailedLoginAttempts
+	}
+
+	failedAttempts, lockoutCount, err := s.userRepo.RecordFailedLogin(user.ID)
+	if err != nil {
+		return err
+	}
+	user.FailedLoginAttempts = failedAttempts
+	if failedAttempts < maxAttempts {
+		return frameworkconstants.ErrInvalidPassword
+	}
+
+	lockedUntil := now.Add(s.lockoutDuration(lockoutCount + 1))
+	locked, err := s.userRepo.LockOut(user.ID, failedAttempts, lockoutCount, lockedUntil)
+	if err != nil {
+		return err
+	}
+	// Otherwise a concurrent failure locked the account, or will once it has counted itself
+	if locked {
+		user.FailedLoginAttempts = 0
+		user.LockoutCount = lockoutCount + 1
+		user.LockedUntil = &lockedUntil
+	}
+
+	return frameworkconstants.ErrAccountLocked
+}
+
+func (s *LockoutService) lockoutDuration(lockoutCount int) time.Duration {
+	duration := s.cfg.LockoutDuration
+	if duration <= 0 {
+		duration = frameworkconstants.DefaultLockoutDuration
+	}
+	maxDuration := s.cfg.MaxLockoutDuration
+	if maxDuration <= 0 {
+		maxDuration = frameworkconstants.DefaultMaxLockoutDuration
+	}
+
+	for i := 1; i < lockoutCount && duration < maxDuration; i++ {
+		duration *= 2
+	}
+	if duration > maxDuration {
+		duration = maxDuration
+	}
+
+	return duration
+}
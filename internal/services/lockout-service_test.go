@@ -0,0 +1,154 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+)
+
+// enableMFA enrols the user in TOTP MFA and returns the secret
+func enableMFA(t *testing.T, s *testServices, user *entities.User) string {
+	t.Helper()
+
+	secret, err := frameworkutils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.MFASecret = secret
+	user.MFAEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		t.Fatal(err)
+	}
+	return secret
+}
+
+// passwordLogin logs the user in with the password
+func passwordLogin(s *testServices, user *entities.User, password string) (frameworkdto.LoginResponseDTO, error) {
+	return s.loginService.Login(frameworkdto.LoginDTO{Email: user.Email, Password: password}, user.TenantID, "127.0.0.1", "test")
+}
+
+func TestFailedLoginsLockAccount(t *testing.T) {
+	tests := []struct {
+		name string
+		mfa  bool
+		// fail makes the attempt'th failed login
+		fail func(t *testing.T, s *testServices, user *entities.User, attempt int) error
+	}{
+		{
+			name: "wrong passwords",
+			fail: func(t *testing.T, s *testServices, user *entities.User, attempt int) error {
+				_, err := passwordLogin(s, user, "Wrong-Horse-Battery")
+				return err
+			},
+		},
+		{
+			name: "wrong MFA codes",
+			mfa:  true,
+			fail: func(t *testing.T, s *testServices, user *entities.User, attempt int) error {
+				challenge, err := passwordLogin(s, user, testPassword)
+				if err != nil {
+					return err
+				}
+				_, err = s.mfaService.VerifyChallenge(frameworkdto.MFAVerifyRequestDTO{MFAToken: challenge.MFAToken, Code: "wrong-code"}, "127.0.0.1", "test")
+				return err
+			},
+		},
+		{
+			name: "wrong codes after right passwords",
+			mfa:  true,
+			fail: func(t *testing.T, s *testServices, user *entities.User, attempt int) error {
+				if attempt%2 == 0 {
+					_, err := passwordLogin(s, user, "Wrong-Horse-Battery")
+					return err
+				}
+				challenge, err := passwordLogin(s, user, testPassword)
+				if err != nil {
+					return err
+				}
+				_, err = s.mfaService.VerifyChallenge(frameworkdto.MFAVerifyRequestDTO{MFAToken: challenge.MFAToken, Code: "wrong-code"}, "127.0.0.1", "test")
+				return err
+			},
+		},
+		{
+			name: "wrong current passwords when changing password",
+			fail: func(t *testing.T, s *testServices, user *entities.User, attempt int) error {
+				return s.passwordService.ChangePassword(user.TenantID, user.ID, "", frameworkdto.ChangePasswordDTO{
+					CurrentPassword: "Wrong-Horse-Battery",
+					NewPassword:     "Another-Tricky-Horse",
+				})
+			},
+		},
+		{
+			name: "failures counted against a stale copy of the user",
+			fail: func(t *testing.T, s *testServices, user *entities.User, attempt int) error {
+				// Concurrent requests each read the user before any of them counts its failure
+				stale := *user
+				return s.lockoutService.RecordFailedLogin(&stale, time.Now())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			user := s.registerTenant(t, "acme.com", 5)
+			if tt.mfa {
+				enableMFA(t, s, user)
+			}
+
+			for attempt := 0; attempt < frameworkconstants.MaxFailedLoginAttempts; attempt++ {
+				err := tt.fail(t, s, user, attempt)
+				if last := attempt == frameworkconstants.MaxFailedLoginAttempts-1; last != (err == frameworkconstants.ErrAccountLocked) {
+					t.Fatalf("failed login %d: error = %v, want the account locked only by the last", attempt+1, err)
+				}
+				if err == nil {
+					t.Fatalf("failed login %d succeeded", attempt+1)
+				}
+			}
+
+			if _, err := passwordLogin(s, user, testPassword); err != frameworkconstants.ErrAccountLocked {
+				t.Errorf("Login() with the right password error = %v, want %v", err, frameworkconstants.ErrAccountLocked)
+			}
+		})
+	}
+}
+
+func TestSecondFactorResetsFailedLogins(t *testing.T) {
+	s := newTestServices(t)
+	user := s.registerTenant(t, "acme.com", 5)
+	secret := enableMFA(t, s, user)
+
+	if _, err := passwordLogin(s, user, "Wrong-Horse-Battery"); err != frameworkconstants.ErrInvalidPassword {
+		t.Fatalf("Login() error = %v, want %v", err, frameworkconstants.ErrInvalidPassword)
+	}
+	challenge, err := passwordLogin(s, user, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failedAttempts := func() int {
+		stored, err := s.userRepo.GetByID(user.ID, user.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored.FailedLoginAttempts
+	}
+	if got := failedAttempts(); got != 1 {
+		t.Errorf("failed logins after the password = %d, want 1", got)
+	}
+
+	code, err := frameworkutils.GenerateTOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.mfaService.VerifyChallenge(frameworkdto.MFAVerifyRequestDTO{MFAToken: challenge.MFAToken, Code: code}, "127.0.0.1", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if got := failedAttempts(); got != 0 {
+		t.Errorf("failed logins after the second factor = %d, want 0", got)
+	}
+}
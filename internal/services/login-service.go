@@ -6,66 +6,326 @@ import (
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
 	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"github.com/geekible-ltd/serviceframework/internal/repositories"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type LoginService struct {
-	cfg        *frameworkdto.FrameworkConfig
-	userRepo   *repositories.UserRepository
-	tenantRepo *repositories.TenantRepository
+	cfg                    *frameworkdto.FrameworkConfig
+	userRepo               *repositories.UserRepository
+	tenantRepo             *repositories.TenantRepository
+	tokenService           *TokenService
+	tokenRevocationService *TokenRevocationService
+	mfaService             *MFAService
+	passwordService        *PasswordService
+	lockoutService         *LockoutService
+	magicLinkRepo          *repositories.MagicLinkRepository
 }
 
-func NewLoginService(cfg *frameworkdto.FrameworkConfig, userRepo *repositories.UserRepository, tenantRepo *repositories.TenantRepository) *LoginService {
+func NewLoginService(
+	cfg *frameworkdto.FrameworkConfig,
+	userRepo *repositories.UserRepository,
+	tenantRepo *repositories.TenantRepository,
+	tokenService *TokenService,
+	tokenRevocationService *TokenRevocationService,
+	mfaService *MFAService,
+	passwordService *PasswordService,
+	lockoutService *LockoutService,
+	magicLinkRepo *repositories.MagicLinkRepository) *LoginService {
 	return &LoginService{
-		cfg:        cfg,
-		userRepo:   userRepo,
-		tenantRepo: tenantRepo,
+		cfg:                    cfg,
+		userRepo:               userRepo,
+		tenantRepo:             tenantRepo,
+		tokenService:           tokenService,
+		tokenRevocationService: tokenRevocationService,
+		mfaService:             mfaService,
+		passwordService:        passwordService,
+		lockoutService:         lockoutService,
+		magicLinkRepo:          magicLinkRepo,
 	}
 }
 
-func (s *LoginService) Login(loginRequest frameworkdto.LoginDTO, ipAddress string) (frameworkdto.LoginResponseDTO, error) {
-	user, err := s.userRepo.GetByEmail(loginRequest.Email)
+// Login signs a user in with their password. When the request was addressed to a tenant,
+// tenantID limits the login to that tenant's users; zero looks the user up by email alone and
+// fails with ErrTenantRequired when users of several tenants share the email.
+func (s *LoginService) Login(loginRequest frameworkdto.LoginDTO, tenantID uint, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	user, err := s.getUserByEmail(loginRequest.Email, tenantID)
 	if err != nil && err == gorm.ErrRecordNotFound {
 		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserNotFound
 	} else if err != nil {
 		return frameworkdto.LoginResponseDTO{}, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginRequest.Password)); err != nil {
-		user.FailedLoginAttempts++
-		if user.FailedLoginAttempts >= frameworkconstants.MaxFailedLoginAttempts {
-			user.IsActive = false
+	now := time.Now()
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrAccountLocked
+	}
+
+	authenticator, err := s.authenticator(user.TenantID)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if err := authenticator.Authenticate(user, loginRequest.Password); err != nil {
+		if err != frameworkconstants.ErrInvalidPassword {
+			return frameworkdto.LoginResponseDTO{}, err
 		}
+		return frameworkdto.LoginResponseDTO{}, s.lockoutService.RecordFailedLogin(user, now)
+	}
+
+	if !user.IsActive {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserAccountInactive
+	}
+
+	tenant, err := s.tenantRepo.GetByID(user.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrTenantNotFound
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return s.completeLogin(user, tenant, frameworkconstants.AuthMethodPassword, now, ipAddress, userAgent)
+}
+
+// ChangeExpiredPassword completes a login that was paused because the password had expired
+func (s *LoginService) ChangeExpiredPassword(changeRequest frameworkdto.PasswordChangeRequestDTO, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	return s.passwordService.CompleteChangeChallenge(changeRequest, ipAddress, userAgent)
+}
+
+func (s *LoginService) RefreshToken(refreshRequest frameworkdto.RefreshTokenRequestDTO, ipAddress string) (frameworkdto.LoginResponseDTO, error) {
+	return s.tokenService.Refresh(refreshRequest.RefreshToken, ipAddress)
+}
+
+func (s *LoginService) Logout(tokenDto frameworkdto.TokenDTO, logoutRequest frameworkdto.LogoutRequestDTO) error {
+	return s.tokenRevocationService.Logout(tokenDto, logoutRequest.RefreshToken)
+}
+
+// Reauthenticate confirms the identity of a signed-in user with their password, a TOTP code, or
+// both, and issues a new access token for the same session with a fresh auth_time. Failures
+// count towards account lockout as failed logins do.
+func (s *LoginService) Reauthenticate(tokenDto frameworkdto.TokenDTO, userID uint, reauthenticateRequest frameworkdto.ReauthenticateDTO) (frameworkdto.LoginResponseDTO, error) {
+	if tokenDto.Sid == "" || frameworkutils.IsServicePrincipal(tokenDto) || frameworkutils.IsImpersonating(tokenDto) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrCannotReauthenticate
+	}
+	if reauthenticateRequest.Password == "" && reauthenticateRequest.Code == "" {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidPassword
+	}
+
+	user, err := s.userRepo.GetByID(userID, tokenDto.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	now := time.Now()
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrAccountLocked
+	}
+	if !user.IsActive {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserAccountInactive
+	}
+
+	var authMethods []string
+	if reauthenticateRequest.Password != "" {
+		authenticator, err := s.authenticator(user.TenantID)
+		if err != nil {
+			return frameworkdto.LoginResponseDTO{}, err
+		}
+		if err := authenticator.Authenticate(user, reauthenticateRequest.Password); err != nil {
+			if err != frameworkconstants.ErrInvalidPassword {
+				return frameworkdto.LoginResponseDTO{}, err
+			}
+			return frameworkdto.LoginResponseDTO{}, s.lockoutService.RecordFailedLogin(user, now)
+		}
+		authMethods = append(authMethods, frameworkconstants.AuthMethodPassword)
+	}
+
+	if reauthenticateRequest.Code != "" {
+		if err := s.mfaService.VerifyTOTP(user, reauthenticateRequest.Code); err != nil {
+			if err != frameworkconstants.ErrInvalidMFACode {
+				return frameworkdto.LoginResponseDTO{}, err
+			}
+			if err := s.lockoutService.RecordFailedLogin(user, now); err != frameworkconstants.ErrInvalidPassword {
+				return frameworkdto.LoginResponseDTO{}, err
+			}
+			return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidMFACode
+		}
+		authMethods = append(authMethods, frameworkconstants.AuthMethodOTP)
+	}
+
+	if len(authMethods) > 1 {
+		authMethods = append(authMethods, frameworkconstants.AuthMethodMFA)
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockoutCount > 0 {
+		clearLockout(user)
 		if err := s.userRepo.Update(user); err != nil {
 			return frameworkdto.LoginResponseDTO{}, err
 		}
-		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidPassword
+	}
+
+	return s.tokenService.Reauthenticate(user, tokenDto.Sid, authMethods)
+}
+
+// RequestMagicLink sends a single-use sign-in link to the user. Nothing is sent to unknown,
+// inactive or locked users, or to users of tenants without magic links enabled, and the caller
+// is not told, so the endpoint cannot be used to discover accounts. A non-zero tenantID limits
+// the request to that tenant's users.
+func (s *LoginService) RequestMagicLink(magicLinkRequest frameworkdto.MagicLinkRequestDTO, tenantID uint, ipAddress string) error {
+	if s.cfg.MagicLinkSender == nil {
+		return frameworkconstants.ErrMagicLinkNotConfigured
+	}
+
+	user, err := s.getUserByEmail(magicLinkRequest.Email, tenantID)
+	if err != nil && (err == gorm.ErrRecordNotFound || err == frameworkconstants.ErrTenantRequired) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !user.IsActive || (user.LockedUntil != nil && user.LockedUntil.After(now)) {
+		return nil
 	}
 
 	tenant, err := s.tenantRepo.GetByID(user.TenantID)
 	if err != nil && err == gorm.ErrRecordNotFound {
-		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrTenantNotFound
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !tenant.MagicLinkEnabled {
+		return nil
+	}
+
+	token, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	ttl := s.cfg.MagicLinkTTL
+	if ttl <= 0 {
+		ttl = frameworkconstants.DefaultMagicLinkTTL
+	}
+
+	magicLink := &entities.MagicLinkToken{
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		TokenHash: frameworkutils.HashToken(token),
+		IPAddress: ipAddress,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.magicLinkRepo.Create(magicLink); err != nil {
+		return err
+	}
+
+	return s.cfg.MagicLinkSender(user.Email, token, magicLink.ExpiresAt)
+}
+
+// MagicLinkLogin exchanges a magic-link token for a login. The link proves control of the
+// email address, so the address is marked verified; MFA and password expiry apply as they do
+// for a password login. A non-zero tenantID only accepts links issued to that tenant's users.
+func (s *LoginService) MagicLinkLogin(magicLinkLogin frameworkdto.MagicLinkLoginDTO, tenantID uint, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	magicLink, err := s.magicLinkRepo.GetByTokenHash(frameworkutils.HashToken(magicLinkLogin.Token))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidMagicLink
 	} else if err != nil {
 		return frameworkdto.LoginResponseDTO{}, err
 	}
 
 	now := time.Now()
+	if magicLink.UsedAt != nil || magicLink.ExpiresAt.Before(now) || (tenantID != 0 && magicLink.TenantID != tenantID) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidMagicLink
+	}
+
+	consumed, err := s.magicLinkRepo.Consume(magicLink.ID)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !consumed {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidMagicLink
+	}
+
+	user, err := s.userRepo.GetByID(magicLink.UserID, magicLink.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrAccountLocked
+	}
+
+	if !user.IsActive {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserAccountInactive
+	}
+
+	tenant, err := s.tenantRepo.GetByID(user.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrTenantNotFound
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	// The tenant may have switched magic links off since the link was sent
+	if !tenant.MagicLinkEnabled {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidMagicLink
+	}
+
+	user.IsEmailVerified = true
+
+	return s.completeLogin(user, tenant, frameworkconstants.AuthMethodMagicLink, now, ipAddress, userAgent)
+}
+
+// getUserByEmail finds the user with the email address, within the tenant when one is given
+func (s *LoginService) getUserByEmail(email string, tenantID uint) (*entities.User, error) {
+	if tenantID != 0 {
+		return s.userRepo.GetByEmailAndTenant(email, tenantID)
+	}
+	return s.userRepo.GetByEmail(email)
+}
+
+// completeLogin turns away users of suspended tenants, records a successful first factor, then
+// starts an MFA or password change challenge when one is due and otherwise issues tokens. Failed
+// logins are only forgotten once tokens are issued, so wrong second factors keep counting.
+func (s *LoginService) completeLogin(user *entities.User, tenant *entities.Tenant, authMethod string, now time.Time, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	if !tenant.IsActive {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrTenantSuspended
+	}
+
 	user.LastLoginAt = &now
 	user.LastLoginIP = ipAddress
-	user.FailedLoginAttempts = 0
+
+	mfaDue := user.MFAEnabled || tenant.MFARequired
+	passwordExpired := false
+	if !mfaDue {
+		var err error
+		if passwordExpired, err = s.passwordService.PasswordExpired(user); err != nil {
+			return frameworkdto.LoginResponseDTO{}, err
+		}
+	}
+	if !mfaDue && !passwordExpired {
+		clearLockout(user)
+	}
 
 	if err := s.userRepo.Update(user); err != nil {
 		return frameworkdto.LoginResponseDTO{}, err
 	}
 
-	token, err := frameworkutils.GenerateJWT(user.ID, tenant.ID, user.Email, user.FirstName, user.LastName, user.Role, []byte(s.cfg.JWTSecret))
-	if err != nil {
-		return frameworkdto.LoginResponseDTO{}, err
+	authMethods := []string{authMethod}
+	if mfaDue {
+		return s.mfaService.CreateChallenge(user, authMethods, ipAddress)
 	}
+	if passwordExpired {
+		return s.passwordService.CreateChangeChallenge(user, authMethods, ipAddress)
+	}
+
+	return s.tokenService.IssueTokens(user, authMethods, ipAddress, userAgent)
+}
 
-	return frameworkdto.LoginResponseDTO{
-		Token: frameworkdto.BearerToken(token),
-	}, nil
+func (s *LoginService) authenticator(tenantID uint) (Authenticator, error) {
+	return s.passwordService.Authenticator(tenantID)
 }
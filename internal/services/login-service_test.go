@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginRehashesPassword(t *testing.T) {
+	tests := []struct {
+		name string
+		// hasher makes the stored hash; nil keeps the one made at registration
+		hasher      frameworkutils.PasswordHasher
+		password    string
+		wantErr     error
+		wantRehash  bool
+		wantChanged bool
+	}{
+		{name: "current hash", password: testPassword},
+		{name: "bcrypt hash of another cost", hasher: &frameworkutils.BcryptPasswordHasher{Cost: bcrypt.MinCost}, password: testPassword, wantChanged: true},
+		{name: "argon2id hash", hasher: &frameworkutils.Argon2idPasswordHasher{MemoryKiB: 64, Iterations: 1, Parallelism: 1}, password: testPassword, wantChanged: true},
+		{name: "wrong password", hasher: &frameworkutils.BcryptPasswordHasher{Cost: bcrypt.MinCost}, password: "Wrong-Horse-Battery", wantErr: frameworkconstants.ErrInvalidPassword, wantRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			admin := s.registerTenant(t, "acme.com", 5)
+
+			if tt.hasher != nil {
+				passwordHash, err := tt.hasher.Hash(testPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				admin.PasswordHash = passwordHash
+				if err := s.userRepo.Update(admin); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := s.loginService.Login(frameworkdto.LoginDTO{Email: admin.Email, Password: tt.password}, admin.TenantID, "127.0.0.1", "test")
+			if err != tt.wantErr {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+
+			user, err := s.userRepo.GetByID(admin.ID, admin.TenantID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := user.PasswordHash != admin.PasswordHash; changed != tt.wantChanged {
+				t.Errorf("stored hash changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if needsRehash := s.passwordHasher.NeedsRehash(user.PasswordHash); needsRehash != tt.wantRehash {
+				t.Errorf("stored hash needs re-hashing = %v, want %v", needsRehash, tt.wantRehash)
+			}
+			if err := s.passwordHasher.Verify(testPassword, user.PasswordHash); err != nil {
+				t.Errorf("stored hash does not verify the password: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+type MFAService struct {
+	cfg          *frameworkdto.FrameworkConfig
+	userRepo     *repositories.UserRepository
+	tenantRepo   *repositories.TenantRepository
+	mfaRepo      *repositories.MFARepository
+	tokenService *TokenService
+
+	passwordService *PasswordService
+	lockoutService  *LockoutService
+}
+
+func NewMFAService(
+	cfg *frameworkdto.FrameworkConfig,
+	userRepo *repositories.UserRepository,
+	tenantRepo *repositories.TenantRepository,
+	mfaRepo *repositories.MFARepository,
+	tokenService *TokenService,
+	passwordService *PasswordService,
+	lockoutService *LockoutService) *MFAService {
+	return &MFAService{
+		cfg:          cfg,
+		userRepo:     userRepo,
+		tenantRepo:   tenantRepo,
+		mfaRepo:      mfaRepo,
+		tokenService: tokenService,
+
+		passwordService: passwordService,
+		lockoutService:  lockoutService,
+	}
+}
+
+// CreateChallenge starts the second login step for a user whose first factor, named by
+// authMethods, has been verified
+func (s *MFAService) CreateChallenge(user *entities.User, authMethods []string, ipAddress string) (frameworkdto.LoginResponseDTO, error) {
+	mfaToken, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if err := s.mfaRepo.CreateChallenge(&entities.MFAChallenge{
+		UserID:      user.ID,
+		TenantID:    user.TenantID,
+		TokenHash:   frameworkutils.HashToken(mfaToken),
+		IPAddress:   ipAddress,
+		AuthMethods: strings.Join(authMethods, " "),
+		ExpiresAt:   time.Now().Add(frameworkconstants.MFAChallengeTTL),
+	}); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return frameworkdto.LoginResponseDTO{
+		MFARequired:           true,
+		MFAEnrollmentRequired: !user.MFAEnabled,
+		MFAToken:              mfaToken,
+	}, nil
+}
+
+// VerifyChallenge completes a login with a TOTP or recovery code. A user enrolling because
+// their tenant requires MFA has the enrollment confirmed here and receives recovery codes.
+// Wrong codes count towards account lockout as wrong passwords do.
+func (s *MFAService) VerifyChallenge(verifyRequest frameworkdto.MFAVerifyRequestDTO, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	challenge, user, err := s.getChallengeUser(verifyRequest.MFAToken)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if user.MFASecret == "" {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrMFANotEnrolled
+	}
+
+	if err := s.verifyCode(user, verifyRequest.Code, user.MFAEnabled); err != nil {
+		if err == frameworkconstants.ErrInvalidMFACode {
+			if updateErr := s.mfaRepo.IncrementChallengeFailures(challenge.ID); updateErr != nil {
+				return frameworkdto.LoginResponseDTO{}, updateErr
+			}
+			if lockoutErr := s.lockoutService.RecordFailedLogin(user, time.Now()); lockoutErr != frameworkconstants.ErrInvalidPassword {
+				return frameworkdto.LoginResponseDTO{}, lockoutErr
+			}
+		}
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	consumed, err := s.mfaRepo.ConsumeChallenge(challenge.ID)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !consumed {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidMFAChallenge
+	}
+
+	var recoveryCodes []string
+	if !user.MFAEnabled {
+		user.MFAEnabled = true
+		if err := s.userRepo.Update(user); err != nil {
+			return frameworkdto.LoginResponseDTO{}, err
+		}
+		if recoveryCodes, err = s.generateRecoveryCodes(user.ID); err != nil {
+			return frameworkdto.LoginResponseDTO{}, err
+		}
+	}
+
+	// An expired password is changed after the second factor so that the password alone
+	// cannot be used to replace it
+	passwordExpired, err := s.passwordService.PasswordExpired(user)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	authMethods := append(strings.Fields(challenge.AuthMethods), frameworkconstants.AuthMethodOTP, frameworkconstants.AuthMethodMFA)
+
+	var loginResponse frameworkdto.LoginResponseDTO
+	if passwordExpired {
+		loginResponse, err = s.passwordService.CreateChangeChallenge(user, authMethods, ipAddress)
+	} else {
+		if user.FailedLoginAttempts > 0 || user.LockoutCount > 0 {
+			clearLockout(user)
+			if err := s.userRepo.Update(user); err != nil {
+				return frameworkdto.LoginResponseDTO{}, err
+			}
+		}
+		loginResponse, err = s.tokenService.IssueTokens(user, authMethods, ipAddress, userAgent)
+	}
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	loginResponse.RecoveryCodes = recoveryCodes
+
+	return loginResponse, nil
+}
+
+// EnrollFromChallenge lets a user who must use MFA but has not enrolled yet obtain a secret
+// using their pending login challenge instead of a bearer token
+func (s *MFAService) EnrollFromChallenge(enrollRequest frameworkdto.MFAEnrollRequestDTO) (frameworkdto.MFAEnrollmentDTO, error) {
+	_, user, err := s.getChallengeUser(enrollRequest.MFAToken)
+	if err != nil {
+		return frameworkdto.MFAEnrollmentDTO{}, err
+	}
+
+	return s.startEnrollment(user)
+}
+
+func (s *MFAService) StartEnrollment(tenantID uint, userID uint) (frameworkdto.MFAEnrollmentDTO, error) {
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return frameworkdto.MFAEnrollmentDTO{}, err
+	}
+
+	return s.startEnrollment(user)
+}
+
+func (s *MFAService) ConfirmEnrollment(tenantID uint, userID uint, code string) (frameworkdto.MFARecoveryCodesDTO, error) {
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return frameworkdto.MFARecoveryCodesDTO{}, err
+	}
+
+	if user.MFAEnabled {
+		return frameworkdto.MFARecoveryCodesDTO{}, frameworkconstants.ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return frameworkdto.MFARecoveryCodesDTO{}, frameworkconstants.ErrMFANotEnrolled
+	}
+
+	if err := s.verifyCode(user, code, false); err != nil {
+		return frameworkdto.MFARecoveryCodesDTO{}, err
+	}
+
+	user.MFAEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return frameworkdto.MFARecoveryCodesDTO{}, err
+	}
+
+	recoveryCodes, err := s.generateRecoveryCodes(user.ID)
+	if err != nil {
+		return frameworkdto.MFARecoveryCodesDTO{}, err
+	}
+
+	return frameworkdto.MFARecoveryCodesDTO{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *MFAService) Disable(tenantID uint, userID uint, code string) error {
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.MFAEnabled {
+		return frameworkconstants.ErrMFANotEnabled
+	}
+
+	tenant, err := s.tenantRepo.GetByID(user.TenantID)
+	if err != nil {
+		return err
+	}
+	if tenant.MFARequired {
+		return frameworkconstants.ErrMFARequiredByTenant
+	}
+
+	if err := s.verifyCode(user, code, true); err != nil {
+		return err
+	}
+
+	user.MFAEnabled = false
+	user.MFASecret = ""
+	user.MFALastUsedStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.mfaRepo.ReplaceRecoveryCodes(user.ID, nil)
+}
+
+func (s *MFAService) RegenerateRecoveryCodes(tenantID uint, userID uint, code string) (frameworkdto.MFARecoveryCodesDTO, error) {
+	user, err := s.getUser(tenantID, userID)
+	if err != nil {
+		return frameworkdto.MFARecoveryCodesDTO{}, err
+	}
+
+	if !user.MFAEnabled {
+		return frameworkdto.MFARecoveryCodesDTO{}, frameworkconstants.ErrMFANotEnabled
+	}
+
+	if err := s.verifyCode(user, code, false); err != nil {
+		return frameworkdto.MFARecoveryCodesDTO{}, err
+	}
+
+	recoveryCodes, err := s.generateRecoveryCodes(user.ID)
+	if err != nil {
+		return frameworkdto.MFARecoveryCodesDTO{}, err
+	}
+
+	return frameworkdto.MFARecoveryCodesDTO{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *MFAService) getUser(tenantID uint, userID uint) (*entities.User, error) {
+	user, err := s.userRepo.GetByID(userID, tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return nil, frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *MFAService) getChallengeUser(mfaToken string) (*entities.MFAChallenge, *entities.User, error) {
+	challenge, err := s.mfaRepo.GetChallengeByTokenHash(frameworkutils.HashToken(mfaToken))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return nil, nil, frameworkconstants.ErrInvalidMFAChallenge
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if challenge.UsedAt != nil ||
+		challenge.ExpiresAt.Before(time.Now()) ||
+		challenge.FailedAttempts >= frameworkconstants.MaxMFAChallengeAttempts {
+		return nil, nil, frameworkconstants.ErrInvalidMFAChallenge
+	}
+
+	user, err := s.getUser(challenge.TenantID, challenge.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, frameworkconstants.ErrUserAccountInactive
+	}
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return nil, nil, frameworkconstants.ErrAccountLocked
+	}
+
+	return challenge, user, nil
+}
+
+func (s *MFAService) startEnrollment(user *entities.User) (frameworkdto.MFAEnrollmentDTO, error) {
+	if user.MFAEnabled {
+		return frameworkdto.MFAEnrollmentDTO{}, frameworkconstants.ErrMFAAlreadyEnabled
+	}
+
+	issuer := s.cfg.MFAIssuer
+	if issuer == "" {
+		tenant, err := s.tenantRepo.GetByID(user.TenantID)
+		if err != nil {
+			return frameworkdto.MFAEnrollmentDTO{}, err
+		}
+		issuer = tenant.Name
+	}
+
+	secret, err := frameworkutils.GenerateTOTPSecret()
+	if err != nil {
+		return frameworkdto.MFAEnrollmentDTO{}, err
+	}
+
+	user.MFASecret = secret
+	user.MFALastUsedStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return frameworkdto.MFAEnrollmentDTO{}, err
+	}
+
+	return frameworkdto.MFAEnrollmentDTO{
+		Secret:     secret,
+		OTPAuthURI: frameworkutils.TOTPAuthURI(issuer, user.Email, secret),
+	}, nil
+}
+
+// VerifyTOTP checks a current authenticator code for an enrolled user. Recovery codes are not
+// accepted because they are kept for regaining access, not for confirming a session.
+func (s *MFAService) VerifyTOTP(user *entities.User, code string) error {
+	if !user.MFAEnabled {
+		return frameworkconstants.ErrMFANotEnrolled
+	}
+	return s.verifyCode(user, code, false)
+}
+
+// verifyCode accepts a TOTP code that has not been used before or, when allowed, an unused recovery code
+func (s *MFAService) verifyCode(user *entities.User, code string, allowRecoveryCode bool) error {
+	if step, ok := frameworkutils.ValidateTOTP(user.MFASecret, code, time.Now()); ok {
+		used, err := s.userRepo.UseTOTPStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return frameworkconstants.ErrInvalidMFACode
+		}
+		user.MFALastUsedStep = step
+		return nil
+	}
+
+	if !allowRecoveryCode {
+		return frameworkconstants.ErrInvalidMFACode
+	}
+
+	recoveryCode, err := s.mfaRepo.GetUnusedRecoveryCode(user.ID, frameworkutils.HashToken(frameworkutils.NormalizeRecoveryCode(code)))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrInvalidMFACode
+	} else if err != nil {
+		return err
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(recoveryCode.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return frameworkconstants.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *MFAService) generateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, frameworkconstants.MFARecoveryCodeCount)
+	records := make([]entities.MFARecoveryCode, frameworkconstants.MFARecoveryCodeCount)
+	for i := range codes {
+		code, err := frameworkutils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = entities.MFARecoveryCode{
+			UserID:   userID,
+			CodeHash: frameworkutils.HashToken(frameworkutils.NormalizeRecoveryCode(code)),
+		}
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcCacheTTL = time.Hour
+	// oidcMaxResponseBytes caps what is read from a provider; discovery documents, key sets and
+	// token responses are a few kilobytes
+	oidcMaxResponseBytes = 1 << 20
+)
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcProviderMetadata struct {
+	discovery oidcDiscovery
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+type oidcIDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	Email           string `json:"email"`
+	EmailVerified   *bool  `json:"email_verified"`
+	GivenName       string `json:"given_name"`
+	FamilyName      string `json:"family_name"`
+}
+
+// oidcClient talks to OpenID Connect providers, caching discovery documents and signing keys per issuer
+type oidcClient struct {
+	httpClient *http.Client
+	mu         sync.Mutex
+	providers  map[string]*oidcProviderMetadata
+}
+
+// newOIDCClient returns a client that only reaches public addresses. Issuers are set by tenant
+// admins, so the client must not become a way into the framework's own network or cloud
+// metadata endpoints. The address is checked after DNS resolution, covering redirects and
+// names that resolve to private addresses. Requests are made directly rather than through
+// a proxy from the environment, whose address would be checked instead of the provider's.
+func newOIDCClient() *oidcClient {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return checkOIDCAddress(address)
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &oidcClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		providers:  make(map[string]*oidcProviderMetadata),
+	}
+}
+
+// checkOIDCAddress refuses loopback, private, link-local and other non-public addresses
+func checkOIDCAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("oidc provider address %s is not public", host)
+	}
+	return nil
+}
+
+// validateOIDCIssuer requires the https URL without query or fragment that OpenID Connect
+// issuers must be
+func validateOIDCIssuer(issuer string) error {
+	parsed, err := url.Parse(issuer)
+	if err != nil || !isHTTPSURL(parsed) || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return frameworkconstants.ErrInvalidOIDCIssuer
+	}
+	return nil
+}
+
+func isHTTPSURL(parsed *url.URL) bool {
+	return parsed.Scheme == "https" && parsed.Host != "" && parsed.User == nil
+}
+
+func (c *oidcClient) metadata(issuer string, forceRefresh bool) (*oidcProviderMetadata, error) {
+	c.mu.Lock()
+	cached, ok := c.providers[issuer]
+	c.mu.Unlock()
+	if ok && !forceRefresh && time.Since(cached.fetchedAt) < oidcCacheTTL {
+		return cached, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := c.getJSON(strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != issuer {
+		return nil, fmt.Errorf("oidc discovery issuer %q does not match configured issuer %q", discovery.Issuer, issuer)
+	}
+	for _, endpoint := range []string{discovery.AuthorizationEndpoint, discovery.TokenEndpoint, discovery.JWKSURI} {
+		if parsed, err := url.Parse(endpoint); err != nil || !isHTTPSURL(parsed) {
+			return nil, fmt.Errorf("oidc discovery endpoint %q is not an https url", endpoint)
+		}
+	}
+
+	var jwks frameworkdto.JWKSDTO
+	if err := c.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]any)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	metadata := &oidcProviderMetadata{discovery: discovery, keys: keys, fetchedAt: time.Now()}
+	c.mu.Lock()
+	c.providers[issuer] = metadata
+	c.mu.Unlock()
+
+	return metadata, nil
+}
+
+func (c *oidcClient) getJSON(endpoint string, out any) error {
+	if parsed, err := url.Parse(endpoint); err != nil || !isHTTPSURL(parsed) {
+		return fmt.Errorf("oidc endpoint %q is not an https url", endpoint)
+	}
+
+	resp, err := c.httpClient.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc request to %s failed with status %d", endpoint, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseBytes)).Decode(out)
+}
+
+// exchangeCode redeems an authorization code at the token endpoint and returns the raw ID token
+func (c *oidcClient) exchangeCode(metadata *oidcProviderMetadata, clientID, clientSecret, redirectURL, code, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("client_id", clientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, metadata.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseBytes)).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || tokenResponse.Error != "" {
+		return "", fmt.Errorf("oidc token exchange failed: %s %s", tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("oidc token response did not include an id_token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// validateIDToken verifies the ID token signature, issuer, audience, expiry and nonce
+func (c *oidcClient) validateIDToken(issuer, clientID, nonce, rawIDToken string) (*oidcIDTokenClaims, error) {
+	metadata, err := c.metadata(issuer, false)
+	if err != nil {
+		return nil, err
+	}
+
+	keyfunc := func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if key, ok := metadata.keys[kid]; ok {
+			return key, nil
+		}
+		// The provider may have rotated its keys since they were cached
+		refreshed, err := c.metadata(issuer, true)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := refreshed.keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown oidc signing key %q", kid)
+	}
+
+	claims := &oidcIDTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("oidc id token nonce mismatch")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != clientID {
+		return nil, errors.New("oidc id token azp does not match client id")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("oidc id token has no subject")
+	}
+
+	return claims, nil
+}
+
+func parseJWK(jwk frameworkdto.JWKDTO) (any, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
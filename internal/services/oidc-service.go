@@ -0,0 +1,294 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const defaultOIDCScopes = "openid email profile"
+
+type OIDCService struct {
+	userRepo             *repositories.UserRepository
+	identityProviderRepo *repositories.IdentityProviderRepository
+	registrationService  *UserRegistrationService
+	tokenService         *TokenService
+	rbacService          *RBACService
+	client               *oidcClient
+}
+
+func NewOIDCService(
+	userRepo *repositories.UserRepository,
+	identityProviderRepo *repositories.IdentityProviderRepository,
+	registrationService *UserRegistrationService,
+	tokenService *TokenService,
+	rbacService *RBACService) *OIDCService {
+	return &OIDCService{
+		userRepo:             userRepo,
+		identityProviderRepo: identityProviderRepo,
+		registrationService:  registrationService,
+		tokenService:         tokenService,
+		rbacService:          rbacService,
+		client:               newOIDCClient(),
+	}
+}
+
+func (s *OIDCService) GetIdentityProvider(tenantID uint) (frameworkdto.IdentityProviderDTO, error) {
+	provider, err := s.getProvider(tenantID)
+	if err != nil {
+		return frameworkdto.IdentityProviderDTO{}, err
+	}
+
+	return frameworkdto.IdentityProviderDTO{
+		Issuer:               provider.Issuer,
+		ClientID:             provider.ClientID,
+		RedirectURL:          provider.RedirectURL,
+		Scopes:               provider.Scopes,
+		DefaultRole:          provider.DefaultRole,
+		AllowJITProvisioning: provider.AllowJITProvisioning,
+		IsEnabled:            provider.IsEnabled,
+	}, nil
+}
+
+// UpdateIdentityProvider saves the tenant's provider. Users provisioned on first sign-in get
+// the default role, so the caller must be allowed to assign it.
+func (s *OIDCService) UpdateIdentityProvider(tokenDto frameworkdto.TokenDTO, providerDTO frameworkdto.UpdateIdentityProviderDTO) error {
+	if err := validateOIDCIssuer(providerDTO.Issuer); err != nil {
+		return err
+	}
+
+	canAssign, err := s.rbacService.CanAssignRole(tokenDto, provisionedRole(providerDTO.DefaultRole))
+	if err != nil {
+		return err
+	}
+	if !canAssign {
+		return frameworkconstants.ErrPermissionNotHeld
+	}
+
+	provider, err := s.identityProviderRepo.GetByTenantID(tokenDto.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		provider = &entities.TenantIdentityProvider{TenantID: tokenDto.TenantID}
+	} else if err != nil {
+		return err
+	}
+
+	provider.Issuer = providerDTO.Issuer
+	provider.ClientID = providerDTO.ClientID
+	provider.RedirectURL = providerDTO.RedirectURL
+	provider.Scopes = providerDTO.Scopes
+	provider.DefaultRole = providerDTO.DefaultRole
+	provider.AllowJITProvisioning = providerDTO.AllowJITProvisioning
+	provider.IsEnabled = providerDTO.IsEnabled
+	if providerDTO.ClientSecret != "" {
+		provider.ClientSecret = providerDTO.ClientSecret
+	}
+
+	return s.identityProviderRepo.Save(provider)
+}
+
+// Authorize starts an authorization code flow with PKCE and returns the URL to send the user to
+func (s *OIDCService) Authorize(tenantID uint) (frameworkdto.OIDCAuthorizeResponseDTO, error) {
+	provider, err := s.getProvider(tenantID)
+	if err != nil {
+		return frameworkdto.OIDCAuthorizeResponseDTO{}, err
+	}
+	if !provider.IsEnabled {
+		return frameworkdto.OIDCAuthorizeResponseDTO{}, frameworkconstants.ErrIdentityProviderNotFound
+	}
+
+	// Fetch failures describe the provider's network, which is no business of the caller
+	metadata, err := s.client.metadata(provider.Issuer, false)
+	if err != nil {
+		return frameworkdto.OIDCAuthorizeResponseDTO{}, frameworkconstants.ErrOIDCAuthenticationFailed
+	}
+
+	state, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.OIDCAuthorizeResponseDTO{}, err
+	}
+	nonce, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.OIDCAuthorizeResponseDTO{}, err
+	}
+	codeVerifier, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.OIDCAuthorizeResponseDTO{}, err
+	}
+
+	if err := s.identityProviderRepo.CreateLoginState(&entities.OIDCLoginState{
+		TenantID:     tenantID,
+		StateHash:    frameworkutils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(frameworkconstants.OIDCLoginStateTTL),
+	}); err != nil {
+		return frameworkdto.OIDCAuthorizeResponseDTO{}, err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	scopes := provider.Scopes
+	if scopes == "" {
+		scopes = defaultOIDCScopes
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", provider.ClientID)
+	params.Set("redirect_uri", provider.RedirectURL)
+	params.Set("scope", scopes)
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return frameworkdto.OIDCAuthorizeResponseDTO{
+		AuthorizationURL: metadata.discovery.AuthorizationEndpoint + separator + params.Encode(),
+		State:            state,
+	}, nil
+}
+
+// Callback redeems the authorization code, validates the ID token and signs the user in,
+// linking or provisioning the framework user as needed
+func (s *OIDCService) Callback(callbackRequest frameworkdto.OIDCCallbackRequestDTO, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	loginState, err := s.identityProviderRepo.GetLoginStateByHash(frameworkutils.HashToken(callbackRequest.State))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidOIDCState
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if loginState.UsedAt != nil || loginState.ExpiresAt.Before(time.Now()) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidOIDCState
+	}
+
+	consumed, err := s.identityProviderRepo.ConsumeLoginState(loginState.ID)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !consumed {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidOIDCState
+	}
+
+	provider, err := s.getProvider(loginState.TenantID)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !provider.IsEnabled {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrIdentityProviderNotFound
+	}
+
+	metadata, err := s.client.metadata(provider.Issuer, false)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrOIDCAuthenticationFailed
+	}
+
+	rawIDToken, err := s.client.exchangeCode(metadata, provider.ClientID, provider.ClientSecret, provider.RedirectURL, callbackRequest.Code, loginState.CodeVerifier)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrOIDCAuthenticationFailed
+	}
+
+	claims, err := s.client.validateIDToken(provider.Issuer, provider.ClientID, loginState.Nonce, rawIDToken)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrOIDCAuthenticationFailed
+	}
+
+	user, err := s.resolveUser(provider, claims)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if !user.IsActive {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserAccountInactive
+	}
+
+	if claims.GivenName != "" {
+		user.FirstName = claims.GivenName
+	}
+	if claims.FamilyName != "" {
+		user.LastName = claims.FamilyName
+	}
+	now := time.Now()
+	user.LastLoginAt = &now
+	user.LastLoginIP = ipAddress
+	if err := s.userRepo.Update(user); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return s.tokenService.IssueTokens(user, []string{frameworkconstants.AuthMethodOIDC}, ipAddress, userAgent)
+}
+
+func (s *OIDCService) resolveUser(provider *entities.TenantIdentityProvider, claims *oidcIDTokenClaims) (*entities.User, error) {
+	identity, err := s.identityProviderRepo.GetExternalIdentity(provider.TenantID, provider.Issuer, claims.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(identity.UserID, identity.TenantID)
+		if err != nil && err == gorm.ErrRecordNotFound {
+			return nil, frameworkconstants.ErrUserNotFound
+		}
+		return user, err
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	// First sign in through this provider: match on an email address the provider says it has
+	// verified. Providers that leave out email_verified are not trusted to have checked it.
+	if claims.Email == "" || claims.EmailVerified == nil || !*claims.EmailVerified {
+		return nil, frameworkconstants.ErrOIDCEmailNotVerified
+	}
+
+	// Emails are unique within a tenant, so only the provider's tenant is searched
+	user, err := s.userRepo.GetByEmailAndTenant(claims.Email, provider.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		if !provider.AllowJITProvisioning {
+			return nil, frameworkconstants.ErrOIDCUserNotProvisioned
+		}
+
+		user, err = s.registrationService.ProvisionUser(provider.TenantID, claims.Email, claims.GivenName, claims.FamilyName, provisionedRole(provider.DefaultRole))
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := s.identityProviderRepo.CreateExternalIdentity(&entities.ExternalIdentity{
+		UserID:   user.ID,
+		TenantID: user.TenantID,
+		Issuer:   provider.Issuer,
+		Subject:  claims.Subject,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provisionedRole returns the role users provisioned on first sign-in get
+func provisionedRole(defaultRole string) string {
+	if defaultRole == "" {
+		return string(frameworkconstants.UserRoleTenantUser)
+	}
+	return defaultRole
+}
+
+func (s *OIDCService) getProvider(tenantID uint) (*entities.TenantIdentityProvider, error) {
+	provider, err := s.identityProviderRepo.GetByTenantID(tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return nil, frameworkconstants.ErrIdentityProviderNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
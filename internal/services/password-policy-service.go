@@ -0,0 +1,99 @@
+package services
+
+import (
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+type PasswordPolicyService struct {
+	cfg                *frameworkdto.FrameworkConfig
+	passwordPolicyRepo *repositories.PasswordPolicyRepository
+}
+
+func NewPasswordPolicyService(cfg *frameworkdto.FrameworkConfig, passwordPolicyRepo *repositories.PasswordPolicyRepository) *PasswordPolicyService {
+	return &PasswordPolicyService{cfg: cfg, passwordPolicyRepo: passwordPolicyRepo}
+}
+
+// GetPasswordPolicy returns the policy in force for the tenant: its own policy when it has one,
+// otherwise the framework-wide policy. Tenant ID 0 always gets the framework-wide policy.
+func (s *PasswordPolicyService) GetPasswordPolicy(tenantID uint) (frameworkdto.PasswordPolicyDTO, error) {
+	if tenantID != 0 {
+		policy, err := s.passwordPolicyRepo.GetByTenantID(tenantID)
+		if err == nil {
+			return frameworkutils.NormalizePasswordPolicy(frameworkdto.PasswordPolicyDTO{
+				MinLength:               policy.MinLength,
+				MaxLength:               policy.MaxLength,
+				RequireUppercase:        policy.RequireUppercase,
+				RequireLowercase:        policy.RequireLowercase,
+				RequireDigit:            policy.RequireDigit,
+				RequireSymbol:           policy.RequireSymbol,
+				DisallowCommonPasswords: policy.DisallowCommonPasswords,
+				DisallowUserInfo:        policy.DisallowUserInfo,
+				PasswordHistoryCount:    policy.PasswordHistoryCount,
+				MaxPasswordAgeDays:      policy.MaxPasswordAgeDays,
+			}), nil
+		} else if err != gorm.ErrRecordNotFound {
+			return frameworkdto.PasswordPolicyDTO{}, err
+		}
+	}
+
+	if s.cfg.PasswordPolicy != nil {
+		return frameworkutils.NormalizePasswordPolicy(*s.cfg.PasswordPolicy), nil
+	}
+
+	return frameworkutils.DefaultPasswordPolicy(), nil
+}
+
+// ValidatePassword checks a password for a user of the tenant, returning a
+// *frameworkutils.PasswordPolicyError listing every broken rule
+func (s *PasswordPolicyService) ValidatePassword(tenantID uint, password, email, firstName, lastName string) error {
+	policy, err := s.GetPasswordPolicy(tenantID)
+	if err != nil {
+		return err
+	}
+
+	return frameworkutils.ValidatePassword(policy, password, email, firstName, lastName)
+}
+
+func (s *PasswordPolicyService) UpdateTenantPasswordPolicy(tenantID uint, policyDTO frameworkdto.PasswordPolicyDTO) error {
+	if policyDTO.MaxLength > frameworkconstants.MaxPasswordBytes ||
+		policyDTO.PasswordHistoryCount < 0 ||
+		policyDTO.PasswordHistoryCount > frameworkconstants.MaxPasswordHistoryCount ||
+		policyDTO.MaxPasswordAgeDays < 0 {
+		return frameworkconstants.ErrInvalidPasswordPolicy
+	}
+
+	policyDTO = frameworkutils.NormalizePasswordPolicy(policyDTO)
+	if policyDTO.MinLength > policyDTO.MaxLength {
+		return frameworkconstants.ErrInvalidPasswordPolicy
+	}
+
+	policy, err := s.passwordPolicyRepo.GetByTenantID(tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		policy = &entities.TenantPasswordPolicy{TenantID: tenantID}
+	} else if err != nil {
+		return err
+	}
+
+	policy.MinLength = policyDTO.MinLength
+	policy.MaxLength = policyDTO.MaxLength
+	policy.RequireUppercase = policyDTO.RequireUppercase
+	policy.RequireLowercase = policyDTO.RequireLowercase
+	policy.RequireDigit = policyDTO.RequireDigit
+	policy.RequireSymbol = policyDTO.RequireSymbol
+	policy.DisallowCommonPasswords = policyDTO.DisallowCommonPasswords
+	policy.DisallowUserInfo = policyDTO.DisallowUserInfo
+	policy.PasswordHistoryCount = policyDTO.PasswordHistoryCount
+	policy.MaxPasswordAgeDays = policyDTO.MaxPasswordAgeDays
+
+	return s.passwordPolicyRepo.Save(policy)
+}
+
+// ResetTenantPasswordPolicy removes the tenant's own policy so the framework-wide policy applies again
+func (s *PasswordPolicyService) ResetTenantPasswordPolicy(tenantID uint) error {
+	return s.passwordPolicyRepo.DeleteByTenantID(tenantID)
+}
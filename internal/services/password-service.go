@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// PasswordService changes passwords, enforcing the tenant's policy, history and maximum age
+type PasswordService struct {
+	passwordHasher         frameworkutils.PasswordHasher
+	userRepo               *repositories.UserRepository
+	passwordRepo           *repositories.PasswordRepository
+	passwordPolicyService  *PasswordPolicyService
+	tokenService           *TokenService
+	tokenRevocationService *TokenRevocationService
+	lockoutService         *LockoutService
+	ldapService            *LDAPService
+	passwordAuthenticator  Authenticator
+}
+
+func NewPasswordService(
+	passwordHasher frameworkutils.PasswordHasher,
+	userRepo *repositories.UserRepository,
+	passwordRepo *repositories.PasswordRepository,
+	passwordPolicyService *PasswordPolicyService,
+	tokenService *TokenService,
+	tokenRevocationService *TokenRevocationService,
+	lockoutService *LockoutService,
+	ldapService *LDAPService) *PasswordService {
+	return &PasswordService{
+		passwordHasher:         passwordHasher,
+		userRepo:               userRepo,
+		passwordRepo:           passwordRepo,
+		passwordPolicyService:  passwordPolicyService,
+		tokenService:           tokenService,
+		tokenRevocationService: tokenRevocationService,
+		lockoutService:         lockoutService,
+		ldapService:            ldapService,
+		passwordAuthenticator:  &passwordAuthenticator{passwordHasher: passwordHasher},
+	}
+}
+
+// Authenticator returns the tenant's directory authenticator when it has one, and otherwise
+// checks the framework password
+func (s *PasswordService) Authenticator(tenantID uint) (Authenticator, error) {
+	authenticator, err := s.ldapService.Authenticator(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if authenticator == nil {
+		return s.passwordAuthenticator, nil
+	}
+	return authenticator, nil
+}
+
+// SetPassword replaces the user's password after checking it against the tenant's policy and
+// recent passwords, and records the old hash in the password history. The user is saved by
+// the caller.
+func (s *PasswordService) SetPassword(user *entities.User, password string) error {
+	policy, err := s.passwordPolicyService.GetPasswordPolicy(user.TenantID)
+	if err != nil {
+		return err
+	}
+
+	if err := frameworkutils.ValidatePassword(policy, password, user.Email, user.FirstName, user.LastName); err != nil {
+		return err
+	}
+
+	if err := s.checkPasswordHistory(user, password, policy.PasswordHistoryCount); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		return frameworkconstants.ErrFailedToHashPassword
+	}
+
+	if user.PasswordHash != "" {
+		if err := s.passwordRepo.CreateHistory(&entities.PasswordHistory{UserID: user.ID, PasswordHash: user.PasswordHash}); err != nil {
+			return err
+		}
+	}
+	// The current password counts towards the history, so only the previous count-1 are kept
+	keep := policy.PasswordHistoryCount - 1
+	if keep < 0 {
+		keep = 0
+	}
+	if err := s.passwordRepo.PruneHistory(user.ID, keep); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.PasswordHash = passwordHash
+	user.PasswordChangedAt = &now
+	clearLockout(user)
+
+	return nil
+}
+
+// ChangePassword lets a signed-in user replace their password. The current password is checked
+// the way logins check it, and wrong ones count towards the account lockout. Every session
+// other than currentSessionID is signed out.
+func (s *PasswordService) ChangePassword(tenantID uint, userID uint, currentSessionID string, changeRequest frameworkdto.ChangePasswordDTO) error {
+	user, err := s.userRepo.GetByID(userID, tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		return frameworkconstants.ErrAccountLocked
+	}
+
+	authenticator, err := s.Authenticator(user.TenantID)
+	if err != nil {
+		return err
+	}
+	if err := authenticator.Authenticate(user, changeRequest.CurrentPassword); err != nil {
+		if err != frameworkconstants.ErrInvalidPassword {
+			return err
+		}
+		return s.lockoutService.RecordFailedLogin(user, now)
+	}
+
+	if err := s.SetPassword(user, changeRequest.NewPassword); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if currentSessionID == "" {
+		return s.tokenRevocationService.RevokeAllForUser(user)
+	}
+	return s.tokenRevocationService.RevokeOtherSessions(user.ID, currentSessionID)
+}
+
+// PasswordExpired reports whether the user's password is older than the tenant's maximum
+// password age. Users without a password change date are measured from account creation.
+// Passwords of tenants that log in against a directory are managed there and never expire here.
+func (s *PasswordService) PasswordExpired(user *entities.User) (bool, error) {
+	if user.PasswordHash == "" {
+		return false, nil
+	}
+
+	directoryAuthenticator, err := s.ldapService.Authenticator(user.TenantID)
+	if err != nil {
+		return false, err
+	}
+	if directoryAuthenticator != nil {
+		return false, nil
+	}
+
+	policy, err := s.passwordPolicyService.GetPasswordPolicy(user.TenantID)
+	if err != nil {
+		return false, err
+	}
+	if policy.MaxPasswordAgeDays == 0 {
+		return false, nil
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+
+	return time.Since(changedAt) >= time.Duration(policy.MaxPasswordAgeDays)*24*time.Hour, nil
+}
+
+// CreateChangeChallenge pauses a login whose password has expired. The returned token can
+// only be exchanged for a new password. authMethods are the factors already completed.
+func (s *PasswordService) CreateChangeChallenge(user *entities.User, authMethods []string, ipAddress string) (frameworkdto.LoginResponseDTO, error) {
+	changeToken, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if err := s.passwordRepo.CreateChangeChallenge(&entities.PasswordChangeChallenge{
+		UserID:      user.ID,
+		TenantID:    user.TenantID,
+		TokenHash:   frameworkutils.HashToken(changeToken),
+		IPAddress:   ipAddress,
+		AuthMethods: strings.Join(authMethods, " "),
+		ExpiresAt:   time.Now().Add(frameworkconstants.PasswordChangeChallengeTTL),
+	}); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return frameworkdto.LoginResponseDTO{
+		PasswordChangeRequired: true,
+		PasswordChangeToken:    changeToken,
+	}, nil
+}
+
+// CompleteChangeChallenge sets the new password for a paused login and finishes it. Tokens
+// issued before the change are revoked.
+func (s *PasswordService) CompleteChangeChallenge(changeRequest frameworkdto.PasswordChangeRequestDTO, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	challenge, err := s.passwordRepo.GetChangeChallengeByTokenHash(frameworkutils.HashToken(changeRequest.PasswordChangeToken))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidPasswordChange
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if challenge.UsedAt != nil || challenge.ExpiresAt.Before(time.Now()) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidPasswordChange
+	}
+
+	user, err := s.userRepo.GetByID(challenge.UserID, challenge.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !user.IsActive {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserAccountInactive
+	}
+
+	if err := s.SetPassword(user, changeRequest.NewPassword); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	// Consumed only once the new password is accepted, so a rejected password can be retried
+	consumed, err := s.passwordRepo.ConsumeChangeChallenge(challenge.ID)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !consumed {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidPasswordChange
+	}
+
+	// RevokeAllForUser also saves the new password
+	if err := s.tokenRevocationService.RevokeAllForUser(user); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return s.tokenService.IssueTokens(user, strings.Fields(challenge.AuthMethods), ipAddress, userAgent)
+}
+
+func (s *PasswordService) checkPasswordHistory(user *entities.User, password string, historyCount int) error {
+	if historyCount == 0 || user.PasswordHash == "" {
+		return nil
+	}
+
+	previousHashes := []string{user.PasswordHash}
+	if historyCount > 1 {
+		history, err := s.passwordRepo.GetRecentHistory(user.ID, historyCount-1)
+		if err != nil {
+			return err
+		}
+		for _, entry := range history {
+			previousHashes = append(previousHashes, entry.PasswordHash)
+		}
+	}
+
+	for _, previousHash := range previousHashes {
+		if frameworkutils.VerifyPasswordHash(password, previousHash) == nil {
+			return &frameworkutils.PasswordPolicyError{Violations: []frameworkdto.PasswordPolicyViolationDTO{{
+				Rule:    "password_history",
+				Message: fmt.Sprintf("must not match any of your last %d passwords", historyCount),
+			}}}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,733 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxPolicyConditionLength = 2000
+	maxPolicyConditionDepth  = 32
+)
+
+// policyRoots are the attribute sets a policy condition can refer to
+var policyRoots = map[string]bool{"subject": true, "request": true, "resource": true}
+
+// policyEnv is what a policy condition is evaluated against. Attribute values are nil, bool,
+// float64, string, []any or map[string]any, as produced by decoding JSON.
+type policyEnv struct {
+	attributes map[string]any
+	now        time.Time
+}
+
+// policyExpr is a parsed policy condition
+type policyExpr interface {
+	eval(env *policyEnv) (any, error)
+}
+
+// parsePolicyCondition parses a condition such as
+//
+//	subject.role == "tenant_user" && resource.owner_id != subject.user_id
+//
+// Conditions combine attribute paths, string, number, boolean, null and list literals with
+// ==, !=, <, <=, >, >=, in, &&, || and !, and the functions in policyFunctions.
+func parsePolicyCondition(condition string) (policyExpr, error) {
+	if strings.TrimSpace(condition) == "" {
+		return nil, errors.New("condition is empty")
+	}
+	if len(condition) > maxPolicyConditionLength {
+		return nil, fmt.Errorf("condition is longer than %d characters", maxPolicyConditionLength)
+	}
+
+	tokens, err := tokenizePolicyCondition(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &policyParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != policyTokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return expr, nil
+}
+
+// evaluatePolicyCondition evaluates a parsed condition, which must produce a boolean
+func evaluatePolicyCondition(expr policyExpr, env *policyEnv) (bool, error) {
+	value, err := expr.eval(env)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition produced %s, not a boolean", policyTypeName(value))
+	}
+	return result, nil
+}
+
+type policyTokenKind int
+
+const (
+	policyTokenEOF policyTokenKind = iota
+	policyTokenIdent
+	policyTokenString
+	policyTokenNumber
+	policyTokenOperator
+)
+
+type policyToken struct {
+	kind policyTokenKind
+	text string
+	pos  int
+}
+
+var policyComparisonOperators = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+var policyOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func tokenizePolicyCondition(condition string) ([]policyToken, error) {
+	var tokens []policyToken
+	for i := 0; i < len(condition); {
+		ch := rune(condition[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '"' || ch == '\'':
+			text, end, err := scanPolicyString(condition, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenString, text: text, pos: i})
+			i = end
+		case unicode.IsDigit(ch) || (ch == '-' && i+1 < len(condition) && unicode.IsDigit(rune(condition[i+1]))):
+			end := i + 1
+			for end < len(condition) && (unicode.IsDigit(rune(condition[end])) || condition[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenNumber, text: condition[i:end], pos: i})
+			i = end
+		case ch == '_' || unicode.IsLetter(ch):
+			end := i + 1
+			for end < len(condition) && (condition[end] == '_' || unicode.IsLetter(rune(condition[end])) || unicode.IsDigit(rune(condition[end]))) {
+				end++
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenIdent, text: condition[i:end], pos: i})
+			i = end
+		default:
+			operator := ""
+			for _, candidate := range policyOperators {
+				if strings.HasPrefix(condition[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", condition[i], i)
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenOperator, text: operator, pos: i})
+			i += len(operator)
+		}
+	}
+	return append(tokens, policyToken{kind: policyTokenEOF, pos: len(condition)}), nil
+}
+
+func scanPolicyString(condition string, start int) (string, int, error) {
+	quote := condition[start]
+	var text strings.Builder
+	for i := start + 1; i < len(condition); i++ {
+		switch condition[i] {
+		case quote:
+			return text.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(condition) {
+				return "", 0, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			switch condition[i] {
+			case 'n':
+				text.WriteByte('\n')
+			case 't':
+				text.WriteByte('\t')
+			default:
+				text.WriteByte(condition[i])
+			}
+		default:
+			text.WriteByte(condition[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at position %d", start)
+}
+
+type policyParser struct {
+	tokens []policyToken
+	pos    int
+	depth  int
+}
+
+func (p *policyParser) peek() policyToken {
+	return p.tokens[p.pos]
+}
+
+func (p *policyParser) next() policyToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != policyTokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *policyParser) acceptOperator(operator string) bool {
+	if tok := p.peek(); tok.kind == policyTokenOperator && tok.text == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *policyParser) expectOperator(operator string) error {
+	if !p.acceptOperator(operator) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d", operator, tok.pos)
+	}
+	return nil
+}
+
+func (p *policyParser) parseOr() (policyExpr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxPolicyConditionDepth {
+		return nil, errors.New("condition is nested too deeply")
+	}
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &policyLogical{operator: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *policyParser) parseAnd() (policyExpr, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOperator("&&") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &policyLogical{operator: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *policyParser) parseComparison() (policyExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	operator := ""
+	switch {
+	case tok.kind == policyTokenOperator && policyComparisonOperators[tok.text]:
+		operator = tok.text
+	case tok.kind == policyTokenIdent && tok.text == "in":
+		operator = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &policyComparison{operator: operator, left: left, right: right}, nil
+}
+
+func (p *policyParser) parseUnary() (policyExpr, error) {
+	if p.acceptOperator("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &policyNot{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *policyParser) parsePrimary() (policyExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case policyTokenString:
+		return &policyLiteral{value: tok.text}, nil
+	case policyTokenNumber:
+		number, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return &policyLiteral{value: number}, nil
+	case policyTokenIdent:
+		return p.parseIdent(tok)
+	case policyTokenOperator:
+		switch tok.text {
+		case "(":
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expectOperator(")")
+		case "[":
+			list := &policyList{}
+			if p.acceptOperator("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.acceptOperator("]") {
+					return list, nil
+				}
+				if err := p.expectOperator(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case policyTokenEOF:
+		return nil, errors.New("unexpected end of condition")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *policyParser) parseIdent(tok policyToken) (policyExpr, error) {
+	switch tok.text {
+	case "true":
+		return &policyLiteral{value: true}, nil
+	case "false":
+		return &policyLiteral{value: false}, nil
+	case "null":
+		return &policyLiteral{value: nil}, nil
+	}
+
+	if p.acceptOperator("(") {
+		function, ok := policyFunctions[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q at position %d", tok.text, tok.pos)
+		}
+		call := &policyCall{name: tok.text, function: function}
+		if !p.acceptOperator(")") {
+			for {
+				arg, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, arg)
+				if p.acceptOperator(")") {
+					break
+				}
+				if err := p.expectOperator(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(call.args) < function.minArgs || (function.maxArgs >= 0 && len(call.args) > function.maxArgs) {
+			return nil, fmt.Errorf("wrong number of arguments to %s at position %d", tok.text, tok.pos)
+		}
+		return call, nil
+	}
+
+	if !policyRoots[tok.text] {
+		return nil, fmt.Errorf("unknown attribute %q at position %d; attributes start with subject, request or resource", tok.text, tok.pos)
+	}
+	path := &policyPath{path: []string{tok.text}}
+	for p.acceptOperator(".") {
+		field := p.next()
+		if field.kind != policyTokenIdent {
+			return nil, fmt.Errorf("expected an attribute name at position %d", field.pos)
+		}
+		path.path = append(path.path, field.text)
+	}
+	return path, nil
+}
+
+type policyLiteral struct {
+	value any
+}
+
+func (e *policyLiteral) eval(env *policyEnv) (any, error) {
+	return e.value, nil
+}
+
+type policyList struct {
+	items []policyExpr
+}
+
+func (e *policyList) eval(env *policyEnv) (any, error) {
+	values := make([]any, 0, len(e.items))
+	for _, item := range e.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// policyPath reads an attribute. Missing attributes are null.
+type policyPath struct {
+	path []string
+}
+
+func (e *policyPath) eval(env *policyEnv) (any, error) {
+	var value any = env.attributes
+	for i, field := range e.path {
+		switch current := value.(type) {
+		case nil:
+			return nil, nil
+		case map[string]any:
+			value = current[field]
+		default:
+			return nil, fmt.Errorf("%s is %s and has no attribute %s", strings.Join(e.path[:i], "."), policyTypeName(current), field)
+		}
+	}
+	return value, nil
+}
+
+type policyNot struct {
+	operand policyExpr
+}
+
+func (e *policyNot) eval(env *policyEnv) (any, error) {
+	value, err := e.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("! needs a boolean, not %s", policyTypeName(value))
+	}
+	return !result, nil
+}
+
+type policyLogical struct {
+	operator    string
+	left, right policyExpr
+}
+
+func (e *policyLogical) eval(env *policyEnv) (any, error) {
+	left, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	leftResult, ok := left.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s needs booleans, not %s", e.operator, policyTypeName(left))
+	}
+	if (e.operator == "&&" && !leftResult) || (e.operator == "||" && leftResult) {
+		return leftResult, nil
+	}
+
+	right, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	rightResult, ok := right.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%s needs booleans, not %s", e.operator, policyTypeName(right))
+	}
+	return rightResult, nil
+}
+
+type policyComparison struct {
+	operator    string
+	left, right policyExpr
+}
+
+func (e *policyComparison) eval(env *policyEnv) (any, error) {
+	left, err := e.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.operator {
+	case "==":
+		return policyValuesEqual(left, right), nil
+	case "!=":
+		return !policyValuesEqual(left, right), nil
+	case "in":
+		return policyContains(right, left)
+	}
+
+	var comparison int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %s", policyTypeName(right))
+		}
+		comparison = compareOrdered(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %s", policyTypeName(right))
+		}
+		comparison = compareOrdered(l, r)
+	default:
+		return nil, fmt.Errorf("%s cannot compare %s", e.operator, policyTypeName(left))
+	}
+
+	switch e.operator {
+	case "<":
+		return comparison < 0, nil
+	case "<=":
+		return comparison <= 0, nil
+	case ">":
+		return comparison > 0, nil
+	default:
+		return comparison >= 0, nil
+	}
+}
+
+func compareOrdered[T float64 | string](left, right T) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type policyFunction struct {
+	minArgs, maxArgs int
+	call             func(env *policyEnv, args []any) (any, error)
+}
+
+type policyCall struct {
+	name     string
+	function policyFunction
+	args     []policyExpr
+}
+
+func (e *policyCall) eval(env *policyEnv) (any, error) {
+	args := make([]any, 0, len(e.args))
+	for _, arg := range e.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	result, err := e.function.call(env, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.name, err)
+	}
+	return result, nil
+}
+
+// policyFunctions are the functions policy conditions can call. A maxArgs of -1 means any number.
+var policyFunctions = map[string]policyFunction{
+	// ip_in(ip, cidr_or_ip, ...) reports whether the IP address is in any of the networks,
+	// given as strings or lists of strings
+	"ip_in": {minArgs: 2, maxArgs: -1, call: func(env *policyEnv, args []any) (any, error) {
+		address, _ := args[0].(string)
+		ip := net.ParseIP(address)
+		var networks []any
+		for _, arg := range args[1:] {
+			if list, ok := arg.([]any); ok {
+				networks = append(networks, list...)
+			} else {
+				networks = append(networks, arg)
+			}
+		}
+
+		found := false
+		for _, network := range networks {
+			text, ok := network.(string)
+			if !ok {
+				return nil, fmt.Errorf("networks must be strings, not %s", policyTypeName(network))
+			}
+			if !strings.Contains(text, "/") {
+				allowed := net.ParseIP(text)
+				if allowed == nil {
+					return nil, fmt.Errorf("invalid ip address %q", text)
+				}
+				found = found || (ip != nil && allowed.Equal(ip))
+				continue
+			}
+			_, ipNet, err := net.ParseCIDR(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q", text)
+			}
+			found = found || (ip != nil && ipNet.Contains(ip))
+		}
+		return found, nil
+	}},
+	// hour(time_zone) is the hour of the request, 0-23, in the IANA time zone (UTC if omitted)
+	"hour": {minArgs: 0, maxArgs: 1, call: func(env *policyEnv, args []any) (any, error) {
+		now, err := policyLocalTime(env, args)
+		if err != nil {
+			return nil, err
+		}
+		return float64(now.Hour()), nil
+	}},
+	// weekday(time_zone) is the lower-case English day of the request, such as "monday"
+	"weekday": {minArgs: 0, maxArgs: 1, call: func(env *policyEnv, args []any) (any, error) {
+		now, err := policyLocalTime(env, args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToLower(now.Weekday().String()), nil
+	}},
+	"starts_with": {minArgs: 2, maxArgs: 2, call: func(env *policyEnv, args []any) (any, error) {
+		text, prefix, err := policyStringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasPrefix(text, prefix), nil
+	}},
+	"ends_with": {minArgs: 2, maxArgs: 2, call: func(env *policyEnv, args []any) (any, error) {
+		text, suffix, err := policyStringArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return strings.HasSuffix(text, suffix), nil
+	}},
+	// contains(collection, value) is the same as value in collection
+	"contains": {minArgs: 2, maxArgs: 2, call: func(env *policyEnv, args []any) (any, error) {
+		return policyContains(args[0], args[1])
+	}},
+	"lower": {minArgs: 1, maxArgs: 1, call: func(env *policyEnv, args []any) (any, error) {
+		text, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("needs a string, not %s", policyTypeName(args[0]))
+		}
+		return strings.ToLower(text), nil
+	}},
+	"len": {minArgs: 1, maxArgs: 1, call: func(env *policyEnv, args []any) (any, error) {
+		switch value := args[0].(type) {
+		case nil:
+			return float64(0), nil
+		case string:
+			return float64(len(value)), nil
+		case []any:
+			return float64(len(value)), nil
+		case map[string]any:
+			return float64(len(value)), nil
+		default:
+			return nil, fmt.Errorf("%s has no length", policyTypeName(value))
+		}
+	}},
+}
+
+func policyLocalTime(env *policyEnv, args []any) (time.Time, error) {
+	if len(args) == 0 {
+		return env.now.UTC(), nil
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("time zone must be a string, not %s", policyTypeName(args[0]))
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown time zone %q", name)
+	}
+	return env.now.In(location), nil
+}
+
+// policyStringArgs returns two string arguments, treating null as an empty string
+func policyStringArgs(args []any) (string, string, error) {
+	strs := make([]string, 2)
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+		text, ok := arg.(string)
+		if !ok {
+			return "", "", fmt.Errorf("needs strings, not %s", policyTypeName(arg))
+		}
+		strs[i] = text
+	}
+	return strs[0], strs[1], nil
+}
+
+// policyContains reports whether a list holds the value, a string contains the substring, or
+// an object has the key. Nothing is in null.
+func policyContains(collection, value any) (any, error) {
+	switch c := collection.(type) {
+	case nil:
+		return false, nil
+	case []any:
+		for _, item := range c {
+			if policyValuesEqual(item, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot look for %s in a string", policyTypeName(value))
+		}
+		return strings.Contains(c, text), nil
+	case map[string]any:
+		key, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot look for %s in an object", policyTypeName(value))
+		}
+		_, found := c[key]
+		return found, nil
+	default:
+		return nil, fmt.Errorf("cannot look inside %s", policyTypeName(collection))
+	}
+}
+
+func policyValuesEqual(left, right any) bool {
+	return reflect.DeepEqual(left, right)
+}
+
+func policyTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePolicyCondition(t *testing.T) {
+	tests := []struct {
+		condition string
+		wantErr   string
+	}{
+		{condition: `subject.role == "tenant_user" && resource.owner_id != subject.user_id`},
+		{condition: `!(request.method in ["GET", 'HEAD']) || subject.mfa == true`},
+		{condition: `ip_in(request.ip, "10.0.0.0/8", ["192.168.1.1"]) && hour("Europe/London") >= 9`},
+		{condition: `len(subject.groups) > -1.5 && resource.deleted_at == null`},
+		{condition: `contains(subject.groups, "admins")`},
+		{condition: "", wantErr: "condition is empty"},
+		{condition: "   ", wantErr: "condition is empty"},
+		{condition: strings.Repeat("a", maxPolicyConditionLength+1), wantErr: "longer than"},
+		{condition: strings.Repeat("(", maxPolicyConditionDepth+1) + "true" + strings.Repeat(")", maxPolicyConditionDepth+1), wantErr: "nested too deeply"},
+		{condition: `subject.role == "admin`, wantErr: "unterminated string at position 16"},
+		{condition: `subject.role = "admin"`, wantErr: `unexpected '=' at position 13`},
+		{condition: `subject.role ==`, wantErr: "unexpected end of condition"},
+		{condition: `subject.role == "admin" true`, wantErr: `unexpected "true" at position 24`},
+		{condition: `(subject.mfa`, wantErr: `expected ")" at position 12`},
+		{condition: `["a" "b"]`, wantErr: `expected "," at position 5`},
+		{condition: `role == "admin"`, wantErr: `unknown attribute "role"`},
+		{condition: `subject.`, wantErr: "expected an attribute name at position 8"},
+		{condition: `exec("rm")`, wantErr: `unknown function "exec"`},
+		{condition: `lower()`, wantErr: "wrong number of arguments to lower"},
+		{condition: `starts_with(subject.email, "a", "b")`, wantErr: "wrong number of arguments to starts_with"},
+		{condition: `1.2.3 == 1`, wantErr: `invalid number "1.2.3"`},
+	}
+
+	for _, tt := range tests {
+		_, err := parsePolicyCondition(tt.condition)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("parsePolicyCondition(%q) error = %v", tt.condition, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("parsePolicyCondition(%q) error = %v, want %q", tt.condition, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEvaluatePolicyCondition(t *testing.T) {
+	env := &policyEnv{
+		attributes: map[string]any{
+			"subject": map[string]any{
+				"user_id": float64(7),
+				"role":    "tenant_user",
+				"email":   "Ada@Acme.com",
+				"groups":  []any{"admins", "billing"},
+			},
+			"request":  map[string]any{"ip": "10.1.2.3", "method": "GET"},
+			"resource": map[string]any{"owner_id": float64(7), "name": "report"},
+		},
+		// A Monday, 09:30 in London
+		now: time.Date(2024, time.June, 3, 8, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+		wantErr   string
+	}{
+		{condition: `subject.role == "tenant_user" && resource.owner_id == subject.user_id`, want: true},
+		{condition: `subject.role == "tenant_admin" || resource.owner_id != subject.user_id`, want: false},
+		{condition: `!(subject.user_id > 7) && subject.user_id >= 7 && subject.user_id <= 7 && subject.user_id < 8`, want: true},
+		{condition: `"b" > "a"`, want: true},
+		{condition: `request.method in ["GET", "HEAD"]`, want: true},
+		{condition: `"auditors" in subject.groups`, want: false},
+		{condition: `contains(subject.groups, "billing")`, want: true},
+		{condition: `resource.missing == null && subject.missing.deeper == null`, want: true},
+		{condition: `ip_in(request.ip, "10.0.0.0/8")`, want: true},
+		{condition: `ip_in(request.ip, ["192.168.0.0/16", "10.1.2.3"])`, want: true},
+		{condition: `ip_in(request.ip, "192.168.0.0/16", "10.1.2.4")`, want: false},
+		{condition: `hour() == 8 && hour("Europe/London") == 9`, want: true},
+		{condition: `weekday("Europe/London") == "monday"`, want: true},
+		{condition: `starts_with(lower(subject.email), "ada@") && ends_with(subject.email, ".com")`, want: true},
+		{condition: `len(subject.groups) == 2 && len(resource.name) == 6 && len(resource.missing) == 0`, want: true},
+		// The right side of a decided && or || is not evaluated
+		{condition: `false && subject.role > 1`, want: false},
+		{condition: `true || subject.role > 1`, want: true},
+		{condition: `subject.role`, wantErr: "not a boolean"},
+		{condition: `subject.role > 1`, wantErr: "cannot compare string with a number"},
+		{condition: `subject.groups > 1`, wantErr: "cannot compare"},
+		{condition: `!subject.role`, wantErr: "! needs a boolean"},
+		{condition: `subject.role && true`, wantErr: "&& needs booleans"},
+		{condition: `subject.role.name == "x"`, wantErr: "subject.role is"},
+		{condition: `ip_in(request.ip, "10.0.0.0/33")`, wantErr: "invalid network"},
+		{condition: `hour("Mars/Olympus_Mons") == 1`, wantErr: "unknown time zone"},
+		{condition: `len(true) == 1`, wantErr: "has no length"},
+	}
+
+	for _, tt := range tests {
+		expr, err := parsePolicyCondition(tt.condition)
+		if err != nil {
+			t.Errorf("parsePolicyCondition(%q) error = %v", tt.condition, err)
+			continue
+		}
+
+		got, err := evaluatePolicyCondition(expr, env)
+		switch {
+		case tt.wantErr != "":
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("evaluatePolicyCondition(%q) error = %v, want %q", tt.condition, err, tt.wantErr)
+			}
+		case err != nil:
+			t.Errorf("evaluatePolicyCondition(%q) error = %v", tt.condition, err)
+		case got != tt.want:
+			t.Errorf("evaluatePolicyCondition(%q) = %v, want %v", tt.condition, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const maxPolicyNameLength = 100
+
+// compiledPolicy is an access policy with its condition parsed
+type compiledPolicy struct {
+	id        uint
+	name      string
+	action    string
+	effect    string
+	condition policyExpr
+	// parseErr is set for stored conditions that no longer parse; such policies fail closed
+	parseErr error
+}
+
+// PolicyService stores tenants' attribute-based access policies and decides actions with them.
+// A matching deny policy always denies. Otherwise, if the tenant has allow policies for the
+// action, one must match; with no policies for the action, the action is allowed and roles
+// alone decide. Parsed policies are cached per tenant for AccessControlCacheTTL, or until they
+// change on this replica.
+type PolicyService struct {
+	policyRepo *repositories.AccessPolicyRepository
+	userRepo   *repositories.UserRepository
+	groupRepo  *repositories.GroupRepository
+
+	mu             sync.RWMutex
+	tenantPolicies map[uint]tenantPolicySet
+}
+
+// tenantPolicySet is a tenant's cached policies
+type tenantPolicySet struct {
+	policies  []compiledPolicy
+	expiresAt time.Time
+}
+
+func NewPolicyService(
+	policyRepo *repositories.AccessPolicyRepository,
+	userRepo *repositories.UserRepository,
+	groupRepo *repositories.GroupRepository) *PolicyService {
+	return &PolicyService{
+		policyRepo:     policyRepo,
+		userRepo:       userRepo,
+		groupRepo:      groupRepo,
+		tenantPolicies: map[uint]tenantPolicySet{},
+	}
+}
+
+func (s *PolicyService) GetAllPolicies(tenantID uint) ([]frameworkdto.PolicyDTO, error) {
+	policies, err := s.policyRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	policyDTOs := make([]frameworkdto.PolicyDTO, 0, len(policies))
+	for i := range policies {
+		policyDTOs = append(policyDTOs, toPolicyDTO(&policies[i]))
+	}
+	return policyDTOs, nil
+}
+
+func (s *PolicyService) CreatePolicy(tenantID uint, createDTO frameworkdto.CreatePolicyDTO) (frameworkdto.PolicyDTO, error) {
+	if _, err := compilePolicy(0, createDTO); err != nil {
+		return frameworkdto.PolicyDTO{}, err
+	}
+
+	policy := &entities.AccessPolicy{TenantID: tenantID}
+	applyPolicyDTO(policy, createDTO)
+	if err := s.policyRepo.Create(policy); err != nil {
+		return frameworkdto.PolicyDTO{}, err
+	}
+	s.invalidate(tenantID)
+
+	return toPolicyDTO(policy), nil
+}
+
+func (s *PolicyService) UpdatePolicy(tenantID uint, updateDTO frameworkdto.UpdatePolicyDTO) (frameworkdto.PolicyDTO, error) {
+	policy, err := s.getPolicy(tenantID, updateDTO.ID)
+	if err != nil {
+		return frameworkdto.PolicyDTO{}, err
+	}
+	if _, err := compilePolicy(policy.ID, updateDTO.CreatePolicyDTO); err != nil {
+		return frameworkdto.PolicyDTO{}, err
+	}
+
+	applyPolicyDTO(policy, updateDTO.CreatePolicyDTO)
+	if err := s.policyRepo.Update(policy); err != nil {
+		return frameworkdto.PolicyDTO{}, err
+	}
+	s.invalidate(tenantID)
+
+	return toPolicyDTO(policy), nil
+}
+
+func (s *PolicyService) DeletePolicy(tenantID, policyID uint) error {
+	policy, err := s.getPolicy(tenantID, policyID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.policyRepo.Delete(policy); err != nil {
+		return err
+	}
+	s.invalidate(tenantID)
+
+	return nil
+}
+
+// Evaluate decides whether the caller may perform the action on the resource. The resource is
+// anything that encodes to a JSON object, such as a struct or a map, and may be nil.
+func (s *PolicyService) Evaluate(tokenDto frameworkdto.TokenDTO, request frameworkdto.PolicyRequestDTO, action string, resource any) (frameworkdto.PolicyDecisionDTO, error) {
+	policies, err := s.getTenantPolicies(tokenDto.TenantID)
+	if err != nil {
+		return frameworkdto.PolicyDecisionDTO{}, err
+	}
+
+	env, err := newPolicyEnv(tokenDto, request, resource)
+	if err != nil {
+		return frameworkdto.PolicyDecisionDTO{}, err
+	}
+
+	return decide(policies, action, env), nil
+}
+
+// Explain decides an action without performing it, for a dry run. The subject is the caller or
+// another user of the caller's tenant, and draft policies can stand in for the stored ones.
+func (s *PolicyService) Explain(tokenDto frameworkdto.TokenDTO, explainDTO frameworkdto.ExplainPolicyDTO, request frameworkdto.PolicyRequestDTO) (frameworkdto.PolicyDecisionDTO, error) {
+	subject := tokenDto
+	if explainDTO.UserID != nil {
+		user, err := s.userRepo.GetByID(*explainDTO.UserID, tokenDto.TenantID)
+		if err == gorm.ErrRecordNotFound {
+			return frameworkdto.PolicyDecisionDTO{}, frameworkconstants.ErrUserNotFound
+		} else if err != nil {
+			return frameworkdto.PolicyDecisionDTO{}, err
+		}
+		groupIDs, err := userGroupIDs(s.groupRepo, user.TenantID, user.ID)
+		if err != nil {
+			return frameworkdto.PolicyDecisionDTO{}, err
+		}
+		subject = frameworkdto.TokenDTO{
+			Sub:           strconv.FormatUint(uint64(user.ID), 10),
+			TenantID:      user.TenantID,
+			Email:         user.Email,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			Role:          user.Role,
+			Groups:        groupIDs,
+			PrincipalType: frameworkconstants.PrincipalTypeUser,
+		}
+	}
+
+	if explainDTO.Request != nil {
+		if explainDTO.Request.Method != "" {
+			request.Method = strings.ToUpper(explainDTO.Request.Method)
+		}
+		if explainDTO.Request.Path != "" {
+			request.Path = explainDTO.Request.Path
+		}
+		if explainDTO.Request.IP != "" {
+			request.IP = explainDTO.Request.IP
+		}
+		if !explainDTO.Request.Time.IsZero() {
+			request.Time = explainDTO.Request.Time
+		}
+	}
+
+	if len(explainDTO.Policies) == 0 {
+		return s.Evaluate(subject, request, explainDTO.Action, explainDTO.Resource)
+	}
+
+	policies := make([]compiledPolicy, 0, len(explainDTO.Policies))
+	for _, draft := range explainDTO.Policies {
+		policy, err := compilePolicy(0, draft)
+		if err != nil {
+			return frameworkdto.PolicyDecisionDTO{}, err
+		}
+		policies = append(policies, policy)
+	}
+
+	env, err := newPolicyEnv(subject, request, explainDTO.Resource)
+	if err != nil {
+		return frameworkdto.PolicyDecisionDTO{}, err
+	}
+	return decide(policies, explainDTO.Action, env), nil
+}
+
+func (s *PolicyService) getPolicy(tenantID, policyID uint) (*entities.AccessPolicy, error) {
+	policy, err := s.policyRepo.GetByID(policyID, tenantID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, frameworkconstants.ErrPolicyNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// getTenantPolicies returns the tenant's enabled policies, parsed
+func (s *PolicyService) getTenantPolicies(tenantID uint) ([]compiledPolicy, error) {
+	s.mu.RLock()
+	cached, ok := s.tenantPolicies[tenantID]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.policies, nil
+	}
+
+	stored, err := s.policyRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]compiledPolicy, 0, len(stored))
+	for _, policy := range stored {
+		if !policy.Enabled {
+			continue
+		}
+		condition, parseErr := parsePolicyCondition(policy.Condition)
+		policies = append(policies, compiledPolicy{
+			id:        policy.ID,
+			name:      policy.Name,
+			action:    policy.Action,
+			effect:    policy.Effect,
+			condition: condition,
+			parseErr:  parseErr,
+		})
+	}
+
+	s.mu.Lock()
+	s.tenantPolicies[tenantID] = tenantPolicySet{policies: policies, expiresAt: time.Now().Add(frameworkconstants.AccessControlCacheTTL)}
+	s.mu.Unlock()
+
+	return policies, nil
+}
+
+func (s *PolicyService) invalidate(tenantID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tenantPolicies, tenantID)
+}
+
+// compilePolicy validates a policy and parses its condition
+func compilePolicy(id uint, policyDTO frameworkdto.CreatePolicyDTO) (compiledPolicy, error) {
+	name := strings.TrimSpace(policyDTO.Name)
+	if name == "" || len(name) > maxPolicyNameLength {
+		return compiledPolicy{}, fmt.Errorf("%w: a name of at most %d characters is required", frameworkconstants.ErrInvalidPolicy, maxPolicyNameLength)
+	}
+
+	action := strings.TrimSpace(policyDTO.Action)
+	if action == "" || strings.ContainsAny(action, " \t\r\n") {
+		return compiledPolicy{}, fmt.Errorf("%w: %s: the action cannot be empty or contain whitespace", frameworkconstants.ErrInvalidPolicy, name)
+	}
+
+	if policyDTO.Effect != frameworkconstants.PolicyEffectAllow && policyDTO.Effect != frameworkconstants.PolicyEffectDeny {
+		return compiledPolicy{}, fmt.Errorf("%w: %s: the effect must be allow or deny", frameworkconstants.ErrInvalidPolicy, name)
+	}
+
+	condition, err := parsePolicyCondition(policyDTO.Condition)
+	if err != nil {
+		return compiledPolicy{}, fmt.Errorf("%w: %s: %v", frameworkconstants.ErrInvalidPolicy, name, err)
+	}
+
+	return compiledPolicy{id: id, name: name, action: action, effect: policyDTO.Effect, condition: condition}, nil
+}
+
+// decide evaluates every policy that applies to the action, so the decision can be explained
+func decide(policies []compiledPolicy, action string, env *policyEnv) frameworkdto.PolicyDecisionDTO {
+	decision := frameworkdto.PolicyDecisionDTO{Action: action, Policies: []frameworkdto.PolicyEvaluationDTO{}}
+
+	denyReason, allowReason := "", ""
+	allowPolicies := 0
+	for _, policy := range policies {
+		if !policyActionMatches(policy.action, action) {
+			continue
+		}
+
+		evaluation := frameworkdto.PolicyEvaluationDTO{ID: policy.id, Name: policy.name, Effect: policy.effect}
+		err := policy.parseErr
+		if err == nil {
+			evaluation.Matched, err = evaluatePolicyCondition(policy.condition, env)
+		}
+		if err != nil {
+			evaluation.Error = err.Error()
+		}
+		decision.Policies = append(decision.Policies, evaluation)
+
+		switch policy.effect {
+		case frameworkconstants.PolicyEffectDeny:
+			if denyReason == "" && err != nil {
+				denyReason = fmt.Sprintf("policy %q could not be evaluated", policy.name)
+			} else if denyReason == "" && evaluation.Matched {
+				denyReason = fmt.Sprintf("denied by policy %q", policy.name)
+			}
+		case frameworkconstants.PolicyEffectAllow:
+			allowPolicies++
+			if allowReason == "" && evaluation.Matched {
+				allowReason = fmt.Sprintf("allowed by policy %q", policy.name)
+			}
+		}
+	}
+
+	switch {
+	case denyReason != "":
+		decision.Reason = denyReason
+	case allowPolicies > 0 && allowReason == "":
+		decision.Reason = "no allow policy matched"
+	case allowReason != "":
+		decision.Allowed, decision.Reason = true, allowReason
+	case len(decision.Policies) > 0:
+		decision.Allowed, decision.Reason = true, "no deny policy matched"
+	default:
+		decision.Allowed, decision.Reason = true, "no policies apply"
+	}
+	return decision
+}
+
+// policyActionMatches reports whether a policy's action, which may be * or end in *, covers the action
+func policyActionMatches(policyAction, action string) bool {
+	if prefix, ok := strings.CutSuffix(policyAction, "*"); ok {
+		return strings.HasPrefix(action, prefix)
+	}
+	return policyAction == action
+}
+
+// newPolicyEnv builds the subject, request and resource attributes a condition can read
+func newPolicyEnv(tokenDto frameworkdto.TokenDTO, request frameworkdto.PolicyRequestDTO, resource any) (*policyEnv, error) {
+	principalType := tokenDto.PrincipalType
+	if principalType == "" {
+		principalType = frameworkconstants.PrincipalTypeUser
+	}
+
+	subject := map[string]any{
+		"id":             tokenDto.Sub,
+		"user_id":        nil,
+		"tenant_id":      float64(tokenDto.TenantID),
+		"email":          tokenDto.Email,
+		"first_name":     tokenDto.FirstName,
+		"last_name":      tokenDto.LastName,
+		"role":           tokenDto.Role,
+		"principal_type": principalType,
+		"scopes":         stringsToPolicyList(tokenDto.Scopes),
+		"amr":            stringsToPolicyList(tokenDto.AMR),
+		"groups":         idsToPolicyList(tokenDto.Groups),
+		"auth_time":      float64(tokenDto.AuthTime),
+		"impersonated":   frameworkutils.IsImpersonating(tokenDto),
+		"extra":          map[string]any{},
+	}
+	if !frameworkutils.IsServicePrincipal(tokenDto) {
+		if userID, err := strconv.ParseUint(tokenDto.Sub, 10, 64); err == nil {
+			subject["user_id"] = float64(userID)
+		}
+	}
+	if tokenDto.Extra != nil {
+		extra, err := toPolicyValue(tokenDto.Extra)
+		if err != nil {
+			return nil, err
+		}
+		subject["extra"] = extra
+	}
+
+	now := request.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	resourceAttributes := map[string]any{}
+	if resource != nil {
+		value, err := toPolicyValue(resource)
+		if err != nil {
+			return nil, err
+		}
+		attributes, ok := value.(map[string]any)
+		if value == nil {
+			attributes, ok = map[string]any{}, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: the resource must be an object", frameworkconstants.ErrInvalidPolicy)
+		}
+		resourceAttributes = attributes
+	}
+
+	return &policyEnv{
+		attributes: map[string]any{
+			"subject": subject,
+			"request": map[string]any{
+				"method": request.Method,
+				"path":   request.Path,
+				"ip":     request.IP,
+				"time":   now.UTC().Format(time.RFC3339),
+			},
+			"resource": resourceAttributes,
+		},
+		now: now,
+	}, nil
+}
+
+// toPolicyValue converts a value to the JSON types conditions work with
+func toPolicyValue(value any) (any, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func stringsToPolicyList(values []string) []any {
+	list := make([]any, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+	return list
+}
+
+func idsToPolicyList(ids []uint) []any {
+	list := make([]any, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, float64(id))
+	}
+	return list
+}
+
+func applyPolicyDTO(policy *entities.AccessPolicy, policyDTO frameworkdto.CreatePolicyDTO) {
+	policy.Name = strings.TrimSpace(policyDTO.Name)
+	policy.Description = strings.TrimSpace(policyDTO.Description)
+	policy.Action = strings.TrimSpace(policyDTO.Action)
+	policy.Effect = policyDTO.Effect
+	policy.Condition = policyDTO.Condition
+	policy.Enabled = policyDTO.Enabled
+}
+
+func toPolicyDTO(policy *entities.AccessPolicy) frameworkdto.PolicyDTO {
+	return frameworkdto.PolicyDTO{
+		ID:          policy.ID,
+		Name:        policy.Name,
+		Description: policy.Description,
+		Action:      policy.Action,
+		Effect:      policy.Effect,
+		Condition:   policy.Condition,
+		Enabled:     policy.Enabled,
+		CreatedAt:   policy.CreatedAt,
+		UpdatedAt:   policy.UpdatedAt,
+	}
+}
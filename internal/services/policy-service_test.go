@@ -0,0 +1,48 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+)
+
+func TestPolicyChangesOnAnotherReplicaApplyOnceCached(t *testing.T) {
+	s := newTestServices(t)
+	admin := s.registerTenant(t, "acme.com", 5)
+	token := tokenDTO(admin)
+
+	// Each replica runs its own PolicyService over the shared database
+	newPolicyService := func() *PolicyService {
+		return NewPolicyService(repositories.NewAccessPolicyRepository(s.db), s.userRepo, repositories.NewGroupRepository(s.db))
+	}
+	policyService, replica := newPolicyService(), newPolicyService()
+	for _, service := range []*PolicyService{policyService, replica} {
+		if decision, err := service.Evaluate(token, frameworkdto.PolicyRequestDTO{}, "reports:export", nil); err != nil || !decision.Allowed {
+			t.Fatalf("Evaluate() = %+v, %v, want the action allowed without policies", decision, err)
+		}
+	}
+
+	if _, err := policyService.CreatePolicy(admin.TenantID, frameworkdto.CreatePolicyDTO{
+		Name:      "no exports",
+		Action:    "reports:export",
+		Effect:    frameworkconstants.PolicyEffectDeny,
+		Condition: "true",
+		Enabled:   true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if decision, _ := policyService.Evaluate(token, frameworkdto.PolicyRequestDTO{}, "reports:export", nil); decision.Allowed {
+		t.Errorf("Evaluate() on the replica making the change allowed the action, want it denied")
+	}
+
+	cached := replica.tenantPolicies[admin.TenantID]
+	cached.expiresAt = time.Now()
+	replica.tenantPolicies[admin.TenantID] = cached
+	if decision, _ := replica.Evaluate(token, frameworkdto.PolicyRequestDTO{}, "reports:export", nil); decision.Allowed {
+		t.Errorf("Evaluate() on another replica once its cache expired allowed the action, want it denied")
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+)
+
+// roleKey identifies a role; built-in roles have tenant ID 0
+type roleKey struct {
+	tenantID uint
+	name     string
+}
+
+// groupGrant holds the permissions a group's roles grant its members
+type groupGrant struct {
+	tenantID    uint
+	permissions map[string]bool
+}
+
+// rbacGrants is a snapshot of the permissions granted by roles and groups
+type rbacGrants struct {
+	roles     map[roleKey]map[string]bool
+	groups    map[uint]groupGrant
+	expiresAt time.Time
+}
+
+// RBACService resolves the permissions granted by roles, held directly or through groups. Role
+// and group permissions are cached briefly, so changes made by other replicas apply within
+// AccessControlCacheTTL; Invalidate applies this replica's changes at once.
+type RBACService struct {
+	roleRepo  *repositories.RoleRepository
+	groupRepo *repositories.GroupRepository
+
+	mu     sync.RWMutex
+	grants *rbacGrants
+}
+
+func NewRBACService(roleRepo *repositories.RoleRepository, groupRepo *repositories.GroupRepository) *RBACService {
+	return &RBACService{roleRepo: roleRepo, groupRepo: groupRepo}
+}
+
+// HasPermission reports whether the caller's role or groups grant the permission. API keys
+// hold no permissions; they are authorised by their scopes instead.
+func (s *RBACService) HasPermission(tokenDto frameworkdto.TokenDTO, permission string) (bool, error) {
+	return s.HoldsPermissions(tokenDto, []string{permission})
+}
+
+// HoldsPermissions reports whether the caller's role and groups together grant every one of
+// the permissions
+func (s *RBACService) HoldsPermissions(tokenDto frameworkdto.TokenDTO, permissions []string) (bool, error) {
+	if frameworkutils.IsServicePrincipal(tokenDto) {
+		return false, nil
+	}
+
+	grants, err := s.getGrants()
+	if err != nil {
+		return false, err
+	}
+
+	for _, permission := range permissions {
+		if !grants.holds(tokenDto, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// CanAssignRole reports whether the caller may give a user of their tenant the role: the
+// caller must already hold every permission the role grants, so assigning roles cannot
+// escalate privileges
+func (s *RBACService) CanAssignRole(tokenDto frameworkdto.TokenDTO, role string) (bool, error) {
+	grants, err := s.getGrants()
+	if err != nil {
+		return false, err
+	}
+
+	permissions, ok := lookupRole(grants.roles, tokenDto.TenantID, role)
+	if !ok {
+		return false, frameworkconstants.ErrRoleNotFound
+	}
+
+	permissionNames := make([]string, 0, len(permissions))
+	for permission := range permissions {
+		permissionNames = append(permissionNames, permission)
+	}
+	return s.HoldsPermissions(tokenDto, permissionNames)
+}
+
+// Invalidate drops the cached role and group permissions so the next check reads them again
+func (s *RBACService) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.grants = nil
+}
+
+func (s *RBACService) getGrants() (*rbacGrants, error) {
+	s.mu.RLock()
+	grants := s.grants
+	s.mu.RUnlock()
+	if grants != nil && time.Now().Before(grants.expiresAt) {
+		return grants, nil
+	}
+
+	roles, err := s.roleRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groupRepo.GetAllWithRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	grants = &rbacGrants{
+		roles:     make(map[roleKey]map[string]bool, len(roles)),
+		groups:    make(map[uint]groupGrant, len(groups)),
+		expiresAt: time.Now().Add(frameworkconstants.AccessControlCacheTTL),
+	}
+	for _, role := range roles {
+		key := roleKey{name: role.Name}
+		if role.TenantID != nil {
+			key.tenantID = *role.TenantID
+		}
+		grants.roles[key] = rolePermissionSet(role)
+	}
+	for _, group := range groups {
+		permissions := make(map[string]bool)
+		for _, role := range group.Roles {
+			for permission := range rolePermissionSet(role) {
+				permissions[permission] = true
+			}
+		}
+		grants.groups[group.ID] = groupGrant{tenantID: group.TenantID, permissions: permissions}
+	}
+
+	s.mu.Lock()
+	s.grants = grants
+	s.mu.Unlock()
+
+	return grants, nil
+}
+
+// holds reports whether the caller's role, or one of the groups named in their token, grants
+// the permission. Groups of another tenant are ignored.
+func (g *rbacGrants) holds(tokenDto frameworkdto.TokenDTO, permission string) bool {
+	if tokenDto.Role != "" {
+		if permissions, _ := lookupRole(g.roles, tokenDto.TenantID, tokenDto.Role); permissions[permission] {
+			return true
+		}
+	}
+	for _, groupID := range tokenDto.Groups {
+		if grant, ok := g.groups[groupID]; ok && grant.tenantID == tokenDto.TenantID && grant.permissions[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+func rolePermissionSet(role entities.Role) map[string]bool {
+	permissions := make(map[string]bool, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions[permission.Name] = true
+	}
+	return permissions
+}
+
+// lookupRole finds a built-in role or one of the tenant's own roles
+func lookupRole(rolePermissions map[roleKey]map[string]bool, tenantID uint, role string) (map[string]bool, bool) {
+	if permissions, ok := rolePermissions[roleKey{name: role}]; ok {
+		return permissions, true
+	}
+	permissions, ok := rolePermissions[roleKey{tenantID: tenantID, name: role}]
+	return permissions, ok
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+)
+
+func TestRBACChangesOnAnotherReplicaApplyOnceCached(t *testing.T) {
+	s := newTestServices(t)
+	admin := s.registerTenant(t, "acme.com", 5)
+
+	roleRepo := repositories.NewRoleRepository(s.db)
+	permission, err := roleRepo.SeedPermission("reports:read", "Read reports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role := &entities.Role{TenantID: &admin.TenantID, Name: "analyst", Permissions: []entities.Permission{permission}}
+	if err := roleRepo.Create(role); err != nil {
+		t.Fatal(err)
+	}
+	admin.Role = role.Name
+	token := tokenDTO(admin)
+
+	// Each replica runs its own RBACService over the shared database
+	replica := NewRBACService(roleRepo, repositories.NewGroupRepository(s.db))
+	for _, rbacService := range []*RBACService{s.rbacService, replica} {
+		if allowed, err := rbacService.HasPermission(token, "reports:read"); err != nil || !allowed {
+			t.Fatalf("HasPermission() = %v, %v, want the role's permission", allowed, err)
+		}
+	}
+
+	role.Permissions = nil
+	if err := roleRepo.Update(role); err != nil {
+		t.Fatal(err)
+	}
+	s.rbacService.Invalidate()
+
+	if allowed, _ := s.rbacService.HasPermission(token, "reports:read"); allowed {
+		t.Errorf("HasPermission() on the replica making the change = true, want the permission gone")
+	}
+
+	replica.grants.expiresAt = time.Now()
+	if allowed, _ := replica.HasPermission(token, "reports:read"); allowed {
+		t.Errorf("HasPermission() on another replica once its cache expired = true, want the permission gone")
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const maxRoleNameLength = 64
+
+// RoleService manages the custom roles tenants compose from framework and host application
+// permissions. Callers can only put permissions they hold into a role, so custom roles cannot
+// be used to escalate privileges.
+type RoleService struct {
+	roleRepo    *repositories.RoleRepository
+	userRepo    *repositories.UserRepository
+	groupRepo   *repositories.GroupRepository
+	rbacService *RBACService
+}
+
+func NewRoleService(
+	roleRepo *repositories.RoleRepository,
+	userRepo *repositories.UserRepository,
+	groupRepo *repositories.GroupRepository,
+	rbacService *RBACService) *RoleService {
+	return &RoleService{roleRepo: roleRepo, userRepo: userRepo, groupRepo: groupRepo, rbacService: rbacService}
+}
+
+// GetAllRoles returns the built-in roles followed by the tenant's own roles
+func (s *RoleService) GetAllRoles(tenantID uint) ([]frameworkdto.RoleDTO, error) {
+	roles, err := s.roleRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	roleDTOs := make([]frameworkdto.RoleDTO, 0, len(roles))
+	for i := range roles {
+		roleDTOs = append(roleDTOs, toRoleDTO(&roles[i]))
+	}
+	return roleDTOs, nil
+}
+
+// GetAllPermissions returns every permission a role can grant, including those registered by
+// the host application
+func (s *RoleService) GetAllPermissions() ([]frameworkdto.PermissionDTO, error) {
+	permissions, err := s.roleRepo.GetAllPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	permissionDTOs := make([]frameworkdto.PermissionDTO, 0, len(permissions))
+	for _, permission := range permissions {
+		permissionDTOs = append(permissionDTOs, frameworkdto.PermissionDTO{Name: permission.Name, Description: permission.Description})
+	}
+	return permissionDTOs, nil
+}
+
+func (s *RoleService) CreateRole(tokenDto frameworkdto.TokenDTO, createDTO frameworkdto.CreateRoleDTO) (frameworkdto.RoleDTO, error) {
+	name := strings.TrimSpace(createDTO.Name)
+	if name == "" || len(name) > maxRoleNameLength {
+		return frameworkdto.RoleDTO{}, frameworkconstants.ErrInvalidRole
+	}
+
+	_, err := s.roleRepo.GetByName(name, tokenDto.TenantID)
+	if err == nil {
+		return frameworkdto.RoleDTO{}, frameworkconstants.ErrRoleAlreadyExists
+	} else if err != gorm.ErrRecordNotFound {
+		return frameworkdto.RoleDTO{}, err
+	}
+
+	permissions, err := s.grantablePermissions(tokenDto, createDTO.Permissions)
+	if err != nil {
+		return frameworkdto.RoleDTO{}, err
+	}
+
+	tenantID := tokenDto.TenantID
+	role := &entities.Role{
+		TenantID:    &tenantID,
+		Name:        name,
+		Description: strings.TrimSpace(createDTO.Description),
+		Permissions: permissions,
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return frameworkdto.RoleDTO{}, err
+	}
+	s.rbacService.Invalidate()
+
+	return toRoleDTO(role), nil
+}
+
+// UpdateRole replaces a custom role's description and permissions. The caller must hold the
+// permissions the role grants now as well as the ones it is given.
+func (s *RoleService) UpdateRole(tokenDto frameworkdto.TokenDTO, updateDTO frameworkdto.UpdateRoleDTO) (frameworkdto.RoleDTO, error) {
+	role, err := s.getRole(tokenDto, updateDTO.ID)
+	if err != nil {
+		return frameworkdto.RoleDTO{}, err
+	}
+
+	permissions, err := s.grantablePermissions(tokenDto, updateDTO.Permissions)
+	if err != nil {
+		return frameworkdto.RoleDTO{}, err
+	}
+
+	role.Description = strings.TrimSpace(updateDTO.Description)
+	role.Permissions = permissions
+	if err := s.roleRepo.Update(role); err != nil {
+		return frameworkdto.RoleDTO{}, err
+	}
+	s.rbacService.Invalidate()
+
+	return toRoleDTO(role), nil
+}
+
+// DeleteRole removes a custom role that no user or group holds any more
+func (s *RoleService) DeleteRole(tokenDto frameworkdto.TokenDTO, roleID uint) error {
+	role, err := s.getRole(tokenDto, roleID)
+	if err != nil {
+		return err
+	}
+
+	users, err := s.userRepo.CountByRole(tokenDto.TenantID, role.Name)
+	if err != nil {
+		return err
+	}
+	if users > 0 {
+		return frameworkconstants.ErrRoleInUse
+	}
+
+	groups, err := s.groupRepo.CountByRoleID(role.ID)
+	if err != nil {
+		return err
+	}
+	if groups > 0 {
+		return frameworkconstants.ErrRoleInUse
+	}
+
+	if err := s.roleRepo.Delete(role); err != nil {
+		return err
+	}
+	s.rbacService.Invalidate()
+
+	return nil
+}
+
+// getRole loads one of the caller's tenant's roles and checks the caller holds everything it grants
+func (s *RoleService) getRole(tokenDto frameworkdto.TokenDTO, roleID uint) (*entities.Role, error) {
+	role, err := s.roleRepo.GetByID(roleID, tokenDto.TenantID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, frameworkconstants.ErrRoleNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	permissionNames := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissionNames = append(permissionNames, permission.Name)
+	}
+	held, err := s.rbacService.HoldsPermissions(tokenDto, permissionNames)
+	if err != nil {
+		return nil, err
+	}
+	if !held {
+		return nil, frameworkconstants.ErrPermissionNotHeld
+	}
+
+	return role, nil
+}
+
+// grantablePermissions resolves the permission names, which must all exist and be held by the caller
+func (s *RoleService) grantablePermissions(tokenDto frameworkdto.TokenDTO, names []string) ([]entities.Permission, error) {
+	unique := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, name)
+	}
+
+	permissions, err := s.roleRepo.GetPermissionsByName(unique)
+	if err != nil {
+		return nil, err
+	}
+	if len(permissions) != len(unique) {
+		return nil, frameworkconstants.ErrInvalidRole
+	}
+
+	held, err := s.rbacService.HoldsPermissions(tokenDto, unique)
+	if err != nil {
+		return nil, err
+	}
+	if !held {
+		return nil, frameworkconstants.ErrPermissionNotHeld
+	}
+
+	return permissions, nil
+}
+
+func toRoleDTO(role *entities.Role) frameworkdto.RoleDTO {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, permission.Name)
+	}
+	sort.Strings(permissions)
+
+	return frameworkdto.RoleDTO{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		IsSystem:    role.IsSystem,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+type SessionService struct {
+	userRepo               *repositories.UserRepository
+	sessionRepo            *repositories.SessionRepository
+	tokenRevocationService *TokenRevocationService
+}
+
+func NewSessionService(
+	userRepo *repositories.UserRepository,
+	sessionRepo *repositories.SessionRepository,
+	tokenRevocationService *TokenRevocationService) *SessionService {
+	return &SessionService{
+		userRepo:               userRepo,
+		sessionRepo:            sessionRepo,
+		tokenRevocationService: tokenRevocationService,
+	}
+}
+
+// GetSessions lists the user's active sessions, flagging currentSessionID as the caller's own
+func (s *SessionService) GetSessions(tenantID, userID uint, currentSessionID string) ([]frameworkdto.SessionDTO, error) {
+	if err := s.ensureUser(tenantID, userID); err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessionRepo.GetActiveByUserID(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionDTOs := make([]frameworkdto.SessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		sessionDTOs = append(sessionDTOs, frameworkdto.SessionDTO{
+			SessionID:  session.SessionID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    currentSessionID != "" && session.SessionID == currentSessionID,
+		})
+	}
+
+	return sessionDTOs, nil
+}
+
+func (s *SessionService) RevokeSession(tenantID, userID uint, sessionID string) error {
+	session, err := s.sessionRepo.GetBySessionID(sessionID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrSessionNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if session.UserID != userID || session.TenantID != tenantID {
+		return frameworkconstants.ErrSessionNotFound
+	}
+
+	return s.tokenRevocationService.RevokeSession(sessionID)
+}
+
+// RevokeAllSessions signs the user out everywhere except exceptSessionID, which may be empty
+func (s *SessionService) RevokeAllSessions(tenantID, userID uint, exceptSessionID string) error {
+	if err := s.ensureUser(tenantID, userID); err != nil {
+		return err
+	}
+
+	return s.tokenRevocationService.RevokeOtherSessions(userID, exceptSessionID)
+}
+
+func (s *SessionService) ensureUser(tenantID, userID uint) error {
+	_, err := s.userRepo.GetByID(userID, tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrUserNotFound
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// TenantDatabaseService keeps the host application's tenant data apart at the database level.
+// Under schema-per-tenant isolation each tenant has its own PostgreSQL schema for the tenant
+// models; the framework's own tables stay shared, as users must be found before their tenant
+// is known. Under row-level security, tenant connections only see their tenant's rows.
+type TenantDatabaseService struct {
+	cfg                *frameworkdto.FrameworkConfig
+	tenantDatabaseRepo *repositories.TenantDatabaseRepository
+	tenantRepo         *repositories.TenantRepository
+	models             []any
+}
+
+func NewTenantDatabaseService(
+	cfg *frameworkdto.FrameworkConfig,
+	tenantDatabaseRepo *repositories.TenantDatabaseRepository,
+	tenantRepo *repositories.TenantRepository,
+	models []any) *TenantDatabaseService {
+	return &TenantDatabaseService{
+		cfg:                cfg,
+		tenantDatabaseRepo: tenantDatabaseRepo,
+		tenantRepo:         tenantRepo,
+		models:             models,
+	}
+}
+
+// Isolated reports whether tenant database access needs a connection set up for the tenant
+func (s *TenantDatabaseService) Isolated() bool {
+	return s.cfg.TenantIsolation == frameworkdto.TenantIsolationSchema || s.cfg.TenantIsolation == frameworkdto.TenantIsolationRowLevelSecurity
+}
+
+// ProvisionTenant creates the tenant's schema and migrates the tenant models into it. Outside
+// schema-per-tenant isolation there is nothing to provision.
+func (s *TenantDatabaseService) ProvisionTenant(tenantID uint) error {
+	if s.cfg.TenantIsolation != frameworkdto.TenantIsolationSchema {
+		return nil
+	}
+	return s.tenantDatabaseRepo.MigrateSchema(frameworkutils.TenantSchemaName(tenantID), s.models)
+}
+
+// ProvisionAllTenants brings every tenant's schema up to date with the tenant models
+func (s *TenantDatabaseService) ProvisionAllTenants() error {
+	if s.cfg.TenantIsolation != frameworkdto.TenantIsolationSchema {
+		return nil
+	}
+
+	tenants, err := s.tenantRepo.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		if err := s.ProvisionTenant(tenant.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect reserves a connection searching the tenant's schema or limited to the tenant's rows.
+// The caller must call release once finished with it.
+func (s *TenantDatabaseService) Connect(ctx context.Context, tenantID uint) (*gorm.DB, func(), error) {
+	ctx = frameworkutils.WithTenantID(ctx, tenantID)
+	if s.cfg.TenantIsolation == frameworkdto.TenantIsolationRowLevelSecurity {
+		return s.tenantDatabaseRepo.ConnectAsTenant(ctx, tenantID)
+	}
+	return s.tenantDatabaseRepo.ConnectToSchema(ctx, frameworkutils.TenantSchemaName(tenantID))
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// TenantDomainService manages the custom domains a tenant's users reach the application on
+type TenantDomainService struct {
+	cfg                   *frameworkdto.FrameworkConfig
+	tenantDomainRepo      *repositories.TenantDomainRepository
+	tenantResolverService *TenantResolverService
+}
+
+func NewTenantDomainService(cfg *frameworkdto.FrameworkConfig, tenantDomainRepo *repositories.TenantDomainRepository, tenantResolverService *TenantResolverService) *TenantDomainService {
+	return &TenantDomainService{cfg: cfg, tenantDomainRepo: tenantDomainRepo, tenantResolverService: tenantResolverService}
+}
+
+func (s *TenantDomainService) GetDomains(tenantID uint) ([]frameworkdto.TenantDomainDTO, error) {
+	domains, err := s.tenantDomainRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	domainsDTO := make([]frameworkdto.TenantDomainDTO, len(domains))
+	for i := range domains {
+		domainsDTO[i] = toTenantDomainDTO(&domains[i])
+	}
+	return domainsDTO, nil
+}
+
+// AddDomain registers an unverified domain for the tenant. The domain serves the tenant once
+// VerifyDomain finds its verification TXT record. Unverified claims do not block other tenants,
+// so a domain can be claimed by several tenants until one of them verifies it.
+func (s *TenantDomainService) AddDomain(tenantID uint, addDomainDTO frameworkdto.AddTenantDomainDTO) (frameworkdto.TenantDomainDTO, error) {
+	domain, ok := s.normaliseDomain(addDomainDTO.Domain)
+	if !ok {
+		return frameworkdto.TenantDomainDTO{}, frameworkconstants.ErrInvalidDomain
+	}
+
+	if _, err := s.tenantDomainRepo.GetByDomainAndTenant(domain, tenantID); err == nil {
+		return frameworkdto.TenantDomainDTO{}, frameworkconstants.ErrDomainAlreadyExists
+	} else if err != gorm.ErrRecordNotFound {
+		return frameworkdto.TenantDomainDTO{}, err
+	}
+	if err := s.checkNotVerified(domain); err != nil {
+		return frameworkdto.TenantDomainDTO{}, err
+	}
+
+	token, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.TenantDomainDTO{}, err
+	}
+
+	tenantDomain := &entities.TenantDomain{TenantID: tenantID, Domain: domain, VerificationToken: token}
+	if err := s.tenantDomainRepo.Create(tenantDomain); err != nil {
+		return frameworkdto.TenantDomainDTO{}, err
+	}
+
+	return toTenantDomainDTO(tenantDomain), nil
+}
+
+// VerifyDomain looks up the domain's verification TXT record and marks the domain verified when
+// it holds the domain's token, unless another tenant verified the domain first
+func (s *TenantDomainService) VerifyDomain(tenantID, domainID uint) (frameworkdto.TenantDomainDTO, error) {
+	tenantDomain, err := s.getDomain(tenantID, domainID)
+	if err != nil {
+		return frameworkdto.TenantDomainDTO{}, err
+	}
+	if tenantDomain.VerifiedAt != nil {
+		return toTenantDomainDTO(tenantDomain), nil
+	}
+
+	lookup := s.cfg.DomainVerificationLookup
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupTXT
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), frameworkconstants.DomainVerificationTimeout)
+	defer cancel()
+
+	// Lookup failures, including a missing record, all mean the domain is not verified yet
+	records, _ := lookup(ctx, verificationRecord(tenantDomain.Domain))
+	verified := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == frameworkconstants.DomainVerificationValuePrefix+tenantDomain.VerificationToken {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return frameworkdto.TenantDomainDTO{}, frameworkconstants.ErrDomainVerificationFailed
+	}
+
+	if err := s.checkNotVerified(tenantDomain.Domain); err != nil {
+		return frameworkdto.TenantDomainDTO{}, err
+	}
+
+	now := time.Now()
+	tenantDomain.VerifiedAt = &now
+	tenantDomain.VerifiedDomain = &tenantDomain.Domain
+	if err := s.tenantDomainRepo.Update(tenantDomain); err != nil {
+		// The unique index on verified domains turns away a tenant verifying at the same moment
+		if checkErr := s.checkNotVerified(tenantDomain.Domain); checkErr != nil {
+			return frameworkdto.TenantDomainDTO{}, checkErr
+		}
+		return frameworkdto.TenantDomainDTO{}, err
+	}
+	s.tenantResolverService.Invalidate()
+
+	return toTenantDomainDTO(tenantDomain), nil
+}
+
+func (s *TenantDomainService) DeleteDomain(tenantID, domainID uint) error {
+	tenantDomain, err := s.getDomain(tenantID, domainID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tenantDomainRepo.Delete(tenantDomain); err != nil {
+		return err
+	}
+	s.tenantResolverService.Invalidate()
+
+	return nil
+}
+
+// checkNotVerified fails when a tenant already holds the domain verified
+func (s *TenantDomainService) checkNotVerified(domain string) error {
+	if _, err := s.tenantDomainRepo.GetVerifiedByDomain(domain); err == nil {
+		return frameworkconstants.ErrDomainAlreadyVerified
+	} else if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	return nil
+}
+
+func (s *TenantDomainService) getDomain(tenantID, domainID uint) (*entities.TenantDomain, error) {
+	tenantDomain, err := s.tenantDomainRepo.GetByID(domainID, tenantID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, frameworkconstants.ErrDomainNotFound
+	}
+	return tenantDomain, err
+}
+
+// normaliseDomain lowercases a domain name and checks it is a valid name outside the base
+// domain, whose subdomains are reached through tenant slugs
+func (s *TenantDomainService) normaliseDomain(domain string) (string, bool) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if len(domain) > 253 {
+		return "", false
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return "", false
+	}
+	for _, label := range labels {
+		if !dnsLabelPattern.MatchString(label) {
+			return "", false
+		}
+	}
+	if isAllDigits(labels[len(labels)-1]) {
+		return "", false
+	}
+
+	if baseDomain := normaliseHost(s.cfg.TenantBaseDomain); baseDomain != "" {
+		if domain == baseDomain || strings.HasSuffix(domain, "."+baseDomain) {
+			return "", false
+		}
+	}
+	return domain, true
+}
+
+func verificationRecord(domain string) string {
+	return frameworkconstants.DomainVerificationRecordPrefix + "." + domain
+}
+
+func isAllDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func toTenantDomainDTO(tenantDomain *entities.TenantDomain) frameworkdto.TenantDomainDTO {
+	return frameworkdto.TenantDomainDTO{
+		ID:                 tenantDomain.ID,
+		Domain:             tenantDomain.Domain,
+		Verified:           tenantDomain.VerifiedAt != nil,
+		VerifiedAt:         tenantDomain.VerifiedAt,
+		VerificationRecord: verificationRecord(tenantDomain.Domain),
+		VerificationValue:  frameworkconstants.DomainVerificationValuePrefix + tenantDomain.VerificationToken,
+		CreatedAt:          tenantDomain.CreatedAt,
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+)
+
+func TestDomainClaims(t *testing.T) {
+	s := newTestServices(t)
+	acme := s.registerTenant(t, "acme.com", 5)
+	globex := s.registerTenant(t, "globex.com", 5)
+	initech := s.registerTenant(t, "initech.com", 5)
+
+	// records holds the TXT records published for the verification record name
+	var records []string
+	s.cfg.DomainVerificationLookup = func(ctx context.Context, name string) ([]string, error) {
+		return records, nil
+	}
+	tenantDomainRepo := repositories.NewTenantDomainRepository(s.db)
+	resolver := NewTenantResolverService(s.cfg, s.tenantRepo, tenantDomainRepo)
+	domainService := NewTenantDomainService(s.cfg, tenantDomainRepo, resolver)
+
+	shop := frameworkdto.AddTenantDomainDTO{Domain: "shop.example.com"}
+	acmeDomain, err := domainService.AddDomain(acme.TenantID, shop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := domainService.AddDomain(acme.TenantID, shop); err != frameworkconstants.ErrDomainAlreadyExists {
+		t.Errorf("AddDomain() twice in a tenant error = %v, want %v", err, frameworkconstants.ErrDomainAlreadyExists)
+	}
+
+	// An unverified claim does not keep another tenant from claiming and verifying the domain
+	globexDomain, err := domainService.AddDomain(globex.TenantID, shop)
+	if err != nil {
+		t.Fatalf("AddDomain() of a domain another tenant has not verified error = %v", err)
+	}
+	records = []string{globexDomain.VerificationValue}
+	if _, err := domainService.VerifyDomain(acme.TenantID, acmeDomain.ID); err != frameworkconstants.ErrDomainVerificationFailed {
+		t.Errorf("VerifyDomain() with another tenant's record error = %v, want %v", err, frameworkconstants.ErrDomainVerificationFailed)
+	}
+	if _, err := domainService.VerifyDomain(globex.TenantID, globexDomain.ID); err != nil {
+		t.Fatalf("VerifyDomain() error = %v", err)
+	}
+	if resolved, err := resolver.Resolve("shop.example.com", ""); err != nil || resolved == nil || resolved.TenantID != globex.TenantID {
+		t.Errorf("Resolve() = %v, %v, want the verifying tenant", resolved, err)
+	}
+
+	// Once verified, the domain is held until its tenant lets it go
+	records = []string{acmeDomain.VerificationValue}
+	if _, err := domainService.VerifyDomain(acme.TenantID, acmeDomain.ID); err != frameworkconstants.ErrDomainAlreadyVerified {
+		t.Errorf("VerifyDomain() of a domain verified by another tenant error = %v, want %v", err, frameworkconstants.ErrDomainAlreadyVerified)
+	}
+	if _, err := domainService.AddDomain(initech.TenantID, shop); err != frameworkconstants.ErrDomainAlreadyVerified {
+		t.Errorf("AddDomain() of a verified domain error = %v, want %v", err, frameworkconstants.ErrDomainAlreadyVerified)
+	}
+
+	if err := domainService.DeleteDomain(globex.TenantID, globexDomain.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := domainService.VerifyDomain(acme.TenantID, acmeDomain.ID); err != nil {
+		t.Errorf("VerifyDomain() after the holder deleted the domain error = %v", err)
+	}
+}
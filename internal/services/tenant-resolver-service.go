@@ -0,0 +1,165 @@
+package services
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// maxTenantResolutions caps the resolution cache, which is keyed by caller-supplied hosts and
+// headers
+const maxTenantResolutions = 10000
+
+type tenantResolution struct {
+	tenant    *frameworkdto.ResolvedTenantDTO
+	err       error
+	expiresAt time.Time
+}
+
+// TenantResolverService identifies the tenant a request is addressed to before the caller is
+// authenticated. Resolutions are cached briefly; changes to slugs, domains and tenants clear
+// the cache.
+type TenantResolverService struct {
+	cfg              *frameworkdto.FrameworkConfig
+	tenantRepo       *repositories.TenantRepository
+	tenantDomainRepo *repositories.TenantDomainRepository
+
+	mu          sync.Mutex
+	resolutions map[string]tenantResolution
+}
+
+func NewTenantResolverService(cfg *frameworkdto.FrameworkConfig, tenantRepo *repositories.TenantRepository, tenantDomainRepo *repositories.TenantDomainRepository) *TenantResolverService {
+	return &TenantResolverService{
+		cfg:              cfg,
+		tenantRepo:       tenantRepo,
+		tenantDomainRepo: tenantDomainRepo,
+		resolutions:      make(map[string]tenantResolution),
+	}
+}
+
+// Resolve returns the tenant named by the tenant header, or failing that the tenant owning the
+// host as a verified custom domain or a subdomain of the base domain. Hosts belonging to no
+// tenant resolve to nothing; a header or subdomain naming no active tenant is
+// ErrTenantNotFound.
+func (s *TenantResolverService) Resolve(host, header string) (*frameworkdto.ResolvedTenantDTO, error) {
+	header = strings.TrimSpace(header)
+	host = normaliseHost(host)
+
+	key := "host:" + host
+	if header != "" {
+		key = "header:" + header
+	}
+
+	s.mu.Lock()
+	resolution, ok := s.resolutions[key]
+	s.mu.Unlock()
+	if ok && time.Now().Before(resolution.expiresAt) {
+		return resolution.tenant, resolution.err
+	}
+
+	var tenant *frameworkdto.ResolvedTenantDTO
+	var err error
+	if header != "" {
+		tenant, err = s.resolveHeader(header)
+	} else {
+		tenant, err = s.resolveHost(host)
+	}
+	if err != nil && err != frameworkconstants.ErrTenantNotFound {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if len(s.resolutions) >= maxTenantResolutions {
+		s.resolutions = make(map[string]tenantResolution)
+	}
+	s.resolutions[key] = tenantResolution{tenant: tenant, err: err, expiresAt: time.Now().Add(frameworkconstants.TenantResolutionCacheTTL)}
+	s.mu.Unlock()
+
+	return tenant, err
+}
+
+// Invalidate forgets every cached resolution
+func (s *TenantResolverService) Invalidate() {
+	s.mu.Lock()
+	s.resolutions = make(map[string]tenantResolution)
+	s.mu.Unlock()
+}
+
+// resolveHeader treats a numeric header as a tenant ID and anything else as a slug
+func (s *TenantResolverService) resolveHeader(header string) (*frameworkdto.ResolvedTenantDTO, error) {
+	var tenant *entities.Tenant
+	var err error
+	if tenantID, parseErr := strconv.ParseUint(header, 10, 0); parseErr == nil {
+		tenant, err = s.tenantRepo.GetByID(uint(tenantID))
+	} else {
+		tenant, err = s.tenantRepo.GetBySlug(strings.ToLower(header))
+	}
+	return resolvedTenant(tenant, err, frameworkconstants.TenantSourceHeader)
+}
+
+func (s *TenantResolverService) resolveHost(host string) (*frameworkdto.ResolvedTenantDTO, error) {
+	if host == "" {
+		return nil, nil
+	}
+
+	if baseDomain := normaliseHost(s.cfg.TenantBaseDomain); baseDomain != "" {
+		if host == baseDomain || host == "www."+baseDomain {
+			return nil, nil
+		}
+		if slug, ok := strings.CutSuffix(host, "."+baseDomain); ok {
+			if strings.Contains(slug, ".") {
+				return nil, nil
+			}
+			tenant, err := s.tenantRepo.GetBySlug(slug)
+			return resolvedTenant(tenant, err, frameworkconstants.TenantSourceSubdomain)
+		}
+	}
+
+	domain, err := s.tenantDomainRepo.GetVerifiedByDomain(host)
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	tenant, err := s.tenantRepo.GetByID(domain.TenantID)
+	resolved, err := resolvedTenant(tenant, err, frameworkconstants.TenantSourceDomain)
+	if err == frameworkconstants.ErrTenantNotFound {
+		return nil, nil
+	}
+	return resolved, err
+}
+
+func resolvedTenant(tenant *entities.Tenant, err error, source string) (*frameworkdto.ResolvedTenantDTO, error) {
+	if err == gorm.ErrRecordNotFound {
+		return nil, frameworkconstants.ErrTenantNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !tenant.IsActive {
+		return nil, frameworkconstants.ErrTenantNotFound
+	}
+
+	resolved := &frameworkdto.ResolvedTenantDTO{TenantID: tenant.ID, TenantName: tenant.Name, Source: source}
+	if tenant.Slug != nil {
+		resolved.Slug = *tenant.Slug
+	}
+	return resolved, nil
+}
+
+// normaliseHost lowercases a host and strips its port and any trailing dot
+func normaliseHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+}
@@ -1,16 +1,44 @@
 package services
 
 import (
+	"strings"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
 )
 
 type TenantService struct {
-	tenantRepo *repositories.TenantRepository
+	tenantRepo        *repositories.TenantRepository
+	tenantLicenceRepo *repositories.TenantLicenceRepository
+	licenceTypeRepo   *repositories.LicenceTypeRepository
+	userRepo          *repositories.UserRepository
+
+	registrationService    *UserRegistrationService
+	tokenRevocationService *TokenRevocationService
+	tenantResolverService  *TenantResolverService
 }
 
-func NewTenantService(tenantRepo *repositories.TenantRepository) *TenantService {
-	return &TenantService{tenantRepo: tenantRepo}
+func NewTenantService(
+	tenantRepo *repositories.TenantRepository,
+	tenantLicenceRepo *repositories.TenantLicenceRepository,
+	licenceTypeRepo *repositories.LicenceTypeRepository,
+	userRepo *repositories.UserRepository,
+	registrationService *UserRegistrationService,
+	tokenRevocationService *TokenRevocationService,
+	tenantResolverService *TenantResolverService) *TenantService {
+	return &TenantService{
+		tenantRepo:             tenantRepo,
+		tenantLicenceRepo:      tenantLicenceRepo,
+		licenceTypeRepo:        licenceTypeRepo,
+		userRepo:               userRepo,
+		registrationService:    registrationService,
+		tokenRevocationService: tokenRevocationService,
+		tenantResolverService:  tenantResolverService,
+	}
 }
 
 func (s *TenantService) GetTenantByID(tenantID uint) (frameworkdto.GetTenantDTO, error) {
@@ -20,11 +48,14 @@ func (s *TenantService) GetTenantByID(tenantID uint) (frameworkdto.GetTenantDTO,
 	}
 
 	return frameworkdto.GetTenantDTO{
-		TenantID:      tenant.ID,
-		TenantName:    tenant.Name,
-		TenantEmail:   tenant.Email,
-		TenantPhone:   tenant.Phone,
-		TenantAddress: tenant.Address,
+		TenantID:         tenant.ID,
+		TenantName:       tenant.Name,
+		TenantEmail:      tenant.Email,
+		TenantPhone:      tenant.Phone,
+		TenantAddress:    tenant.Address,
+		MFARequired:      tenant.MFARequired,
+		MagicLinkEnabled: tenant.MagicLinkEnabled,
+		Slug:             tenantSlug(tenant),
 	}, nil
 }
 
@@ -37,11 +68,14 @@ func (s *TenantService) GetAllTenants() ([]frameworkdto.GetTenantDTO, error) {
 	tenantsDTO := make([]frameworkdto.GetTenantDTO, len(tenants))
 	for i, tenant := range tenants {
 		tenantsDTO[i] = frameworkdto.GetTenantDTO{
-			TenantID:      tenant.ID,
-			TenantName:    tenant.Name,
-			TenantEmail:   tenant.Email,
-			TenantPhone:   tenant.Phone,
-			TenantAddress: tenant.Address,
+			TenantID:         tenant.ID,
+			TenantName:       tenant.Name,
+			TenantEmail:      tenant.Email,
+			TenantPhone:      tenant.Phone,
+			TenantAddress:    tenant.Address,
+			MFARequired:      tenant.MFARequired,
+			MagicLinkEnabled: tenant.MagicLinkEnabled,
+			Slug:             tenantSlug(&tenant),
 		}
 	}
 	return tenantsDTO, nil
@@ -57,15 +91,323 @@ func (s *TenantService) UpdateTenant(tenantID uint, tenantDTO frameworkdto.Updat
 	tenant.Email = tenantDTO.TenantEmail
 	tenant.Phone = tenantDTO.TenantPhone
 	tenant.Address = tenantDTO.TenantAddress
+	tenant.MFARequired = tenantDTO.MFARequired
+	tenant.MagicLinkEnabled = tenantDTO.MagicLinkEnabled
+
+	if tenantDTO.Slug != nil {
+		if err := s.setSlug(tenant, *tenantDTO.Slug); err != nil {
+			return err
+		}
+	}
+
+	if err := s.tenantRepo.Update(tenant); err != nil {
+		return err
+	}
+	s.tenantResolverService.Invalidate()
 
-	return s.tenantRepo.Update(tenant)
+	return nil
 }
 
+// DeleteTenant deletes a tenant without child tenants. A child tenant's seats return to its
+// parent's licence.
 func (s *TenantService) DeleteTenant(tenantID uint) error {
 	tenant, err := s.tenantRepo.GetByID(tenantID)
 	if err != nil {
 		return err
 	}
 
-	return s.tenantRepo.Delete(tenant)
+	children, err := s.tenantRepo.CountChildren(tenantID)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return frameworkconstants.ErrTenantHasChildren
+	}
+
+	if tenant.ParentID != nil {
+		if err := s.releaseSeats(tenant); err != nil {
+			return err
+		}
+	}
+
+	if err := s.tenantRepo.Delete(tenant); err != nil {
+		return err
+	}
+	s.tenantResolverService.Invalidate()
+
+	return nil
+}
+
+// setSlug gives the tenant a new slug, or removes its slug when empty. Slugs are DNS labels and
+// cannot be all digits, so the tenant header can carry either a slug or a tenant ID.
+func (s *TenantService) setSlug(tenant *entities.Tenant, slug string) error {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if slug == "" {
+		tenant.Slug = nil
+		return nil
+	}
+	if !dnsLabelPattern.MatchString(slug) || isAllDigits(slug) || slug == "www" {
+		return frameworkconstants.ErrInvalidTenantSlug
+	}
+
+	existing, err := s.tenantRepo.GetBySlug(slug)
+	if err == nil && existing.ID != tenant.ID {
+		return frameworkconstants.ErrTenantSlugTaken
+	} else if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	tenant.Slug = &slug
+	return nil
+}
+
+func tenantSlug(tenant *entities.Tenant) string {
+	if tenant.Slug == nil {
+		return ""
+	}
+	return *tenant.Slug
+}
+
+// GetChildTenants lists the tenants the parent manages with their licences
+func (s *TenantService) GetChildTenants(parentID uint) ([]frameworkdto.ChildTenantDTO, error) {
+	children, err := s.tenantRepo.GetChildren(parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	childrenDTO := make([]frameworkdto.ChildTenantDTO, len(children))
+	for i := range children {
+		childrenDTO[i] = toChildTenantDTO(&children[i])
+	}
+	return childrenDTO, nil
+}
+
+// CreateChildTenant registers a tenant managed by the parent, with its first admin user, and
+// allocates the child's seats from the parent's licence
+func (s *TenantService) CreateChildTenant(parentID uint, createDTO frameworkdto.CreateChildTenantDTO) (frameworkdto.ChildTenantDTO, error) {
+	if createDTO.Seats < 1 {
+		return frameworkdto.ChildTenantDTO{}, frameworkconstants.ErrInvalidSeatAllocation
+	}
+
+	parentLicence, parentLicenceType, available, err := s.seatPool(parentID)
+	if err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+	if err := s.checkGrantableLicenceType(createDTO.LicenceTypeID, parentLicenceType); err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+	if createDTO.Seats > available {
+		return frameworkdto.ChildTenantDTO{}, frameworkconstants.ErrTenantLicenceExceeded
+	}
+
+	// The seats are set aside before the child exists, so a child never holds seats its parent
+	// has not given up, even when other requests take the parent's seats at the same time
+	allocated, err := s.tenantLicenceRepo.AllocateSeats(parentID, createDTO.Seats, parentLicenceType.MaxSeats)
+	if err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+	if !allocated {
+		return frameworkdto.ChildTenantDTO{}, frameworkconstants.ErrTenantLicenceExceeded
+	}
+
+	child, err := s.registrationService.RegisterChildTenant(parentID, createDTO.TenantRegistrationDTO, createDTO.Seats, parentLicence.ExpiryDate)
+	if err != nil {
+		if releaseErr := s.tenantLicenceRepo.ReleaseSeats(parentID, createDTO.Seats); releaseErr != nil {
+			return frameworkdto.ChildTenantDTO{}, releaseErr
+		}
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+
+	return s.getChildTenant(child.ID, parentID)
+}
+
+// SetChildTenantActive suspends or reactivates one of the parent's tenants. Suspending a tenant
+// signs out all of its users and stops them signing in or using API keys until it is
+// reactivated.
+func (s *TenantService) SetChildTenantActive(parentID, childID uint, active bool) error {
+	child, err := s.tenantRepo.GetChild(childID, parentID)
+	if err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrTenantNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if child.IsActive == active {
+		return nil
+	}
+
+	child.IsActive = active
+	if err := s.tenantRepo.Update(child); err != nil {
+		return err
+	}
+	s.tenantResolverService.Invalidate()
+
+	if active {
+		return nil
+	}
+
+	users, err := s.userRepo.GetAll(child.ID)
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if err := s.tokenRevocationService.RevokeAllForUser(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateChildTenantLicence changes a child tenant's licence type, expiry and seats. Extra seats
+// come from the parent's licence and removed seats return to it; a child cannot drop below the
+// seats its users take and it has allocated to its own children. As at creation, the child's
+// licence cannot outlast the parent's or be of a larger licence type.
+func (s *TenantService) UpdateChildTenantLicence(parentID uint, updateDTO frameworkdto.UpdateChildTenantLicenceDTO) (frameworkdto.ChildTenantDTO, error) {
+	if _, err := s.getChildTenant(updateDTO.TenantID, parentID); err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+
+	childLicence, err := s.tenantLicenceRepo.GetByTenantID(updateDTO.TenantID)
+	if err == gorm.ErrRecordNotFound {
+		return frameworkdto.ChildTenantDTO{}, frameworkconstants.ErrTenantLicenceNotFound
+	} else if err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+	if updateDTO.Seats < 1 || updateDTO.Seats < childLicence.UsedSeats+childLicence.AllocatedSeats {
+		return frameworkdto.ChildTenantDTO{}, frameworkconstants.ErrInvalidSeatAllocation
+	}
+
+	parentLicence, parentLicenceType, available, err := s.seatPool(parentID)
+	if err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+	if err := s.checkGrantableLicenceType(updateDTO.LicenceTypeID, parentLicenceType); err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+
+	expiryDate := updateDTO.ExpiryDate
+	if parentLicence.ExpiryDate != nil && (expiryDate == nil || expiryDate.After(*parentLicence.ExpiryDate)) {
+		expiryDate = parentLicence.ExpiryDate
+	}
+
+	currentSeats := 0
+	if childLicence.SeatLimit != nil {
+		currentSeats = *childLicence.SeatLimit
+	}
+	if updateDTO.Seats-currentSeats > available {
+		return frameworkdto.ChildTenantDTO{}, frameworkconstants.ErrTenantLicenceExceeded
+	}
+
+	// The checks above are repeated in the update itself, in case seats were taken meanwhile
+	err = s.tenantLicenceRepo.ReallocateSeats(parentID, parentLicenceType.MaxSeats, updateDTO.TenantID, childLicence.SeatLimit, updateDTO.Seats, updateDTO.LicenceTypeID, expiryDate)
+	if err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+
+	return s.getChildTenant(updateDTO.TenantID, parentID)
+}
+
+// GetSeatPool reports how many of the tenant's licence seats are used, allocated to child
+// tenants and still available
+func (s *TenantService) GetSeatPool(tenantID uint) (frameworkdto.SeatPoolDTO, error) {
+	tenantLicence, _, available, err := s.seatPool(tenantID)
+	if err != nil {
+		return frameworkdto.SeatPoolDTO{}, err
+	}
+
+	return frameworkdto.SeatPoolDTO{
+		TotalSeats:     available + tenantLicence.UsedSeats + tenantLicence.AllocatedSeats,
+		UsedSeats:      tenantLicence.UsedSeats,
+		AllocatedSeats: tenantLicence.AllocatedSeats,
+		AvailableSeats: available,
+	}, nil
+}
+
+// seatPool returns the tenant's licence, its licence type and the seats it can still give to
+// users or children. Expired licences have no seats to give.
+func (s *TenantService) seatPool(tenantID uint) (*entities.TenantLicence, entities.LicenceType, int, error) {
+	tenantLicence, err := s.tenantLicenceRepo.GetByTenantID(tenantID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, entities.LicenceType{}, 0, frameworkconstants.ErrTenantLicenceNotFound
+	} else if err != nil {
+		return nil, entities.LicenceType{}, 0, err
+	}
+
+	licenceType, err := s.licenceTypeRepo.GetByID(tenantLicence.LicenceTypeID)
+	if err != nil {
+		return nil, entities.LicenceType{}, 0, err
+	}
+
+	if tenantLicence.ExpiryDate != nil && tenantLicence.ExpiryDate.Before(time.Now()) {
+		return nil, entities.LicenceType{}, 0, frameworkconstants.ErrTenantLicenceExpired
+	}
+
+	available := seatCapacity(tenantLicence, licenceType) - tenantLicence.UsedSeats - tenantLicence.AllocatedSeats
+	return tenantLicence, licenceType, max(available, 0), nil
+}
+
+// releaseSeats returns a child tenant's seats to its parent's licence
+func (s *TenantService) releaseSeats(child *entities.Tenant) error {
+	childLicence, err := s.tenantLicenceRepo.GetByTenantID(child.ID)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if childLicence.SeatLimit == nil {
+		return nil
+	}
+
+	return s.tenantLicenceRepo.ReleaseChildSeats(*child.ParentID, child.ID, *childLicence.SeatLimit)
+}
+
+// checkGrantableLicenceType fails unless the licence type exists and allows no more seats than
+// the parent's own, so resellers cannot hand out a higher tier than they hold
+func (s *TenantService) checkGrantableLicenceType(licenceTypeID uint, parentLicenceType entities.LicenceType) error {
+	licenceType, err := s.licenceTypeRepo.GetByID(licenceTypeID)
+	if err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrLicenceTypeNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if licenceType.ID != parentLicenceType.ID && licenceType.MaxSeats > parentLicenceType.MaxSeats {
+		return frameworkconstants.ErrLicenceTypeNotGrantable
+	}
+	return nil
+}
+
+func (s *TenantService) getChildTenant(childID, parentID uint) (frameworkdto.ChildTenantDTO, error) {
+	child, err := s.tenantRepo.GetChild(childID, parentID)
+	if err == gorm.ErrRecordNotFound {
+		return frameworkdto.ChildTenantDTO{}, frameworkconstants.ErrTenantNotFound
+	} else if err != nil {
+		return frameworkdto.ChildTenantDTO{}, err
+	}
+	return toChildTenantDTO(child), nil
+}
+
+func toChildTenantDTO(tenant *entities.Tenant) frameworkdto.ChildTenantDTO {
+	childDTO := frameworkdto.ChildTenantDTO{
+		TenantID:      tenant.ID,
+		TenantName:    tenant.Name,
+		TenantEmail:   tenant.Email,
+		TenantPhone:   tenant.Phone,
+		TenantAddress: tenant.Address,
+		Slug:          tenantSlug(tenant),
+		IsActive:      tenant.IsActive,
+		CreatedAt:     tenant.CreatedAt,
+	}
+
+	if licence := tenant.TenantLicence; licence != nil {
+		childDTO.Licence = &frameworkdto.ChildTenantLicenceDTO{
+			LicenceTypeID:  licence.LicenceTypeID,
+			LicenceType:    licence.LicenceType.Name,
+			ExpiryDate:     licence.ExpiryDate,
+			Seats:          seatCapacity(licence, licence.LicenceType),
+			UsedSeats:      licence.UsedSeats,
+			AllocatedSeats: licence.AllocatedSeats,
+		}
+	}
+	return childDTO
 }
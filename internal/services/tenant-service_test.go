@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+)
+
+// childTenantDTO describes a child tenant on its own email domain with the given seats
+func childTenantDTO(domain string, seats int) frameworkdto.CreateChildTenantDTO {
+	createDTO := frameworkdto.CreateChildTenantDTO{Seats: seats}
+	createDTO.Name = domain
+	createDTO.Email = "info@" + domain
+	createDTO.LicenceTypeID = 1
+	createDTO.User.FirstName = "Cleo"
+	createDTO.User.LastName = "Child"
+	createDTO.User.Email = "admin@" + domain
+	createDTO.User.Password = testPassword
+	return createDTO
+}
+
+func TestSeatsAreNotOversubscribed(t *testing.T) {
+	s := newTestServices(t)
+	reseller := s.registerTenant(t, "reseller.com", 10)
+
+	// Twelve children of three seats and twelve users compete for the ten seats
+	const attempts = 12
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*attempts)
+	for i := range attempts {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := s.tenantService.CreateChildTenant(reseller.TenantID, childTenantDTO(fmt.Sprintf("child%d.com", i), 3))
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			userDTO := frameworkdto.UserRegistrationDTO{FirstName: "Uma", LastName: "User", Email: fmt.Sprintf("user%d@reseller.com", i), Password: testPassword}
+			errs <- s.registrationService.RegisterUser(reseller.TenantID, userDTO)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil && err != frameworkconstants.ErrTenantLicenceExceeded {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+
+	licence, err := s.tenantLicenceRepo.GetByTenantID(reseller.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if licence.UsedSeats+licence.AllocatedSeats > 10 {
+		t.Errorf("used %d + allocated %d seats exceed the licence's 10", licence.UsedSeats, licence.AllocatedSeats)
+	}
+
+	children, err := s.tenantService.GetChildTenants(reseller.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := s.userRepo.GetAll(reseller.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The reseller's first admin does not take a seat
+	if licence.AllocatedSeats != 3*len(children) || licence.UsedSeats != len(users)-1 {
+		t.Errorf("licence counts %d used and %d allocated seats for %d users and %d children", licence.UsedSeats, licence.AllocatedSeats, len(users), len(children))
+	}
+}
+
+func TestFailedChildRegistrationReturnsSeats(t *testing.T) {
+	s := newTestServices(t)
+	reseller := s.registerTenant(t, "reseller.com", 10)
+
+	// The reseller's own email domain is taken, so the child cannot be registered
+	if _, err := s.tenantService.CreateChildTenant(reseller.TenantID, childTenantDTO("reseller.com", 3)); err != frameworkconstants.ErrTenantAlreadyExists {
+		t.Fatalf("CreateChildTenant() error = %v, want %v", err, frameworkconstants.ErrTenantAlreadyExists)
+	}
+
+	pool, err := s.tenantService.GetSeatPool(reseller.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.AllocatedSeats != 0 || pool.AvailableSeats != 10 {
+		t.Errorf("seat pool = %+v, want the seats back", pool)
+	}
+}
+
+func TestChildTenantAdminTakesSeat(t *testing.T) {
+	s := newTestServices(t)
+	reseller := s.registerTenant(t, "reseller.com", 10)
+
+	if _, err := s.tenantService.CreateChildTenant(reseller.TenantID, childTenantDTO("empty.com", 0)); err != frameworkconstants.ErrInvalidSeatAllocation {
+		t.Fatalf("CreateChildTenant() with no seats error = %v, want %v", err, frameworkconstants.ErrInvalidSeatAllocation)
+	}
+
+	child, err := s.tenantService.CreateChildTenant(reseller.TenantID, childTenantDTO("child.com", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	childLicence, err := s.tenantLicenceRepo.GetByTenantID(child.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if childLicence.UsedSeats != 1 {
+		t.Errorf("child used seats = %d, want 1 for its admin", childLicence.UsedSeats)
+	}
+
+	userDTO := frameworkdto.UserRegistrationDTO{FirstName: "Uma", LastName: "User", Email: "uma@child.com", Password: testPassword}
+	if err := s.registrationService.RegisterUser(child.TenantID, userDTO); err != frameworkconstants.ErrTenantLicenceExceeded {
+		t.Errorf("RegisterUser() beyond the child's seat error = %v, want %v", err, frameworkconstants.ErrTenantLicenceExceeded)
+	}
+}
+
+func TestUpdateChildTenantLicenceSeats(t *testing.T) {
+	s := newTestServices(t)
+	reseller := s.registerTenant(t, "reseller.com", 10)
+	child, err := s.tenantService.CreateChildTenant(reseller.TenantID, childTenantDTO("child.com", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userDTO := frameworkdto.UserRegistrationDTO{FirstName: "Uma", LastName: "User", Email: "uma@child.com", Password: testPassword}
+	if err := s.registrationService.RegisterUser(child.TenantID, userDTO); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		seats         int
+		wantErr       error
+		wantAllocated int
+	}{
+		{name: "grow to the whole pool", seats: 10, wantAllocated: 10},
+		{name: "grow beyond the pool", seats: 11, wantErr: frameworkconstants.ErrTenantLicenceExceeded, wantAllocated: 10},
+		{name: "shrink to the child's admin and user", seats: 2, wantAllocated: 2},
+		{name: "shrink below the child's users", seats: 1, wantErr: frameworkconstants.ErrInvalidSeatAllocation, wantAllocated: 2},
+	}
+
+	for _, tt := range tests {
+		updateDTO := frameworkdto.UpdateChildTenantLicenceDTO{TenantID: child.TenantID, LicenceTypeID: 1, Seats: tt.seats}
+		if _, err := s.tenantService.UpdateChildTenantLicence(reseller.TenantID, updateDTO); err != tt.wantErr {
+			t.Errorf("%s: UpdateChildTenantLicence() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		parentLicence, err := s.tenantLicenceRepo.GetByTenantID(reseller.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parentLicence.AllocatedSeats != tt.wantAllocated {
+			t.Errorf("%s: parent allocated seats = %d, want %d", tt.name, parentLicence.AllocatedSeats, tt.wantAllocated)
+		}
+	}
+
+	if err := s.tenantService.DeleteTenant(child.TenantID); err != nil {
+		t.Fatal(err)
+	}
+	parentLicence, err := s.tenantLicenceRepo.GetByTenantID(reseller.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parentLicence.AllocatedSeats != 0 {
+		t.Errorf("parent allocated seats after deleting the child = %d, want 0", parentLicence.AllocatedSeats)
+	}
+}
+
+func TestChildTenantLicenceLimits(t *testing.T) {
+	s := newTestServices(t)
+	reseller := s.registerTenant(t, "reseller.com", 10)
+
+	// The reseller holds the seeded Free licence type, which allows one seat
+	for _, licenceType := range []entities.LicenceType{
+		{Name: "Starter", Description: "Starter", MaxSeats: 1},
+		{Name: "Pro", Description: "Pro", MaxSeats: 50},
+	} {
+		if err := s.licenceTypeRepo.Create(licenceType, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	licenceTypeID := func(name string) uint {
+		var licenceType entities.LicenceType
+		if err := s.db.Where("name = ?", name).First(&licenceType).Error; err != nil {
+			t.Fatal(err)
+		}
+		return licenceType.ID
+	}
+
+	parentExpiry := time.Now().AddDate(0, 1, 0).Truncate(time.Second)
+	parentLicence, err := s.tenantLicenceRepo.GetByTenantID(reseller.TenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentLicence.ExpiryDate = &parentExpiry
+	if err := s.tenantLicenceRepo.Update(parentLicence); err != nil {
+		t.Fatal(err)
+	}
+
+	createDTO := childTenantDTO("pro.com", 1)
+	createDTO.LicenceTypeID = licenceTypeID("Pro")
+	if _, err := s.tenantService.CreateChildTenant(reseller.TenantID, createDTO); err != frameworkconstants.ErrLicenceTypeNotGrantable {
+		t.Errorf("CreateChildTenant() with a larger licence type error = %v, want %v", err, frameworkconstants.ErrLicenceTypeNotGrantable)
+	}
+	child, err := s.tenantService.CreateChildTenant(reseller.TenantID, childTenantDTO("child.com", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	earlier := parentExpiry.AddDate(0, 0, -10)
+	later := parentExpiry.AddDate(0, 0, 10)
+	tests := []struct {
+		name          string
+		licenceType   string
+		expiryDate    *time.Time
+		wantErr       error
+		wantExpiry    time.Time
+		wantTypeAfter string
+	}{
+		{name: "larger licence type", licenceType: "Pro", expiryDate: &earlier, wantErr: frameworkconstants.ErrLicenceTypeNotGrantable, wantExpiry: parentExpiry, wantTypeAfter: "Free"},
+		{name: "licence type as large as the reseller's", licenceType: "Starter", expiryDate: &earlier, wantExpiry: earlier, wantTypeAfter: "Starter"},
+		{name: "expiry after the reseller's", licenceType: "Free", expiryDate: &later, wantExpiry: parentExpiry, wantTypeAfter: "Free"},
+		{name: "no expiry", licenceType: "Free", wantExpiry: parentExpiry, wantTypeAfter: "Free"},
+	}
+
+	for _, tt := range tests {
+		updateDTO := frameworkdto.UpdateChildTenantLicenceDTO{TenantID: child.TenantID, LicenceTypeID: licenceTypeID(tt.licenceType), ExpiryDate: tt.expiryDate, Seats: 1}
+		if _, err := s.tenantService.UpdateChildTenantLicence(reseller.TenantID, updateDTO); err != tt.wantErr {
+			t.Errorf("%s: UpdateChildTenantLicence() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+
+		childLicence, err := s.tenantLicenceRepo.GetByTenantID(child.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if childLicence.ExpiryDate == nil || !childLicence.ExpiryDate.Equal(tt.wantExpiry) {
+			t.Errorf("%s: child expiry = %v, want %v", tt.name, childLicence.ExpiryDate, tt.wantExpiry)
+		}
+		if childLicence.LicenceTypeID != licenceTypeID(tt.wantTypeAfter) {
+			t.Errorf("%s: child licence type = %d, want %s", tt.name, childLicence.LicenceTypeID, tt.wantTypeAfter)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/config"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testPassword = "Tr1cky-Horse-Battery"
+
+// testServices wires the framework's services to an in-memory SQLite database the way
+// GetRouter wires them to the configured one
+type testServices struct {
+	db     *gorm.DB
+	cfg    *frameworkdto.FrameworkConfig
+	keySet *frameworkutils.JWTKeySet
+
+	userRepo          *repositories.UserRepository
+	tenantRepo        *repositories.TenantRepository
+	tenantLicenceRepo *repositories.TenantLicenceRepository
+	licenceTypeRepo   *repositories.LicenceTypeRepository
+	refreshTokenRepo  *repositories.RefreshTokenRepository
+	sessionRepo       *repositories.SessionRepository
+	passwordRepo      *repositories.PasswordRepository
+	passwordHasher    frameworkutils.PasswordHasher
+
+	tokenService           *TokenService
+	tokenRevocationService *TokenRevocationService
+	passwordService        *PasswordService
+	lockoutService         *LockoutService
+	mfaService             *MFAService
+	loginService           *LoginService
+	registrationService    *UserRegistrationService
+	tenantService          *TenantService
+	rbacService            *RBACService
+	groupService           *GroupService
+	oidcService            *OIDCService
+	userMaintenanceService *UserMaintenanceService
+}
+
+func newTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(t.Name()))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := config.MigrateDatabase(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestServices(t *testing.T) *testServices {
+	t.Helper()
+
+	db := newTestDatabase(t)
+	cfg := &frameworkdto.FrameworkConfig{
+		Environment: frameworkdto.EnvDev,
+		JWTSecret:   "test-secret",
+		DBType:      frameworkdto.DatabaseTypeSQLite,
+	}
+	keySet, err := frameworkutils.NewJWTKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordHasher, err := frameworkutils.NewPasswordHasher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServices{
+		db:                db,
+		cfg:               cfg,
+		keySet:            keySet,
+		userRepo:          repositories.NewUserRepository(db),
+		tenantRepo:        repositories.NewTenantRepository(db),
+		tenantLicenceRepo: repositories.NewTenantLicenceRepository(db),
+		licenceTypeRepo:   repositories.NewLicenceTypeRepository(db),
+		refreshTokenRepo:  repositories.NewRefreshTokenRepository(db),
+		sessionRepo:       repositories.NewSessionRepository(db),
+		passwordRepo:      repositories.NewPasswordRepository(db),
+		passwordHasher:    passwordHasher,
+	}
+	revokedTokenRepo := repositories.NewRevokedTokenRepository(db)
+	groupRepo := repositories.NewGroupRepository(db)
+	roleRepo := repositories.NewRoleRepository(db)
+	tenantDomainRepo := repositories.NewTenantDomainRepository(db)
+
+	tenantDatabaseService := NewTenantDatabaseService(cfg, repositories.NewTenantDatabaseRepository(db), s.tenantRepo, nil)
+	s.tokenService = NewTokenService(cfg, keySet, s.userRepo, s.tenantRepo, s.refreshTokenRepo, s.sessionRepo, groupRepo, nil)
+	s.tokenRevocationService = NewTokenRevocationService(s.userRepo, revokedTokenRepo, s.refreshTokenRepo, s.sessionRepo)
+	passwordPolicyService := NewPasswordPolicyService(cfg, repositories.NewPasswordPolicyRepository(db))
+	ldapService := NewLDAPService(cfg, repositories.NewLDAPDirectoryRepository(db))
+	s.lockoutService = NewLockoutService(cfg, s.userRepo)
+	s.passwordService = NewPasswordService(passwordHasher, s.userRepo, s.passwordRepo, passwordPolicyService, s.tokenService, s.tokenRevocationService, s.lockoutService, ldapService)
+	s.mfaService = NewMFAService(cfg, s.userRepo, s.tenantRepo, repositories.NewMFARepository(db), s.tokenService, s.passwordService, s.lockoutService)
+	s.loginService = NewLoginService(cfg, s.userRepo, s.tenantRepo, s.tokenService, s.tokenRevocationService, s.mfaService, s.passwordService, s.lockoutService, repositories.NewMagicLinkRepository(db))
+	s.registrationService = NewUserRegistrationService(passwordHasher, s.userRepo, s.tenantRepo, s.tenantLicenceRepo, s.licenceTypeRepo, passwordPolicyService, tenantDatabaseService)
+	tenantResolverService := NewTenantResolverService(cfg, s.tenantRepo, tenantDomainRepo)
+	s.tenantService = NewTenantService(s.tenantRepo, s.tenantLicenceRepo, s.licenceTypeRepo, s.userRepo, s.registrationService, s.tokenRevocationService, tenantResolverService)
+	s.rbacService = NewRBACService(roleRepo, groupRepo)
+	s.groupService = NewGroupService(groupRepo, roleRepo, s.userRepo, s.rbacService, s.tokenRevocationService)
+	s.oidcService = NewOIDCService(s.userRepo, repositories.NewIdentityProviderRepository(db), s.registrationService, s.tokenService, s.rbacService)
+	s.userMaintenanceService = NewUserMaintenanceService(s.userRepo, roleRepo, s.tokenRevocationService, s.passwordService)
+
+	return s
+}
+
+// registerTenant registers a tenant with room for seats users and returns its admin
+func (s *testServices) registerTenant(t *testing.T, domain string, seats int) *entities.User {
+	t.Helper()
+
+	tenantDTO := frameworkdto.TenantRegistrationDTO{
+		Name:          domain,
+		Email:         "info@" + domain,
+		LicenceTypeID: 1,
+	}
+	tenantDTO.User.FirstName = "Ada"
+	tenantDTO.User.LastName = "Admin"
+	tenantDTO.User.Email = "admin@" + domain
+	tenantDTO.User.Password = testPassword
+
+	tenant, err := s.registrationService.registerTenant(tenantDTO, nil, &seats, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := s.userRepo.GetByEmailAndTenant(tenantDTO.User.Email, tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// tokenDTO returns the token claims the auth middleware would give the user's requests
+func tokenDTO(user *entities.User) frameworkdto.TokenDTO {
+	return frameworkdto.TokenDTO{
+		Sub:      fmt.Sprint(user.ID),
+		TenantID: user.TenantID,
+		Role:     user.Role,
+	}
+}
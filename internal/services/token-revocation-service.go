@@ -0,0 +1,215 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"gorm.io/gorm"
+)
+
+type TokenRevocationService struct {
+	userRepo         *repositories.UserRepository
+	revokedTokenRepo *repositories.RevokedTokenRepository
+	refreshTokenRepo *repositories.RefreshTokenRepository
+	sessionRepo      *repositories.SessionRepository
+}
+
+func NewTokenRevocationService(
+	userRepo *repositories.UserRepository,
+	revokedTokenRepo *repositories.RevokedTokenRepository,
+	refreshTokenRepo *repositories.RefreshTokenRepository,
+	sessionRepo *repositories.SessionRepository) *TokenRevocationService {
+	return &TokenRevocationService{
+		userRepo:         userRepo,
+		revokedTokenRepo: revokedTokenRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+	}
+}
+
+// Logout revokes the presented access token and its session, and, when supplied, the refresh
+// token family it belongs to
+func (s *TokenRevocationService) Logout(tokenDto frameworkdto.TokenDTO, refreshToken string) error {
+	if err := s.RevokeToken(tokenDto); err != nil {
+		return err
+	}
+
+	if tokenDto.Sid != "" {
+		if err := s.RevokeSession(tokenDto.Sid); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.refreshTokenRepo.GetByTokenHash(frameworkutils.HashToken(refreshToken))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if strconv.FormatUint(uint64(stored.UserID), 10) != tokenDto.Sub {
+		return nil
+	}
+
+	return s.refreshTokenRepo.RevokeFamily(stored.FamilyID)
+}
+
+func (s *TokenRevocationService) RevokeToken(tokenDto frameworkdto.TokenDTO) error {
+	if tokenDto.Jti == "" {
+		return nil
+	}
+
+	userID, err := strconv.ParseUint(tokenDto.Sub, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	if err := s.revokedTokenRepo.DeleteExpired(); err != nil {
+		return err
+	}
+
+	return s.revokedTokenRepo.Create(&entities.RevokedToken{
+		Jti:       tokenDto.Jti,
+		UserID:    uint(userID),
+		ExpiresAt: time.Unix(tokenDto.Exp, 0),
+	})
+}
+
+// RevokeSession signs a session out, rejecting its access tokens and refresh tokens
+func (s *TokenRevocationService) RevokeSession(sessionID string) error {
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return err
+	}
+
+	return s.refreshTokenRepo.RevokeFamily(sessionID)
+}
+
+// RevokeOtherSessions signs the user out of every session except exceptSessionID
+func (s *TokenRevocationService) RevokeOtherSessions(userID uint, exceptSessionID string) error {
+	if err := s.sessionRepo.RevokeAllForUser(userID, exceptSessionID); err != nil {
+		return err
+	}
+
+	return s.refreshTokenRepo.RevokeAllForUserExceptFamily(userID, exceptSessionID)
+}
+
+// RevokeAllForUser invalidates every access and refresh token issued to the user up to now
+func (s *TokenRevocationService) RevokeAllForUser(user *entities.User) error {
+	now := time.Now()
+	user.TokensRevokedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeAllForUser(user.ID, ""); err != nil {
+		return err
+	}
+
+	return s.refreshTokenRepo.RevokeAllForUser(user.ID)
+}
+
+// IsRevoked reports whether a parsed token may no longer be used, either because it, its
+// session or the session of the super admin impersonating with it was revoked, or because its
+// user has been deleted, deactivated or had all tokens revoked
+func (s *TokenRevocationService) IsRevoked(tokenDto frameworkdto.TokenDTO) (bool, error) {
+	if tokenDto.Jti != "" {
+		revoked, err := s.revokedTokenRepo.ExistsByJti(tokenDto.Jti)
+		if err != nil {
+			return false, err
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	var session *entities.Session
+	if tokenDto.Sid != "" {
+		var err error
+		session, err = s.liveSession(tokenDto.Sid)
+		if err != nil {
+			return false, err
+		}
+		if session == nil {
+			return true, nil
+		}
+	}
+
+	// Impersonation tokens live only as long as the super admin's session
+	if tokenDto.Act != nil {
+		if tokenDto.Act.Sid == "" {
+			return true, nil
+		}
+		adminSession, err := s.liveSession(tokenDto.Act.Sid)
+		if err != nil {
+			return false, err
+		}
+		if adminSession == nil {
+			return true, nil
+		}
+	}
+
+	userID, err := strconv.ParseUint(tokenDto.Sub, 10, 64)
+	if err != nil {
+		return true, nil
+	}
+
+	user, err := s.userRepo.GetByID(uint(userID), tokenDto.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if !user.IsActive {
+		return true, nil
+	}
+
+	// Token times are whole seconds, so a token issued in the second its user's tokens were
+	// revoked is only kept when its session began after the revocation. Revoking a user's
+	// tokens revokes every session they had, so such a session cannot predate it.
+	if user.TokensRevokedAt != nil && tokenDto.Iat <= user.TokensRevokedAt.Unix() &&
+		(session == nil || !session.CreatedAt.After(*user.TokensRevokedAt)) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// liveSession returns the token's session, recording it as seen, or nil when the session has
+// been revoked, has expired or no longer exists
+func (s *TokenRevocationService) liveSession(sessionID string) (*entities.Session, error) {
+	session, err := s.sessionRepo.GetBySessionID(sessionID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || session.ExpiresAt.Before(now) {
+		return nil, nil
+	}
+
+	if now.Sub(session.LastSeenAt) > frameworkconstants.SessionLastSeenInterval {
+		live, err := s.sessionRepo.RecordSeen(sessionID, now)
+		if err != nil {
+			return nil, err
+		}
+		if !live {
+			// Revoked since it was read
+			return nil, nil
+		}
+		session.LastSeenAt = now
+	}
+
+	return session, nil
+}
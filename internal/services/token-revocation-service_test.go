@@ -0,0 +1,225 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+func TestIsRevoked(t *testing.T) {
+	// revokeAt records a revocation of all the user's tokens without revoking their sessions,
+	// so only the token and session times decide
+	revokeAt := func(t *testing.T, s *testServices, user *entities.User, revokedAt time.Time) {
+		user.TokensRevokedAt = &revokedAt
+		if err := s.userRepo.Update(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// revokeInTokenSecond revokes the user's tokens offset from the start of the token's
+	// session and moves the token into the second of the revocation
+	revokeInTokenSecond := func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO, offset time.Duration) {
+		session, err := s.sessionRepo.GetBySessionID(tokenDto.Sid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revokedAt := session.CreatedAt.Add(offset)
+		revokeAt(t, s, user, revokedAt)
+		tokenDto.Iat = revokedAt.Unix()
+	}
+
+	tests := []struct {
+		name string
+		// prepare changes the world after the token is issued and may change the token itself
+		prepare     func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO)
+		wantRevoked bool
+	}{
+		{
+			name:    "live token",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {},
+		},
+		{
+			name: "revoked token",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				if err := s.tokenRevocationService.RevokeToken(*tokenDto); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "revoked session",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				if err := s.tokenRevocationService.RevokeSession(tokenDto.Sid); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "unknown session",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				tokenDto.Sid = "not-a-session"
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "deactivated user",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				user.IsActive = false
+				if err := s.userRepo.Update(user); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "deleted user",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				if err := s.userRepo.Delete(user); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "malformed subject",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				tokenDto.Sub = "admin"
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "all tokens revoked",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				if err := s.tokenRevocationService.RevokeAllForUser(user); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "all tokens revoked in the second the token was issued after its session began",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				revokeInTokenSecond(t, s, user, tokenDto, time.Nanosecond)
+			},
+			wantRevoked: true,
+		},
+		{
+			name: "all tokens revoked in the second the token was issued before its session began",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				revokeInTokenSecond(t, s, user, tokenDto, -time.Nanosecond)
+			},
+		},
+		{
+			name: "all tokens revoked before the token was issued",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, tokenDto *frameworkdto.TokenDTO) {
+				revokeAt(t, s, user, time.Unix(tokenDto.Iat-1, 0))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			user := s.registerTenant(t, "acme.com", 5)
+
+			tokens, err := s.tokenService.IssueTokens(user, []string{frameworkconstants.AuthMethodPassword}, "127.0.0.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokenDto, err := s.keySet.ParseJWT(string(tokens.Token))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.prepare(t, s, user, &tokenDto)
+
+			revoked, err := s.tokenRevocationService.IsRevoked(tokenDto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if revoked != tt.wantRevoked {
+				t.Errorf("IsRevoked() = %v, want %v", revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestSessionRevokedWhileInUseStaysRevoked(t *testing.T) {
+	tests := []struct {
+		name string
+		// use uses the session the way a client would, returning whether it was let in
+		use func(s *testServices, tokens frameworkdto.LoginResponseDTO, tokenDto frameworkdto.TokenDTO) (bool, error)
+	}{
+		{
+			name: "access token",
+			use: func(s *testServices, tokens frameworkdto.LoginResponseDTO, tokenDto frameworkdto.TokenDTO) (bool, error) {
+				revoked, err := s.tokenRevocationService.IsRevoked(tokenDto)
+				return !revoked, err
+			},
+		},
+		{
+			name: "refresh token",
+			use: func(s *testServices, tokens frameworkdto.LoginResponseDTO, tokenDto frameworkdto.TokenDTO) (bool, error) {
+				_, err := s.tokenService.Refresh(tokens.RefreshToken, "127.0.0.1")
+				if err == frameworkconstants.ErrInvalidRefreshToken {
+					return false, nil
+				}
+				return err == nil, err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			user := s.registerTenant(t, "acme.com", 5)
+
+			tokens, err := s.tokenService.IssueTokens(user, []string{frameworkconstants.AuthMethodPassword}, "127.0.0.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokenDto, err := s.keySet.ParseJWT(string(tokens.Token))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Long enough unseen for the next use to record it
+			if err := s.db.Model(&entities.Session{}).Where("session_id = ?", tokenDto.Sid).
+				Update("last_seen_at", time.Now().Add(-time.Hour)).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			// The user signs the session out just after the request has read it
+			revoked := false
+			err = s.db.Callback().Query().After("gorm:query").Register("test:concurrent_revocation", func(db *gorm.DB) {
+				if revoked || db.Statement.Table != "sessions" {
+					return
+				}
+				revoked = true
+				db.AddError(s.sessionRepo.Revoke(tokenDto.Sid))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			allowed, err := tt.use(s, tokens, tokenDto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed {
+				t.Error("a session revoked while in use let the request in")
+			}
+
+			session, err := s.sessionRepo.GetBySessionID(tokenDto.Sid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.RevokedAt == nil {
+				t.Error("using the session undid its revocation")
+			}
+		})
+	}
+}
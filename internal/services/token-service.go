@@ -0,0 +1,325 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"github.com/geekible-ltd/serviceframework/internal/repositories"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TokenService struct {
+	cfg              *frameworkdto.FrameworkConfig
+	keySet           *frameworkutils.JWTKeySet
+	userRepo         *repositories.UserRepository
+	tenantRepo       *repositories.TenantRepository
+	refreshTokenRepo *repositories.RefreshTokenRepository
+	sessionRepo      *repositories.SessionRepository
+	groupRepo        *repositories.GroupRepository
+	claimsProvider   frameworkutils.ClaimsProvider
+}
+
+func NewTokenService(
+	cfg *frameworkdto.FrameworkConfig,
+	keySet *frameworkutils.JWTKeySet,
+	userRepo *repositories.UserRepository,
+	tenantRepo *repositories.TenantRepository,
+	refreshTokenRepo *repositories.RefreshTokenRepository,
+	sessionRepo *repositories.SessionRepository,
+	groupRepo *repositories.GroupRepository,
+	claimsProvider frameworkutils.ClaimsProvider) *TokenService {
+	return &TokenService{
+		cfg:              cfg,
+		keySet:           keySet,
+		userRepo:         userRepo,
+		tenantRepo:       tenantRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+		groupRepo:        groupRepo,
+		claimsProvider:   claimsProvider,
+	}
+}
+
+// IssueTokens starts a new session for the user and issues its first access and refresh tokens.
+// authMethods are the factors the user just completed and become the session's amr claim.
+func (s *TokenService) IssueTokens(user *entities.User, authMethods []string, ipAddress, userAgent string) (frameworkdto.LoginResponseDTO, error) {
+	if err := s.checkTenantActive(user.TenantID); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	now := time.Now()
+	session := &entities.Session{
+		SessionID:   uuid.New().String(),
+		UserID:      user.ID,
+		TenantID:    user.TenantID,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(s.refreshTTL()),
+		AuthTime:    &now,
+		AuthMethods: strings.Join(authMethods, " "),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return s.issueTokens(user, session, ipAddress)
+}
+
+// Reauthenticate records that the user proved their identity again within an existing session
+// and issues a new access token for it. The session's refresh token is left as it is.
+func (s *TokenService) Reauthenticate(user *entities.User, sessionID string, authMethods []string) (frameworkdto.LoginResponseDTO, error) {
+	session, err := s.sessionRepo.GetBySessionID(sessionID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrCannotReauthenticate
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if session.UserID != user.ID || session.RevokedAt != nil {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrCannotReauthenticate
+	}
+
+	now := time.Now()
+	session.AuthTime = &now
+	session.AuthMethods = strings.Join(authMethods, " ")
+	session.LastSeenAt = now
+	live, err := s.sessionRepo.RecordAuthentication(sessionID, now, session.AuthMethods)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !live {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrCannotReauthenticate
+	}
+
+	token, accessTTL, err := s.accessToken(user, session)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return frameworkdto.LoginResponseDTO{
+		Token:     frameworkdto.BearerToken(token),
+		ExpiresIn: int64(accessTTL.Seconds()),
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Every refresh token can only be
+// used once; presenting one that has already been rotated revokes its entire family.
+func (s *TokenService) Refresh(refreshToken string, ipAddress string) (frameworkdto.LoginResponseDTO, error) {
+	stored, err := s.refreshTokenRepo.GetByTokenHash(frameworkutils.HashToken(refreshToken))
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidRefreshToken
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if stored.UsedAt != nil {
+		return frameworkdto.LoginResponseDTO{}, s.revokeReusedFamily(stored.FamilyID)
+	}
+
+	// Revoked by logout or session sign-out rather than replayed
+	if stored.RevokedAt != nil {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidRefreshToken
+	}
+
+	now := time.Now()
+	if stored.ExpiresAt.Before(now) {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrRefreshTokenExpired
+	}
+
+	marked, err := s.refreshTokenRepo.MarkUsed(stored.ID)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+	if !marked {
+		// Another request rotated the token between reading and marking it
+		return frameworkdto.LoginResponseDTO{}, s.revokeReusedFamily(stored.FamilyID)
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID, stored.TenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if !user.IsActive {
+		if err := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+			return frameworkdto.LoginResponseDTO{}, err
+		}
+		return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrUserAccountInactive
+	}
+
+	if err := s.checkTenantActive(user.TenantID); err != nil {
+		if err == frameworkconstants.ErrTenantSuspended {
+			if err := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+				return frameworkdto.LoginResponseDTO{}, err
+			}
+		}
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	expiresAt := now.Add(s.refreshTTL())
+	session, err := s.sessionRepo.GetBySessionID(stored.FamilyID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		// Refresh token families issued before sessions existed become sessions on first use
+		session = &entities.Session{
+			SessionID:  stored.FamilyID,
+			UserID:     user.ID,
+			TenantID:   user.TenantID,
+			IPAddress:  stored.IPAddress,
+			LastSeenAt: now,
+			ExpiresAt:  expiresAt,
+		}
+		if err := s.sessionRepo.Create(session); err != nil {
+			return frameworkdto.LoginResponseDTO{}, err
+		}
+	} else if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	} else {
+		live, err := s.sessionRepo.Extend(session.SessionID, now, expiresAt)
+		if err != nil {
+			return frameworkdto.LoginResponseDTO{}, err
+		}
+		if !live {
+			return frameworkdto.LoginResponseDTO{}, frameworkconstants.ErrInvalidRefreshToken
+		}
+		session.LastSeenAt = now
+		session.ExpiresAt = expiresAt
+	}
+
+	return s.issueTokens(user, session, ipAddress)
+}
+
+// revokeReusedFamily signs out the session one of whose refresh tokens was replayed, revoking
+// its refresh tokens and the access tokens already issued to whoever holds them
+func (s *TokenService) revokeReusedFamily(familyID string) error {
+	if err := s.sessionRepo.Revoke(familyID); err != nil {
+		return err
+	}
+	if err := s.refreshTokenRepo.RevokeFamily(familyID); err != nil {
+		return err
+	}
+	return frameworkconstants.ErrRefreshTokenReused
+}
+
+// checkTenantActive refuses tokens to users of suspended tenants
+func (s *TokenService) checkTenantActive(tenantID uint) error {
+	tenant, err := s.tenantRepo.GetByID(tenantID)
+	if err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrTenantNotFound
+	} else if err != nil {
+		return err
+	}
+	if !tenant.IsActive {
+		return frameworkconstants.ErrTenantSuspended
+	}
+	return nil
+}
+
+func (s *TokenService) refreshTTL() time.Duration {
+	if s.cfg.RefreshTokenTTL > 0 {
+		return s.cfg.RefreshTokenTTL
+	}
+	return frameworkconstants.DefaultRefreshTokenTTL
+}
+
+// issueTokens issues a token pair for the session; the session ID is the refresh token family
+func (s *TokenService) issueTokens(user *entities.User, session *entities.Session, ipAddress string) (frameworkdto.LoginResponseDTO, error) {
+	token, accessTTL, err := s.accessToken(user, session)
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	refreshToken, err := frameworkutils.GenerateOpaqueToken()
+	if err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	if err := s.refreshTokenRepo.Create(&entities.RefreshToken{
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		FamilyID:  session.SessionID,
+		TokenHash: frameworkutils.HashToken(refreshToken),
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(s.refreshTTL()),
+	}); err != nil {
+		return frameworkdto.LoginResponseDTO{}, err
+	}
+
+	return frameworkdto.LoginResponseDTO{
+		Token:        frameworkdto.BearerToken(token),
+		ExpiresIn:    int64(accessTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// accessToken signs an access token for the session carrying its auth_time and amr
+func (s *TokenService) accessToken(user *entities.User, session *entities.Session) (string, time.Duration, error) {
+	accessTTL := s.cfg.AccessTokenTTL
+	if accessTTL <= 0 {
+		accessTTL = frameworkconstants.DefaultAccessTokenTTL
+	}
+
+	claims, err := s.CustomClaims(user)
+	if err != nil {
+		return "", 0, err
+	}
+	claims["sid"] = session.SessionID
+	// Sessions started before step-up authentication have neither and must re-authenticate
+	// before using sensitive routes
+	if session.AuthTime != nil {
+		claims["auth_time"] = session.AuthTime.Unix()
+	}
+	if authMethods := strings.Fields(session.AuthMethods); len(authMethods) > 0 {
+		claims["amr"] = authMethods
+	}
+
+	token, err := s.keySet.GenerateJWTWithClaims(user.ID, user.TenantID, user.Email, user.FirstName, user.LastName, user.Role, accessTTL, claims)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, accessTTL, nil
+}
+
+// CustomClaims returns the user's groups claim and the claims the registered ClaimsProvider
+// adds for the user, without reserved claims. The map is never nil so callers can add their
+// own claims to it.
+func (s *TokenService) CustomClaims(user *entities.User) (map[string]any, error) {
+	claims := make(map[string]any)
+
+	groupIDs, err := userGroupIDs(s.groupRepo, user.TenantID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupIDs) > 0 {
+		claims["groups"] = groupIDs
+	}
+
+	if s.claimsProvider == nil {
+		return claims, nil
+	}
+
+	providedClaims, err := s.claimsProvider(frameworkdto.TokenSubjectDTO{
+		UserID:    user.ID,
+		TenantID:  user.TenantID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      user.Role,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range providedClaims {
+		if !frameworkutils.IsReservedClaim(name) {
+			claims[name] = value
+		}
+	}
+	return claims, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+	"gorm.io/gorm"
+)
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare changes the world after the token pair is issued and returns the refresh
+		// token to present
+		prepare func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string
+		wantErr error
+		// wantFamilyRevoked means the token issued by the first refresh no longer works
+		wantFamilyRevoked bool
+	}{
+		{
+			name: "unused token",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string {
+				return refreshToken
+			},
+		},
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string {
+				return "not-a-token"
+			},
+			wantErr: frameworkconstants.ErrInvalidRefreshToken,
+		},
+		{
+			name: "rotated token",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string {
+				if _, err := s.tokenService.Refresh(refreshToken, "127.0.0.1"); err != nil {
+					t.Fatal(err)
+				}
+				return refreshToken
+			},
+			wantErr:           frameworkconstants.ErrRefreshTokenReused,
+			wantFamilyRevoked: true,
+		},
+		{
+			name: "token rotated by a concurrent request",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string {
+				// The other request marks the token used just after this one has read it
+				rotated := false
+				err := s.db.Callback().Query().After("gorm:query").Register("test:concurrent_rotation", func(db *gorm.DB) {
+					if rotated || db.Statement.Table != "refresh_tokens" {
+						return
+					}
+					rotated = true
+					db.AddError(db.Session(&gorm.Session{NewDB: true}).Model(&entities.RefreshToken{}).
+						Where("used_at IS NULL").Update("used_at", time.Now()).Error)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return refreshToken
+			},
+			wantErr:           frameworkconstants.ErrRefreshTokenReused,
+			wantFamilyRevoked: true,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string {
+				stored, err := s.refreshTokenRepo.GetByTokenHash(frameworkutils.HashToken(refreshToken))
+				if err != nil {
+					t.Fatal(err)
+				}
+				stored.ExpiresAt = time.Now().Add(-time.Minute)
+				if err := s.refreshTokenRepo.Update(stored); err != nil {
+					t.Fatal(err)
+				}
+				return refreshToken
+			},
+			wantErr: frameworkconstants.ErrRefreshTokenExpired,
+		},
+		{
+			name: "logged out token",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string {
+				if err := s.tokenRevocationService.RevokeAllForUser(user); err != nil {
+					t.Fatal(err)
+				}
+				return refreshToken
+			},
+			wantErr: frameworkconstants.ErrInvalidRefreshToken,
+		},
+		{
+			name: "deactivated user",
+			prepare: func(t *testing.T, s *testServices, user *entities.User, refreshToken string) string {
+				user.IsActive = false
+				if err := s.userRepo.Update(user); err != nil {
+					t.Fatal(err)
+				}
+				return refreshToken
+			},
+			wantErr: frameworkconstants.ErrUserAccountInactive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			user := s.registerTenant(t, "acme.com", 5)
+
+			tokens, err := s.tokenService.IssueTokens(user, []string{frameworkconstants.AuthMethodPassword}, "127.0.0.1", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			refreshToken := tt.prepare(t, s, user, tokens.RefreshToken)
+
+			refreshed, err := s.tokenService.Refresh(refreshToken, "127.0.0.1")
+			if err != tt.wantErr {
+				t.Fatalf("Refresh() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (refreshed.Token == "" || refreshed.RefreshToken == "" || refreshed.RefreshToken == refreshToken) {
+				t.Errorf("Refresh() = %+v, want a new token pair", refreshed)
+			}
+
+			if tt.wantFamilyRevoked {
+				stored, err := s.refreshTokenRepo.GetByTokenHash(frameworkutils.HashToken(refreshToken))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var family []entities.RefreshToken
+				if err := s.db.Where("family_id = ?", stored.FamilyID).Find(&family).Error; err != nil {
+					t.Fatal(err)
+				}
+				for _, token := range family {
+					if token.RevokedAt == nil {
+						t.Errorf("refresh token %d of the reused family was not revoked", token.ID)
+					}
+				}
+
+				// Access tokens already issued to the session no longer work either
+				tokenDto, err := s.keySet.ParseJWT(string(tokens.Token))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if revoked, err := s.tokenRevocationService.IsRevoked(tokenDto); err != nil || !revoked {
+					t.Errorf("IsRevoked() of the session's access token = %v, %v, want true", revoked, err)
+				}
+			}
+		})
+	}
+}
@@ -5,50 +5,117 @@ import (
 
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"github.com/geekible-ltd/serviceframework/internal/repositories"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type UserMaintenanceService struct {
-	userRepo *repositories.UserRepository
+	userRepo               *repositories.UserRepository
+	roleRepo               *repositories.RoleRepository
+	tokenRevocationService *TokenRevocationService
+	passwordService        *PasswordService
 }
 
-func NewUserMaintenanceService(userRepo *repositories.UserRepository) *UserMaintenanceService {
-	return &UserMaintenanceService{userRepo: userRepo}
+func NewUserMaintenanceService(
+	userRepo *repositories.UserRepository,
+	roleRepo *repositories.RoleRepository,
+	tokenRevocationService *TokenRevocationService,
+	passwordService *PasswordService) *UserMaintenanceService {
+	return &UserMaintenanceService{
+		userRepo:               userRepo,
+		roleRepo:               roleRepo,
+		tokenRevocationService: tokenRevocationService,
+		passwordService:        passwordService,
+	}
 }
 
 func (s *UserMaintenanceService) DeleteUser(tenantID uint, userID uint) error {
-	user, err := s.userRepo.GetByID(tenantID, userID)
+	user, err := s.userRepo.GetByID(userID, tenantID)
 	if err != nil {
 		return err
 	}
 
+	if err := s.tokenRevocationService.RevokeAllForUser(user); err != nil {
+		return err
+	}
+
 	s.userRepo.Delete(user)
 
 	return nil
 }
 
-func (s *UserMaintenanceService) UpdateUser(tenantID uint, userID uint, userDTO frameworkdto.UserUpdateRequestDTO) error {
-	user, err := s.userRepo.GetByID(tenantID, userID)
+// GetUserRole returns the role of the tenant's user
+func (s *UserMaintenanceService) GetUserRole(tenantID uint, userID uint) (string, error) {
+	user, err := s.userRepo.GetByID(userID, tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return "", frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return user.Role, nil
+}
+
+// UpdateUser saves the user's details. Unless manageUsers is set the caller is updating
+// themselves and only the name and email change. Changing the email address moves where
+// password resets and magic links are sent, so it requires the caller to have authenticated
+// recently.
+func (s *UserMaintenanceService) UpdateUser(tenantID uint, userID uint, userDTO frameworkdto.UserUpdateRequestDTO, manageUsers, recentlyAuthenticated bool) error {
+	user, err := s.userRepo.GetByID(userID, tenantID)
 	if err != nil {
 		return err
 	}
 
+	emailChanged := userDTO.Email != user.Email
+	if emailChanged && !recentlyAuthenticated {
+		return frameworkconstants.ErrReauthenticationRequired
+	}
+	if emailChanged {
+		existing, err := s.userRepo.GetByEmailAndTenant(userDTO.Email, tenantID)
+		if err == nil && existing.ID != user.ID {
+			return frameworkconstants.ErrUserAlreadyExists
+		} else if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+	}
+
+	wasActive, previousRole := user.IsActive, user.Role
+
 	user.FirstName = userDTO.FirstName
 	user.LastName = userDTO.LastName
 	user.Email = userDTO.Email
-	user.Role = userDTO.Role
-	user.IsActive = userDTO.IsActive
-	user.IsEmailVerified = userDTO.IsEmailVerified
+	if manageUsers {
+		user.Role = userDTO.Role
+		user.IsActive = userDTO.IsActive
+		user.IsEmailVerified = userDTO.IsEmailVerified
+	} else if emailChanged {
+		// Users changing their own address have not shown they receive mail there
+		user.IsEmailVerified = false
+	}
 
-	s.userRepo.Update(user)
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	// Issued tokens carry the role, so they are revoked when it changes as well as on deactivation
+	if (wasActive && !user.IsActive) || user.Role != previousRole {
+		return s.tokenRevocationService.RevokeAllForUser(user)
+	}
 
 	return nil
 }
 
-func (s *UserMaintenanceService) SetResetPasswordToken(email string) error {
-	user, err := s.userRepo.GetByEmail(email)
+// SetResetPasswordToken issues a reset token to the user with the email address, within the
+// tenant when the request was addressed to one
+func (s *UserMaintenanceService) SetResetPasswordToken(email string, tenantID uint) error {
+	var user *entities.User
+	var err error
+	if tenantID != 0 {
+		user, err = s.userRepo.GetByEmailAndTenant(email, tenantID)
+	} else {
+		user, err = s.userRepo.GetByEmail(email)
+	}
 	if err != nil {
 		return err
 	}
@@ -62,26 +129,57 @@ func (s *UserMaintenanceService) SetResetPasswordToken(email string) error {
 	return nil
 }
 
+// UpdateUserPassword sets a new password from a reset token. The token is cleared in the same
+// save as the password, so it cannot be replayed.
 func (s *UserMaintenanceService) UpdateUserPassword(resetToken string, password string) error {
+	if resetToken == "" {
+		return frameworkconstants.ErrInvalidResetPasswordToken
+	}
+
 	user, err := s.userRepo.GetByResetPasswordToken(resetToken)
-	if err != nil {
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrInvalidResetPasswordToken
+	} else if err != nil {
 		return err
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	if user.ResetPasswordTokenExpiresAt == nil || user.ResetPasswordTokenExpiresAt.Before(time.Now()) {
+		return frameworkconstants.ErrInvalidResetPasswordToken
+	}
+
+	if err := s.passwordService.SetPassword(user, password); err != nil {
 		return err
 	}
 
-	user.PasswordHash = string(passwordHash)
+	user.ResetPasswordToken = ""
+	user.ResetPasswordTokenExpiresAt = nil
 
-	s.userRepo.Update(user)
+	// Saves the password and the cleared reset token along with the revocation
+	return s.tokenRevocationService.RevokeAllForUser(user)
+}
 
-	return nil
+// UnlockUser lifts a failed-login lockout ahead of its expiry
+func (s *UserMaintenanceService) UnlockUser(tenantID uint, userID uint) error {
+	user, err := s.userRepo.GetByID(userID, tenantID)
+	if err != nil && err == gorm.ErrRecordNotFound {
+		return frameworkconstants.ErrUserNotFound
+	} else if err != nil {
+		return err
+	}
+
+	clearLockout(user)
+
+	return s.userRepo.Update(user)
+}
+
+func clearLockout(user *entities.User) {
+	user.FailedLoginAttempts = 0
+	user.LockoutCount = 0
+	user.LockedUntil = nil
 }
 
 func (s *UserMaintenanceService) VerifyEmail(tenantID uint, userID uint, token string) error {
-	user, err := s.userRepo.GetByID(tenantID, userID)
+	user, err := s.userRepo.GetByID(userID, tenantID)
 	if err != nil {
 		return err
 	}
@@ -102,24 +200,39 @@ func (s *UserMaintenanceService) GetAllUsersByTenantID(tenantID uint) ([]framewo
 	usersDTO := make([]frameworkdto.GetUsersResponseDTO, len(users))
 	for i, user := range users {
 		usersDTO[i] = frameworkdto.GetUsersResponseDTO{
-			UserID:    user.ID,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-			Role:      user.Role,
-			IsActive:  user.IsActive,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			UserID:      user.ID,
+			FirstName:   user.FirstName,
+			LastName:    user.LastName,
+			Email:       user.Email,
+			Role:        user.Role,
+			IsActive:    user.IsActive,
+			LockedUntil: lockedUntil(user.LockedUntil),
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
 		}
 	}
 
 	return usersDTO, nil
 }
 
-func (s *UserMaintenanceService) GetUserRoles() ([]frameworkdto.GetUserRoles, error) {
-	roles := []frameworkdto.GetUserRoles{
-		{Role: string(frameworkconstants.UserRoleTenantAdmin)},
-		{Role: string(frameworkconstants.UserRoleTenantUser)},
+// GetUserRoles returns the built-in roles and the tenant's own roles
+func (s *UserMaintenanceService) GetUserRoles(tenantID uint) ([]frameworkdto.GetUserRoles, error) {
+	roles, err := s.roleRepo.GetAllByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	userRoles := make([]frameworkdto.GetUserRoles, 0, len(roles))
+	for _, role := range roles {
+		userRoles = append(userRoles, frameworkdto.GetUserRoles{Role: role.Name})
+	}
+	return userRoles, nil
+}
+
+// lockedUntil hides lockouts that have already expired
+func lockedUntil(until *time.Time) *time.Time {
+	if until == nil || until.Before(time.Now()) {
+		return nil
 	}
-	return roles, nil
+	return until
 }
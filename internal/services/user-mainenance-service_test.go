@@ -0,0 +1,108 @@
+package services
+
+import (
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
+)
+
+func TestUpdateUserRevokesTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		update      func(dto *frameworkdto.UserUpdateRequestDTO)
+		wantRevoked bool
+	}{
+		{name: "name change", update: func(dto *frameworkdto.UserUpdateRequestDTO) { dto.FirstName = "Augusta" }},
+		{name: "role change", update: func(dto *frameworkdto.UserUpdateRequestDTO) { dto.Role = string(frameworkconstants.UserRoleTenantUser) }, wantRevoked: true},
+		{name: "deactivation", update: func(dto *frameworkdto.UserUpdateRequestDTO) { dto.IsActive = false }, wantRevoked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			admin := s.registerTenant(t, "acme.com", 5)
+
+			userDTO := frameworkdto.UserUpdateRequestDTO{
+				FirstName:       admin.FirstName,
+				LastName:        admin.LastName,
+				Email:           admin.Email,
+				Role:            admin.Role,
+				IsActive:        admin.IsActive,
+				IsEmailVerified: admin.IsEmailVerified,
+			}
+			tt.update(&userDTO)
+
+			if err := s.userMaintenanceService.UpdateUser(admin.TenantID, admin.ID, userDTO, true, false); err != nil {
+				t.Fatal(err)
+			}
+
+			user, err := s.userRepo.GetByID(admin.ID, admin.TenantID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.FirstName != userDTO.FirstName || user.Role != userDTO.Role || user.IsActive != userDTO.IsActive {
+				t.Errorf("saved user = %q %q active %v, want the update applied", user.FirstName, user.Role, user.IsActive)
+			}
+			if revoked := user.TokensRevokedAt != nil; revoked != tt.wantRevoked {
+				t.Errorf("tokens revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		email        string
+		manageUsers  bool
+		wantErr      error
+		wantVerified bool
+	}{
+		{name: "own new address", email: "ada@acme.com"},
+		{name: "own address taken", email: "grace@acme.com", wantErr: frameworkconstants.ErrUserAlreadyExists},
+		{name: "address set by a user manager", email: "ada@acme.com", manageUsers: true, wantVerified: true},
+		{name: "address taken set by a user manager", email: "grace@acme.com", manageUsers: true, wantErr: frameworkconstants.ErrUserAlreadyExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServices(t)
+			admin := s.registerTenant(t, "acme.com", 5)
+			admin.IsEmailVerified = true
+			if err := s.userRepo.Update(admin); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.userRepo.Create(&entities.User{TenantID: admin.TenantID, Email: "grace@acme.com", Role: string(frameworkconstants.UserRoleTenantUser), IsActive: true}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := s.userMaintenanceService.UpdateUser(admin.TenantID, admin.ID, frameworkdto.UserUpdateRequestDTO{
+				FirstName:       admin.FirstName,
+				LastName:        admin.LastName,
+				Email:           tt.email,
+				Role:            admin.Role,
+				IsActive:        admin.IsActive,
+				IsEmailVerified: true,
+			}, tt.manageUsers, true)
+			if err != tt.wantErr {
+				t.Fatalf("UpdateUser() error = %v, want %v", err, tt.wantErr)
+			}
+
+			user, err := s.userRepo.GetByID(admin.ID, admin.TenantID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil {
+				if user.Email != admin.Email {
+					t.Errorf("email = %q, want it unchanged", user.Email)
+				}
+				return
+			}
+			if user.Email != tt.email || user.IsEmailVerified != tt.wantVerified {
+				t.Errorf("saved user = %q verified %v, want %q verified %v", user.Email, user.IsEmailVerified, tt.email, tt.wantVerified)
+			}
+		})
+	}
+}
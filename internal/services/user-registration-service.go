@@ -6,41 +6,66 @@ import (
 
 	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
 	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"github.com/geekible-ltd/serviceframework/internal/repositories"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserRegistrationService struct {
+	passwordHasher    frameworkutils.PasswordHasher
 	userRepo          *repositories.UserRepository
 	tenantRepo        *repositories.TenantRepository
 	tenantLicenceRepo *repositories.TenantLicenceRepository
 	licenceTypeRepo   *repositories.LicenceTypeRepository
+
+	passwordPolicyService *PasswordPolicyService
+	tenantDatabaseService *TenantDatabaseService
 }
 
 func NewUserRegistrationService(
+	passwordHasher frameworkutils.PasswordHasher,
 	userRepo *repositories.UserRepository,
 	tenantRepo *repositories.TenantRepository,
 	tenantLicenceRepo *repositories.TenantLicenceRepository,
-	licenceTypeRepo *repositories.LicenceTypeRepository) *UserRegistrationService {
+	licenceTypeRepo *repositories.LicenceTypeRepository,
+	passwordPolicyService *PasswordPolicyService,
+	tenantDatabaseService *TenantDatabaseService) *UserRegistrationService {
 	return &UserRegistrationService{
-		userRepo:          userRepo,
-		tenantRepo:        tenantRepo,
-		tenantLicenceRepo: tenantLicenceRepo,
-		licenceTypeRepo:   licenceTypeRepo}
+		passwordHasher:        passwordHasher,
+		userRepo:              userRepo,
+		tenantRepo:            tenantRepo,
+		tenantLicenceRepo:     tenantLicenceRepo,
+		licenceTypeRepo:       licenceTypeRepo,
+		passwordPolicyService: passwordPolicyService,
+		tenantDatabaseService: tenantDatabaseService}
 }
 
 func (s *UserRegistrationService) RegisterTenant(tenantDTO frameworkdto.TenantRegistrationDTO) error {
+	_, err := s.registerTenant(tenantDTO, nil, nil, nil)
+	return err
+}
+
+// RegisterChildTenant registers a tenant managed by the parent tenant, whose licence holds the
+// seats given to the child's licence
+func (s *UserRegistrationService) RegisterChildTenant(parentID uint, tenantDTO frameworkdto.TenantRegistrationDTO, seats int, expiryDate *time.Time) (*entities.Tenant, error) {
+	return s.registerTenant(tenantDTO, &parentID, &seats, expiryDate)
+}
+
+func (s *UserRegistrationService) registerTenant(tenantDTO frameworkdto.TenantRegistrationDTO, parentID *uint, seatLimit *int, expiryDate *time.Time) (*entities.Tenant, error) {
 	emailDomain := strings.Split(tenantDTO.Email, "@")[1]
 	_, err := s.tenantRepo.GetByEmailDomain(emailDomain)
 
 	if err == nil {
-		return frameworkconstants.ErrTenantAlreadyExists
+		return nil, frameworkconstants.ErrTenantAlreadyExists
 	}
 	if err != gorm.ErrRecordNotFound {
-		return err
+		return nil, err
+	}
+
+	if err := s.passwordPolicyService.ValidatePassword(0, tenantDTO.User.Password, tenantDTO.User.Email, tenantDTO.User.FirstName, tenantDTO.User.LastName); err != nil {
+		return nil, err
 	}
 
 	tenant := entities.Tenant{
@@ -49,33 +74,41 @@ func (s *UserRegistrationService) RegisterTenant(tenantDTO frameworkdto.TenantRe
 		Phone:    tenantDTO.Phone,
 		Address:  tenantDTO.Address,
 		IsActive: true,
+		ParentID: parentID,
 	}
 
 	if err := s.tenantRepo.Create(&tenant); err != nil {
-		return frameworkconstants.ErrFailedToCreateTenant
+		return nil, frameworkconstants.ErrFailedToCreateTenant
 	}
 
 	tenantLicence := entities.TenantLicence{
 		TenantID:      tenant.ID,
 		LicenceKey:    uuid.New().String(),
 		LicenceTypeID: tenantDTO.LicenceTypeID,
-		ExpiryDate:    nil,
+		ExpiryDate:    expiryDate,
+		SeatLimit:     seatLimit,
 	}
 	if err := s.tenantLicenceRepo.Create(&tenantLicence); err != nil {
-		return frameworkconstants.ErrFailedToCreateTenant
+		return nil, frameworkconstants.ErrFailedToCreateTenant
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(tenantDTO.User.Password), bcrypt.DefaultCost)
+	if err := s.tenantDatabaseService.ProvisionTenant(tenant.ID); err != nil {
+		return nil, frameworkconstants.ErrFailedToCreateTenant
+	}
+
+	passwordHash, err := s.passwordHasher.Hash(tenantDTO.User.Password)
 	if err != nil {
-		return frameworkconstants.ErrFailedToHashPassword
+		return nil, frameworkconstants.ErrFailedToHashPassword
 	}
 
+	passwordChangedAt := time.Now()
 	user := entities.User{
 		TenantID:                        tenant.ID,
 		FirstName:                       tenantDTO.User.FirstName,
 		LastName:                        tenantDTO.User.LastName,
 		Email:                           tenantDTO.User.Email,
-		PasswordHash:                    string(passwordHash),
+		PasswordHash:                    passwordHash,
+		PasswordChangedAt:               &passwordChangedAt,
 		FailedLoginAttempts:             0,
 		IsActive:                        true,
 		Role:                            string(frameworkconstants.UserRoleTenantAdmin),
@@ -87,59 +120,46 @@ func (s *UserRegistrationService) RegisterTenant(tenantDTO frameworkdto.TenantRe
 		EmailVerificationToken:          uuid.New().String(),
 		EmailVerificationTokenExpiresAt: nil,
 	}
-	if err := s.userRepo.Create(&user); err != nil {
-		return frameworkconstants.ErrFailedToCreateUser
+	// A child tenant's first admin takes one of the seats its parent gave it, so the child
+	// cannot hold more users than it has seats. Other tenants' first admins take no seat.
+	if parentID != nil {
+		if err := s.createWithSeat(&user); err != nil {
+			return nil, err
+		}
+	} else if err := s.userRepo.Create(&user); err != nil {
+		return nil, frameworkconstants.ErrFailedToCreateUser
 	}
 
-	return nil
+	return &tenant, nil
 }
 
 func (s *UserRegistrationService) RegisterUser(tenantId uint, userDTO frameworkdto.UserRegistrationDTO) error {
-	_, err := s.userRepo.GetByEmail(userDTO.Email)
+	_, err := s.userRepo.GetByEmailAndTenant(userDTO.Email, tenantId)
 
 	if err == nil {
-		return frameworkconstants.ErrTenantAlreadyExists
+		return frameworkconstants.ErrUserAlreadyExists
 	}
 	if err != gorm.ErrRecordNotFound {
 		return err
 	}
 
-	tenantLicence, err := s.tenantLicenceRepo.GetByID(tenantId)
-	if err != nil && err == gorm.ErrRecordNotFound {
-		return frameworkconstants.ErrTenantLicenceNotFound
-	} else if err != nil {
+	if err := s.passwordPolicyService.ValidatePassword(tenantId, userDTO.Password, userDTO.Email, userDTO.FirstName, userDTO.LastName); err != nil {
 		return err
 	}
 
-	licenceType, err := s.licenceTypeRepo.GetByID(tenantLicence.LicenceTypeID)
-	if err != nil {
-		return err
-	}
-
-	if tenantLicence.UsedSeats >= licenceType.MaxSeats {
-		return frameworkconstants.ErrTenantLicenceExceeded
-	}
-
-	if tenantLicence.ExpiryDate != nil && tenantLicence.ExpiryDate.Before(time.Now()) {
-		return frameworkconstants.ErrTenantLicenceExpired
-	}
-
-	tenantLicence.UsedSeats++
-	if err := s.tenantLicenceRepo.Update(tenantLicence); err != nil {
-		return err
-	}
-
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(userDTO.Password), bcrypt.DefaultCost)
+	passwordHash, err := s.passwordHasher.Hash(userDTO.Password)
 	if err != nil {
 		return frameworkconstants.ErrFailedToHashPassword
 	}
 
+	passwordChangedAt := time.Now()
 	user := entities.User{
 		TenantID:                        tenantId,
 		FirstName:                       userDTO.FirstName,
 		LastName:                        userDTO.LastName,
 		Email:                           userDTO.Email,
-		PasswordHash:                    string(passwordHash),
+		PasswordHash:                    passwordHash,
+		PasswordChangedAt:               &passwordChangedAt,
 		FailedLoginAttempts:             0,
 		IsActive:                        true,
 		Role:                            string(frameworkconstants.UserRoleTenantUser),
@@ -151,32 +171,107 @@ func (s *UserRegistrationService) RegisterUser(tenantId uint, userDTO frameworkd
 		EmailVerificationToken:          uuid.New().String(),
 		EmailVerificationTokenExpiresAt: nil,
 	}
-	if err := s.userRepo.Create(&user); err != nil {
-		return frameworkconstants.ErrFailedToCreateUser
+	if err := s.createWithSeat(&user); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (s *UserRegistrationService) DeleteUser(tenantId uint, userId uint) error {
-	user, err := s.userRepo.GetByID(tenantId, userId)
+// ProvisionUser creates an active user authenticated by an external identity provider. The
+// user has no password and takes a licence seat like any other user.
+func (s *UserRegistrationService) ProvisionUser(tenantId uint, email, firstName, lastName, role string) (*entities.User, error) {
+	_, err := s.userRepo.GetByEmailAndTenant(email, tenantId)
+	if err == nil {
+		return nil, frameworkconstants.ErrUserAlreadyExists
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	user := entities.User{
+		TenantID:        tenantId,
+		FirstName:       firstName,
+		LastName:        lastName,
+		Email:           email,
+		IsActive:        true,
+		Role:            role,
+		IsEmailVerified: true,
+	}
+	if err := s.createWithSeat(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *UserRegistrationService) reserveSeat(tenantId uint) error {
+	tenantLicence, err := s.tenantLicenceRepo.GetByID(tenantId)
 	if err != nil && err == gorm.ErrRecordNotFound {
-		return frameworkconstants.ErrUserNotFound
+		return frameworkconstants.ErrTenantLicenceNotFound
 	} else if err != nil {
 		return err
 	}
 
-	s.userRepo.Delete(user)
+	licenceType, err := s.licenceTypeRepo.GetByID(tenantLicence.LicenceTypeID)
+	if err != nil {
+		return err
+	}
+
+	if tenantLicence.ExpiryDate != nil && tenantLicence.ExpiryDate.Before(time.Now()) {
+		return frameworkconstants.ErrTenantLicenceExpired
+	}
 
-	tenantLicence, err := s.tenantLicenceRepo.GetByTenantID(tenantId)
+	// The seat is counted and taken in one statement, so concurrent registrations cannot both
+	// take the last seat
+	reserved, err := s.tenantLicenceRepo.ReserveUserSeat(tenantId, licenceType.MaxSeats)
+	if err != nil {
+		return err
+	}
+	if !reserved {
+		return frameworkconstants.ErrTenantLicenceExceeded
+	}
+	return nil
+}
+
+// createWithSeat takes a seat of the user's tenant's licence and creates the user, returning the
+// seat when the user cannot be created
+func (s *UserRegistrationService) createWithSeat(user *entities.User) error {
+	if err := s.reserveSeat(user.TenantID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		if releaseErr := s.tenantLicenceRepo.ReleaseUserSeat(user.TenantID); releaseErr != nil {
+			return releaseErr
+		}
+		return frameworkconstants.ErrFailedToCreateUser
+	}
+	return nil
+}
+
+// seatCapacity is the number of seats a licence holds: its allocation from the parent tenant for
+// child tenants, and otherwise its licence type's MaxSeats
+func seatCapacity(tenantLicence *entities.TenantLicence, licenceType entities.LicenceType) int {
+	if tenantLicence.SeatLimit != nil {
+		return *tenantLicence.SeatLimit
+	}
+	return licenceType.MaxSeats
+}
+
+func (s *UserRegistrationService) DeleteUser(tenantId uint, userId uint) error {
+	user, err := s.userRepo.GetByID(userId, tenantId)
 	if err != nil && err == gorm.ErrRecordNotFound {
-		return frameworkconstants.ErrTenantLicenceNotFound
+		return frameworkconstants.ErrUserNotFound
 	} else if err != nil {
 		return err
 	}
 
-	tenantLicence.UsedSeats--
-	if err := s.tenantLicenceRepo.Update(tenantLicence); err != nil {
+	if err := s.userRepo.Delete(user); err != nil {
+		return err
+	}
+
+	if err := s.tenantLicenceRepo.ReleaseUserSeat(tenantId); err != nil {
 		return err
 	}
 
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+)
+
+func TestEmailsAreUniquePerTenant(t *testing.T) {
+	s := newTestServices(t)
+	acme := s.registerTenant(t, "acme.com", 5)
+	globex := s.registerTenant(t, "globex.com", 5)
+
+	userDTO := frameworkdto.UserRegistrationDTO{FirstName: "Sam", LastName: "Shared", Email: "sam@contractor.com", Password: testPassword}
+	for _, admin := range []uint{acme.TenantID, globex.TenantID} {
+		if err := s.registrationService.RegisterUser(admin, userDTO); err != nil {
+			t.Fatalf("RegisterUser(tenant %d) error = %v", admin, err)
+		}
+	}
+	if err := s.registrationService.RegisterUser(acme.TenantID, userDTO); err != frameworkconstants.ErrUserAlreadyExists {
+		t.Errorf("RegisterUser() twice in a tenant error = %v, want %v", err, frameworkconstants.ErrUserAlreadyExists)
+	}
+	if _, err := s.registrationService.ProvisionUser(globex.TenantID, userDTO.Email, "Sam", "Shared", string(frameworkconstants.UserRoleTenantUser)); err != frameworkconstants.ErrUserAlreadyExists {
+		t.Errorf("ProvisionUser() of an existing tenant user error = %v, want %v", err, frameworkconstants.ErrUserAlreadyExists)
+	}
+
+	// Without a tenant a shared email cannot pick a user
+	if _, err := s.userRepo.GetByEmail(userDTO.Email); err != frameworkconstants.ErrTenantRequired {
+		t.Errorf("GetByEmail() of a shared email error = %v, want %v", err, frameworkconstants.ErrTenantRequired)
+	}
+	if user, err := s.userRepo.GetByEmail(acme.Email); err != nil || user.ID != acme.ID {
+		t.Errorf("GetByEmail() of a unique email = %v, %v, want the tenant's admin", user, err)
+	}
+
+	loginDTO := frameworkdto.LoginDTO{Email: userDTO.Email, Password: testPassword}
+	if _, err := s.loginService.Login(loginDTO, 0, "127.0.0.1", "test"); err != frameworkconstants.ErrTenantRequired {
+		t.Errorf("Login() without a tenant error = %v, want %v", err, frameworkconstants.ErrTenantRequired)
+	}
+	response, err := s.loginService.Login(loginDTO, globex.TenantID, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("Login() addressed to the tenant error = %v", err)
+	}
+	if response.Token == "" {
+		t.Error("Login() addressed to the tenant did not issue a token")
+	}
+}
@@ -32,12 +32,42 @@ package serviceframework
 // @tag.name Authentication
 // @tag.description User authentication and login
 //
+// @tag.name MFA
+// @tag.description TOTP multi-factor authentication enrollment and management
+//
+// @tag.name OIDC
+// @tag.description Per-tenant OpenID Connect identity provider configuration
+//
+// @tag.name LDAP
+// @tag.description Per-tenant LDAP and Active Directory login configuration
+//
+// @tag.name Session
+// @tag.description Active session listing and remote sign-out
+//
+// @tag.name API Keys
+// @tag.description Scoped API keys for machine clients
+//
+// @tag.name Impersonation
+// @tag.description Super admin impersonation of tenant users with an audit trail
+//
 // @tag.name Registration
 // @tag.description Tenant and user registration
 //
 // @tag.name User Maintenance
 // @tag.description User management, password reset, and email verification
 //
+// @tag.name Password Policy
+// @tag.description Framework-wide and per-tenant password rules
+//
+// @tag.name Role
+// @tag.description Tenant-defined custom roles and the permissions they can grant
+//
+// @tag.name Group
+// @tag.description Nested groups of users within a tenant and the roles they grant
+//
+// @tag.name Policy
+// @tag.description Tenant attribute-based access policies and decision dry runs
+//
 // @tag.name Tenant
 // @tag.description Tenant management operations
 //
@@ -45,9 +75,16 @@ package serviceframework
 // @tag.description Licence type management (Super Admin only)
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	_ "github.com/geekible-ltd/serviceframework/docs"
+	frameworkconstants "github.com/geekible-ltd/serviceframework/framework-constants"
 	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
 	"github.com/geekible-ltd/serviceframework/internal/config"
+	"github.com/geekible-ltd/serviceframework/internal/entities"
 	"github.com/geekible-ltd/serviceframework/internal/handlers"
 	"github.com/geekible-ltd/serviceframework/internal/middleware"
 	"github.com/geekible-ltd/serviceframework/internal/repositories"
@@ -63,9 +100,36 @@ type ServiceFramework struct {
 	fc     *config.FrameworkConfiguration
 	db     *gorm.DB
 	router *gin.Engine
+	keySet *frameworkutils.JWTKeySet
+
+	passwordHasher frameworkutils.PasswordHasher
+	claimsProvider frameworkutils.ClaimsProvider
+
+	authMiddleware gin.HandlerFunc
+	rbacService    *services.RBACService
+	policyService  *services.PolicyService
+	groupService   *services.GroupService
+
+	tenantModels          []any
+	tenantDatabaseService *services.TenantDatabaseService
 }
 
 func NewServiceFramework(cfg *frameworkdto.FrameworkConfig) *ServiceFramework {
+	keySet, err := frameworkutils.NewJWTKeySet(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	passwordHasher, err := frameworkutils.NewPasswordHasher(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	isolation := cfg.TenantIsolation
+	if (isolation == frameworkdto.TenantIsolationSchema || isolation == frameworkdto.TenantIsolationRowLevelSecurity) && cfg.DBType != frameworkdto.DatabaseTypePostgreSQL {
+		panic(frameworkconstants.ErrTenantIsolationUnsupported)
+	}
+
 	fc := config.NewFrameworkConfig(cfg)
 	gormDb := fc.GetDatabase()
 
@@ -74,13 +138,210 @@ func NewServiceFramework(cfg *frameworkdto.FrameworkConfig) *ServiceFramework {
 		fc:     fc,
 		db:     gormDb,
 		router: fc.GetRouter(),
+		keySet: keySet,
+
+		passwordHasher: passwordHasher,
 	}
 }
 
+// GetDatabase returns the framework's own connection pool. Under row-level security it
+// bypasses the tenant policies, so tenant data should be read through GetTenantDatabase or
+// WithTenantDatabase instead.
 func (s *ServiceFramework) GetDatabase() *gorm.DB {
 	return s.db
 }
 
+// GetTenantDatabase returns the database scoped to the caller's tenant: models embedding
+// frameworkutils.TenantScoped only see and create the tenant's rows. Under schema-per-tenant
+// isolation or row-level security it uses a connection searching the tenant's schema or
+// carrying the tenant in app.tenant_id, reserved until the request finishes. The request must
+// have passed the auth middleware or, on public routes, have been addressed to a tenant.
+func (s *ServiceFramework) GetTenantDatabase(c *gin.Context) *gorm.DB {
+	db := s.db.WithContext(c.Request.Context())
+	if s.tenantDatabaseService == nil || !s.tenantDatabaseService.Isolated() {
+		return db
+	}
+
+	if tenantDb, ok := c.Get(frameworkconstants.TenantDatabaseKey); ok {
+		return tenantDb.(*gorm.DB)
+	}
+
+	var tenantID uint
+	if tokenDto, err := frameworkutils.GetTokenDTO(c); err == nil {
+		tenantID = tokenDto.TenantID
+	} else if tenant, ok := frameworkutils.GetResolvedTenant(c); ok {
+		tenantID = tenant.TenantID
+	} else {
+		db.AddError(frameworkconstants.ErrTenantScopeMissing)
+		return db
+	}
+
+	tenantDb, release, err := s.tenantDatabaseService.Connect(c.Request.Context(), tenantID)
+	if err != nil {
+		db.AddError(err)
+		return db
+	}
+	c.Set(frameworkconstants.TenantDatabaseKey, tenantDb)
+	c.Set(frameworkconstants.TenantDatabaseReleaseKey, release)
+
+	return tenantDb
+}
+
+// WithTenantDatabase runs fn with the database scoped to the tenant, for work outside requests
+// such as background jobs. It is available once GetRouter has been called.
+func (s *ServiceFramework) WithTenantDatabase(ctx context.Context, tenantID uint, fn func(db *gorm.DB) error) error {
+	if s.tenantDatabaseService == nil {
+		panic("WithTenantDatabase must be called after GetRouter")
+	}
+	if !s.tenantDatabaseService.Isolated() {
+		return fn(s.db.WithContext(frameworkutils.WithTenantID(ctx, tenantID)))
+	}
+
+	tenantDb, release, err := s.tenantDatabaseService.Connect(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fn(tenantDb)
+}
+
+// RegisterTenantModels adds host application models holding tenant data. They are migrated
+// when GetRouter is called: into every tenant's schema, and each new tenant's, under
+// schema-per-tenant isolation, or into the shared schema otherwise. It must be called before
+// GetRouter.
+func (s *ServiceFramework) RegisterTenantModels(models ...any) {
+	if s.authMiddleware != nil {
+		panic("tenant models must be registered before GetRouter is called")
+	}
+	s.tenantModels = append(s.tenantModels, models...)
+}
+
+// EnableRowLevelSecurity puts host application tables behind the row-level security policy of
+// the framework's tenant-owned tables, so connections from GetTenantDatabase and
+// WithTenantDatabase only read and write the tenant's rows. The models need a TenantID field,
+// such as frameworkutils.TenantScoped provides, and their tables must already exist.
+func (s *ServiceFramework) EnableRowLevelSecurity(models ...any) error {
+	if s.cfg.TenantIsolation != frameworkdto.TenantIsolationRowLevelSecurity {
+		return frameworkconstants.ErrRowLevelSecurityDisabled
+	}
+
+	tenantDatabaseRepo := repositories.NewTenantDatabaseRepository(s.db)
+	for _, model := range models {
+		if err := tenantDatabaseRepo.EnableRowLevelSecurity(model, "TenantID", false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetJWTKeySet returns the keys used to sign and verify framework tokens, for host
+// applications that need to verify tokens outside the built-in middleware
+func (s *ServiceFramework) GetJWTKeySet() *frameworkutils.JWTKeySet {
+	return s.keySet
+}
+
+// GetAuthMiddleware returns the middleware protecting framework routes, accepting bearer JWTs
+// and API keys, so host applications can protect their own routes the same way. It is
+// available once GetRouter has been called.
+func (s *ServiceFramework) GetAuthMiddleware() gin.HandlerFunc {
+	return s.authMiddleware
+}
+
+// RequirePermission returns middleware, to be placed after the auth middleware, that rejects
+// callers whose role does not grant the permission with HTTP 403. API keys hold no permissions;
+// use frameworkutils.RequireScopes for them. It is available once GetRouter has been called.
+func (s *ServiceFramework) RequirePermission(permission string) gin.HandlerFunc {
+	if s.rbacService == nil {
+		panic("RequirePermission must be called after GetRouter")
+	}
+	return middleware.RequirePermission(s.rbacService, permission)
+}
+
+// RequirePolicy returns middleware, to be placed after the auth middleware, that rejects
+// requests the caller's tenant's access policies deny for the action with HTTP 403. The
+// resource loader supplies the resource attributes and may be nil. It is available once
+// GetRouter has been called.
+func (s *ServiceFramework) RequirePolicy(action string, resourceLoader frameworkutils.PolicyResourceLoader) gin.HandlerFunc {
+	if s.policyService == nil {
+		panic("RequirePolicy must be called after GetRouter")
+	}
+	return middleware.RequirePolicy(s.policyService, action, resourceLoader)
+}
+
+// Authorize decides an action on a resource with the caller's tenant's access policies, for
+// handlers that load the resource themselves. The request must have passed the auth middleware.
+func (s *ServiceFramework) Authorize(c *gin.Context, action string, resource any) (frameworkdto.PolicyDecisionDTO, error) {
+	if s.policyService == nil {
+		panic("Authorize must be called after GetRouter")
+	}
+
+	tokenDto, err := frameworkutils.GetTokenDTO(c)
+	if err != nil {
+		return frameworkdto.PolicyDecisionDTO{}, frameworkutils.UnauthorizedError("Unauthorized")
+	}
+	return s.policyService.Evaluate(tokenDto, frameworkutils.GetPolicyRequest(c), action, resource)
+}
+
+// GetUserGroupIDs returns the groups a user of the tenant belongs to directly or through a
+// subgroup. Unlike the token's groups claim it reflects membership changes immediately.
+func (s *ServiceFramework) GetUserGroupIDs(tenantID, userID uint) ([]uint, error) {
+	if s.groupService == nil {
+		panic("GetUserGroupIDs must be called after GetRouter")
+	}
+	return s.groupService.GetUserGroupIDs(tenantID, userID)
+}
+
+// GetReauthenticationMiddleware returns middleware that marks a route as sensitive: callers who
+// have not logged in or re-authenticated within ReauthenticationMaxAge get HTTP 401 with the
+// code REAUTHENTICATION_REQUIRED. It must be placed after the auth middleware.
+func (s *ServiceFramework) GetReauthenticationMiddleware() gin.HandlerFunc {
+	return frameworkutils.RequireRecentAuthentication(s.reauthenticationMaxAge())
+}
+
+func (s *ServiceFramework) reauthenticationMaxAge() time.Duration {
+	if s.cfg.ReauthenticationMaxAge > 0 {
+		return s.cfg.ReauthenticationMaxAge
+	}
+	return frameworkconstants.DefaultReauthenticationMaxAge
+}
+
+// RegisterClaimsProvider adds the provider's claims to every access token the framework issues.
+// It must be called before GetRouter.
+func (s *ServiceFramework) RegisterClaimsProvider(claimsProvider frameworkutils.ClaimsProvider) {
+	if s.authMiddleware != nil {
+		panic("claims provider must be registered before GetRouter is called")
+	}
+	s.claimsProvider = claimsProvider
+}
+
+// RegisterPermission adds a host application permission that roles can grant and grants it to
+// the given built-in roles. Tenants can then put it into their custom roles, and host routes
+// can require it with RequirePermission. Registering a permission again keeps its original
+// description and only adds grants.
+func (s *ServiceFramework) RegisterPermission(name, description string, roles ...frameworkconstants.UserRole) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return frameworkconstants.ErrInvalidPermission
+	}
+
+	roleRepo := repositories.NewRoleRepository(s.db)
+	permission, err := roleRepo.SeedPermission(name, description)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		if err := roleRepo.SeedRole(string(role), frameworkconstants.RoleDescriptions[role], []entities.Permission{permission}); err != nil {
+			return err
+		}
+	}
+
+	if s.rbacService != nil {
+		s.rbacService.Invalidate()
+	}
+	return nil
+}
+
 func (s *ServiceFramework) GetRouter(requestPerSecond, burst int) *gin.Engine {
 	if s.router == nil {
 		panic("router is not initialized")
@@ -88,12 +349,16 @@ func (s *ServiceFramework) GetRouter(requestPerSecond, burst int) *gin.Engine {
 
 	s.router.Use(middleware.CORSMiddleware(s.cfg.CORSCfg))
 	s.router.Use(middleware.RateLimitMiddleware(requestPerSecond, burst))
+	if s.cfg.TenantIsolation == frameworkdto.TenantIsolationSchema || s.cfg.TenantIsolation == frameworkdto.TenantIsolationRowLevelSecurity {
+		s.router.Use(middleware.ReleaseTenantDatabase())
+	}
 
 	if s.cfg.Environment == frameworkdto.EnvDev {
 		s.router.Use(gin.Logger())
 	}
 
 	handlers.NewHealthHandlers().RegisterRoutes(s.router)
+	handlers.NewJWKSHandler(s.keySet).RegisterRoutes(s.router)
 
 	// Register Swagger/Redoc documentation routes
 
@@ -133,20 +398,84 @@ func (s *ServiceFramework) GetRouter(requestPerSecond, burst int) *gin.Engine {
 	tenantRepo := repositories.NewTenantRepository(s.db)
 	tenantLicenceRepo := repositories.NewTenantLicenceRepository(s.db)
 	licenceTypeRepo := repositories.NewLicenceTypeRepository(s.db)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(s.db)
+	revokedTokenRepo := repositories.NewRevokedTokenRepository(s.db)
+	mfaRepo := repositories.NewMFARepository(s.db)
+	identityProviderRepo := repositories.NewIdentityProviderRepository(s.db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(s.db)
+	sessionRepo := repositories.NewSessionRepository(s.db)
+	passwordPolicyRepo := repositories.NewPasswordPolicyRepository(s.db)
+	passwordRepo := repositories.NewPasswordRepository(s.db)
+	impersonationRepo := repositories.NewImpersonationRepository(s.db)
+	magicLinkRepo := repositories.NewMagicLinkRepository(s.db)
+	ldapDirectoryRepo := repositories.NewLDAPDirectoryRepository(s.db)
+	roleRepo := repositories.NewRoleRepository(s.db)
+	accessPolicyRepo := repositories.NewAccessPolicyRepository(s.db)
+	groupRepo := repositories.NewGroupRepository(s.db)
+	tenantDatabaseRepo := repositories.NewTenantDatabaseRepository(s.db)
+	tenantDomainRepo := repositories.NewTenantDomainRepository(s.db)
 
 	// Register Services
-	loginService := services.NewLoginService(s.cfg, userRepo, tenantRepo)
+	tenantDatabaseService := services.NewTenantDatabaseService(s.cfg, tenantDatabaseRepo, tenantRepo, s.tenantModels)
+	if s.cfg.TenantIsolation == frameworkdto.TenantIsolationSchema {
+		if err := tenantDatabaseService.ProvisionAllTenants(); err != nil {
+			panic(err)
+		}
+	} else if len(s.tenantModels) > 0 {
+		if err := s.db.AutoMigrate(s.tenantModels...); err != nil {
+			panic(err)
+		}
+	}
+	tokenService := services.NewTokenService(s.cfg, s.keySet, userRepo, tenantRepo, refreshTokenRepo, sessionRepo, groupRepo, s.claimsProvider)
+	tokenRevocationService := services.NewTokenRevocationService(userRepo, revokedTokenRepo, refreshTokenRepo, sessionRepo)
+	passwordPolicyService := services.NewPasswordPolicyService(s.cfg, passwordPolicyRepo)
+	ldapService := services.NewLDAPService(s.cfg, ldapDirectoryRepo)
+	lockoutService := services.NewLockoutService(s.cfg, userRepo)
+	passwordService := services.NewPasswordService(s.passwordHasher, userRepo, passwordRepo, passwordPolicyService, tokenService, tokenRevocationService, lockoutService, ldapService)
+	mfaService := services.NewMFAService(s.cfg, userRepo, tenantRepo, mfaRepo, tokenService, passwordService, lockoutService)
+	loginService := services.NewLoginService(s.cfg, userRepo, tenantRepo, tokenService, tokenRevocationService, mfaService, passwordService, lockoutService, magicLinkRepo)
 	licenceTypeService := services.NewLicenceTypeService(licenceTypeRepo)
-	registrationService := services.NewUserRegistrationService(userRepo, tenantRepo, tenantLicenceRepo, licenceTypeRepo)
-	tenantService := services.NewTenantService(tenantRepo)
-	userMaintenanceService := services.NewUserMaintenanceService(userRepo)
+	registrationService := services.NewUserRegistrationService(s.passwordHasher, userRepo, tenantRepo, tenantLicenceRepo, licenceTypeRepo, passwordPolicyService, tenantDatabaseService)
+	tenantResolverService := services.NewTenantResolverService(s.cfg, tenantRepo, tenantDomainRepo)
+	tenantService := services.NewTenantService(tenantRepo, tenantLicenceRepo, licenceTypeRepo, userRepo, registrationService, tokenRevocationService, tenantResolverService)
+	tenantDomainService := services.NewTenantDomainService(s.cfg, tenantDomainRepo, tenantResolverService)
+	userMaintenanceService := services.NewUserMaintenanceService(userRepo, roleRepo, tokenRevocationService, passwordService)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, tenantRepo)
+	sessionService := services.NewSessionService(userRepo, sessionRepo, tokenRevocationService)
+	rbacService := services.NewRBACService(roleRepo, groupRepo)
+	oidcService := services.NewOIDCService(userRepo, identityProviderRepo, registrationService, tokenService, rbacService)
+	roleService := services.NewRoleService(roleRepo, userRepo, groupRepo, rbacService)
+	groupService := services.NewGroupService(groupRepo, roleRepo, userRepo, rbacService, tokenRevocationService)
+	policyService := services.NewPolicyService(accessPolicyRepo, userRepo, groupRepo)
+	impersonationService := services.NewImpersonationService(s.cfg, s.keySet, userRepo, impersonationRepo, tokenRevocationService, tokenService)
+
+	// Register Middleware
+	authMiddleware := middleware.AuthMiddleware(s.keySet, tokenRevocationService, apiKeyService)
+	s.authMiddleware = authMiddleware
+	s.rbacService = rbacService
+	s.policyService = policyService
+	s.groupService = groupService
+	s.tenantDatabaseService = tenantDatabaseService
+
+	// Resolve the tenant a request is addressed to ahead of every route that may authenticate
+	s.router.Use(middleware.TenantResolverMiddleware(tenantResolverService))
 
 	// Register login handlers
-	handlers.NewLoginHandlers(loginService).RegisterRoutes(s.router)
-	handlers.NewLicenceTypeHandler(s.cfg.JWTSecret, licenceTypeService).RegisterRoutes(s.router)
-	handlers.NewRegistrationHandlers(s.cfg.JWTSecret, registrationService).RegisterRoutes(s.router)
-	handlers.NewUserMaintenanceHandler(s.cfg.JWTSecret, userMaintenanceService).RegisterRoutes(s.router)
-	handlers.NewTenantHandler(s.cfg.JWTSecret, tenantService).RegisterRoutes(s.router)
+	handlers.NewLoginHandlers(authMiddleware, loginService).RegisterRoutes(s.router)
+	handlers.NewMFAHandler(authMiddleware, mfaService).RegisterRoutes(s.router)
+	handlers.NewOIDCHandler(authMiddleware, rbacService, oidcService).RegisterRoutes(s.router)
+	handlers.NewLicenceTypeHandler(authMiddleware, rbacService, licenceTypeService).RegisterRoutes(s.router)
+	handlers.NewRegistrationHandlers(authMiddleware, rbacService, registrationService).RegisterRoutes(s.router)
+	handlers.NewUserMaintenanceHandler(authMiddleware, rbacService, s.reauthenticationMaxAge(), userMaintenanceService, passwordService).RegisterRoutes(s.router)
+	handlers.NewRoleHandler(authMiddleware, rbacService, roleService).RegisterRoutes(s.router)
+	handlers.NewGroupHandler(authMiddleware, rbacService, groupService).RegisterRoutes(s.router)
+	handlers.NewPolicyHandler(authMiddleware, rbacService, policyService).RegisterRoutes(s.router)
+	handlers.NewTenantHandler(authMiddleware, rbacService, s.reauthenticationMaxAge(), tenantService, tenantDomainService).RegisterRoutes(s.router)
+	handlers.NewAPIKeyHandler(authMiddleware, rbacService, apiKeyService).RegisterRoutes(s.router)
+	handlers.NewSessionHandler(authMiddleware, rbacService, sessionService).RegisterRoutes(s.router)
+	handlers.NewPasswordPolicyHandler(authMiddleware, rbacService, passwordPolicyService).RegisterRoutes(s.router)
+	handlers.NewImpersonationHandler(authMiddleware, rbacService, impersonationService).RegisterRoutes(s.router)
+	handlers.NewLDAPHandler(authMiddleware, rbacService, ldapService).RegisterRoutes(s.router)
 
 	return s.router
 }
@@ -0,0 +1,191 @@
+package serviceframework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	frameworkdto "github.com/geekible-ltd/serviceframework/framework-dto"
+	frameworkutils "github.com/geekible-ltd/serviceframework/framework-utils"
+	"github.com/gin-gonic/gin"
+)
+
+const testPassword = "Tr1cky-Horse-Battery"
+
+// newTestFramework starts the framework on a SQLite database in a temporary directory
+func newTestFramework(t *testing.T, configure func(cfg *frameworkdto.FrameworkConfig)) (*ServiceFramework, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	// SQLite databases live at <database>/<database>.db relative to the working directory
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("test", 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &frameworkdto.FrameworkConfig{
+		Environment: frameworkdto.EnvDev,
+		JWTSecret:   "test-secret",
+		DBType:      frameworkdto.DatabaseTypeSQLite,
+		DbCfg:       frameworkdto.DatabaseConfig{Database: "test"},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	sf := NewServiceFramework(cfg)
+	t.Cleanup(func() {
+		if sqlDB, err := sf.GetDatabase().DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return sf, sf.GetRouter(1000, 1000)
+}
+
+// doJSON sends a JSON request and decodes the data of the framework's response envelope
+func doJSON(t *testing.T, router *gin.Engine, method, path, token string, body any) (int, map[string]any) {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response struct {
+		Data map[string]any `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response.Data
+}
+
+func registerTestTenant(t *testing.T, router *gin.Engine, domain string) {
+	t.Helper()
+
+	code, _ := doJSON(t, router, http.MethodPost, "/registration/tenant", "", map[string]any{
+		"name":            domain,
+		"email":           "info@" + domain,
+		"licence_type_id": 1,
+		"user": map[string]any{
+			"first_name": "Ada",
+			"last_name":  "Admin",
+			"email":      "admin@" + domain,
+			"password":   testPassword,
+		},
+	})
+	if code != http.StatusCreated {
+		t.Fatalf("registering %s returned %d", domain, code)
+	}
+}
+
+func TestExpiredPasswordChangeIssuesUsableToken(t *testing.T) {
+	sf, router := newTestFramework(t, func(cfg *frameworkdto.FrameworkConfig) {
+		policy := frameworkutils.DefaultPasswordPolicy()
+		policy.MaxPasswordAgeDays = 90
+		cfg.PasswordPolicy = &policy
+	})
+	registerTestTenant(t, router, "acme.com")
+
+	changedAt := time.Now().AddDate(0, 0, -91)
+	if err := sf.GetDatabase().Exec("UPDATE users SET password_changed_at = ?", changedAt).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	code, login := doJSON(t, router, http.MethodPost, "/authentication/login", "", map[string]any{
+		"email":    "admin@acme.com",
+		"password": testPassword,
+	})
+	if code != http.StatusOK || login["password_change_required"] != true {
+		t.Fatalf("login returned %d %v, want a password change", code, login)
+	}
+
+	code, changed := doJSON(t, router, http.MethodPost, "/authentication/password/change", "", map[string]any{
+		"password_change_token": login["password_change_token"],
+		"new_password":          "Another-Tr1cky-Horse",
+	})
+	if code != http.StatusOK {
+		t.Fatalf("password change returned %d", code)
+	}
+	token, _ := changed["token"].(string)
+
+	// The token is issued in the same second the user's earlier tokens are revoked
+	if code, _ := doJSON(t, router, http.MethodGet, "/tenant/get-by-id", token, nil); code != http.StatusOK {
+		t.Fatalf("protected route returned %d with the token issued by the password change, want %d", code, http.StatusOK)
+	}
+}
+
+// login logs the user in with the test password and returns the access token
+func login(t *testing.T, router *gin.Engine, email string) string {
+	t.Helper()
+
+	code, response := doJSON(t, router, http.MethodPost, "/authentication/login", "", map[string]any{
+		"email":    email,
+		"password": testPassword,
+	})
+	token, _ := response["token"].(string)
+	if code != http.StatusOK || token == "" {
+		t.Fatalf("login of %s returned %d %v", email, code, response)
+	}
+	return token
+}
+
+func TestUserManagersCannotManageMorePrivilegedUsers(t *testing.T) {
+	sf, router := newTestFramework(t, nil)
+	registerTestTenant(t, router, "acme.com")
+	adminToken := login(t, router, "admin@acme.com")
+
+	if code, _ := doJSON(t, router, http.MethodPost, "/role/create", adminToken, map[string]any{
+		"name":        "user-manager",
+		"permissions": []string{"users:read", "users:update", "users:delete"},
+	}); code != http.StatusCreated {
+		t.Fatalf("creating the role returned %d", code)
+	}
+	if code, _ := doJSON(t, router, http.MethodPost, "/registration/user", adminToken, map[string]any{
+		"first_name": "Max",
+		"last_name":  "Manager",
+		"email":      "manager@acme.com",
+		"password":   testPassword,
+	}); code != http.StatusCreated {
+		t.Fatalf("adding the manager returned %d", code)
+	}
+	if err := sf.GetDatabase().Exec("UPDATE users SET role = ? WHERE email = ?", "user-manager", "manager@acme.com").Error; err != nil {
+		t.Fatal(err)
+	}
+	var adminID uint
+	if err := sf.GetDatabase().Raw("SELECT id FROM users WHERE email = ?", "admin@acme.com").Scan(&adminID).Error; err != nil {
+		t.Fatal(err)
+	}
+	managerToken := login(t, router, "manager@acme.com")
+
+	// The manager holds every permission of their own role, so may assign it, but not those of the admin's
+	if code, _ := doJSON(t, router, http.MethodPut, "/user-maintenance/user", managerToken, map[string]any{
+		"user_id":    adminID,
+		"first_name": "Ada",
+		"last_name":  "Admin",
+		"email":      "admin@acme.com",
+		"role":       "user-manager",
+		"is_active":  true,
+	}); code != http.StatusForbidden {
+		t.Errorf("demoting the admin returned %d, want %d", code, http.StatusForbidden)
+	}
+	if code, _ := doJSON(t, router, http.MethodDelete, fmt.Sprintf("/user-maintenance/user?userId=%d", adminID), managerToken, nil); code != http.StatusForbidden {
+		t.Errorf("deleting the admin returned %d, want %d", code, http.StatusForbidden)
+	}
+
+	// The admin is untouched and can still sign in
+	login(t, router, "admin@acme.com")
+}